@@ -0,0 +1,101 @@
+// Ensambla un programa de instrucciones al formato binario que carga memoria.
+//
+//	go run ./cmd/assemble -o prueba/binarios/PLANI_1.bin prueba/scripts_memoria/PLANI_1
+//	go run ./cmd/assemble -d prueba/binarios/PLANI_1.bin
+//
+// Sin -o el binario queda al lado del archivo con extension .bin
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sisoputnfrba/tp-golang/utils/instrucciones"
+)
+
+func leerLineas(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lineas []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineas = append(lineas, scanner.Text())
+	}
+	return lineas, scanner.Err()
+}
+
+// Muestra cada instruccion con su direccion en bytes
+func desensamblar(path string) error {
+	archivo, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	codigo, err := instrucciones.CodigoBinario(archivo)
+	if err != nil {
+		return err
+	}
+	for pc := 0; pc < len(codigo); {
+		texto, tam, err := instrucciones.Decodificar(codigo[pc:])
+		if err != nil {
+			return fmt.Errorf("direccion %d: %v", pc, err)
+		}
+		fmt.Printf("%6d  % x\n        %s\n", pc, codigo[pc:pc+tam], texto)
+		pc += tam
+	}
+	return nil
+}
+
+func main() {
+	salida := flag.String("o", "", "Archivo de salida (por defecto <archivo>.bin)")
+	desensamblado := flag.Bool("d", false, "Desensamblar un binario en vez de ensamblar")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Uso: %s [-o salida] <programa> | -d <binario>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	entrada := flag.Arg(0)
+
+	if *desensamblado {
+		if err := desensamblar(entrada); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", entrada, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	lineas, err := leerLineas(entrada)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	binario, err := instrucciones.EnsamblarBinario(lineas)
+	if err != nil {
+		if errores, ok := err.(instrucciones.ErroresPrograma); ok {
+			for _, e := range errores {
+				fmt.Fprintf(os.Stderr, "%s:%s\n", entrada, e.Error())
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", entrada, err)
+		}
+		os.Exit(1)
+	}
+
+	if *salida == "" {
+		*salida = entrada + ".bin"
+	}
+	if err := os.WriteFile(*salida, binario, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s: %d bytes de codigo\n", *salida, len(binario)-instrucciones.TamCabecera)
+}
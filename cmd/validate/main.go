@@ -0,0 +1,121 @@
+// Valida programas de instrucciones sin levantar los modulos, con la misma tabla que usan memoria y la CPU.
+//
+//	go run ./cmd/validate prueba/scripts_memoria/*
+//	go run ./cmd/validate -unreachable=false prueba/preliminares
+//
+// Sale con codigo 1 si algun archivo tiene errores
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sisoputnfrba/tp-golang/utils/instrucciones"
+)
+
+func leerLineas(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lineas []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineas = append(lineas, scanner.Text())
+	}
+	return lineas, scanner.Err()
+}
+
+// Los directorios se reemplazan por los archivos que tienen adentro (sin entrar en subdirectorios)
+func expandirArchivos(args []string) ([]string, error) {
+	var archivos []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			archivos = append(archivos, arg)
+			continue
+		}
+		entradas, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, entrada := range entradas {
+			if !entrada.IsDir() {
+				archivos = append(archivos, filepath.Join(arg, entrada.Name()))
+			}
+		}
+	}
+	return archivos, nil
+}
+
+// Devuelve la cantidad de problemas encontrados en el archivo
+func validarArchivo(path string, inalcanzable bool) int {
+	lineas, err := leerLineas(path)
+	if err != nil {
+		fmt.Printf("%s: %v\n", path, err)
+		return 1
+	}
+
+	programa, err := instrucciones.Ensamblar(lineas)
+	problemas := 0
+	if errores, ok := err.(instrucciones.ErroresPrograma); ok {
+		for _, e := range errores {
+			fmt.Printf("%s:%s\n", path, e.Error())
+		}
+		problemas += len(errores)
+	}
+	if len(programa.Lineas) == 0 {
+		fmt.Printf("%s: el archivo no tiene instrucciones\n", path)
+		return problemas + 1
+	}
+
+	inalcanzables, errores := instrucciones.Analizar(programa)
+	for _, e := range errores {
+		fmt.Printf("%s:%s\n", path, e.Error())
+	}
+	problemas += len(errores)
+	if inalcanzable {
+		for _, linea := range inalcanzables {
+			fmt.Printf("%s:linea %d: código inalcanzable: %s\n", path, linea.Numero, linea.Texto)
+		}
+		problemas += len(inalcanzables)
+	}
+	return problemas
+}
+
+func main() {
+	inalcanzable := flag.Bool("unreachable", true, "Reportar código inalcanzable")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Uso: %s [-unreachable=false] <archivo|directorio>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	archivos, err := expandirArchivos(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	problemas := 0
+	for _, archivo := range archivos {
+		problemas += validarArchivo(archivo, *inalcanzable)
+	}
+	if problemas > 0 {
+		fmt.Printf("%d problemas en %d archivos\n", problemas, len(archivos))
+		os.Exit(1)
+	}
+	fmt.Printf("%d archivos sin problemas\n", len(archivos))
+}
@@ -0,0 +1,174 @@
+// Cliente de la API de depuracion de la CPU (hay que poner "debug": true en la config de la CPU).
+//
+//	go run ./cmd/debugger -cpu localhost:8075            // modo interactivo
+//	go run ./cmd/debugger -cpu localhost:8075 break 1 4  // un solo comando
+//
+// Usa solo la API HTTP de la CPU, no importa cpu/utils
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const ayuda = `Comandos:
+  state                      estado de la CPU, registros del proceso pausado y breakpoints
+  break <pid> <pc>           agrega un breakpoint
+  delete <pid> [pc]          borra un breakpoint (sin pc, todos los del proceso)
+  pause                      frena antes de la proxima instruccion
+  step | s                   ejecuta una instruccion
+  continue | c               sigue hasta el proximo breakpoint
+  set <registro> <valor>     cambia un registro del proceso pausado
+  translate <dir> [tam]      traduce una direccion logica del proceso pausado
+  help                       esta ayuda
+  quit                       salir`
+
+var cpu string
+
+func pedir(metodo string, ruta string, body any) error {
+	var cuerpo io.Reader
+	if body != nil {
+		datos, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		cuerpo = bytes.NewReader(datos)
+	}
+	req, err := http.NewRequest(metodo, "http://"+cpu+ruta, cuerpo)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respuesta, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("la CPU no tiene la API de depuracion (falta \"debug\": true en la config)")
+		}
+		return fmt.Errorf("%s", bytes.TrimSpace(respuesta))
+	}
+
+	var lindo bytes.Buffer
+	if json.Indent(&lindo, respuesta, "", "  ") != nil {
+		fmt.Println(string(respuesta))
+		return nil
+	}
+	fmt.Println(lindo.String())
+	return nil
+}
+
+func numeros(args []string, cantidad int) ([]uint64, error) {
+	if len(args) < cantidad {
+		return nil, fmt.Errorf("faltan argumentos, ver help")
+	}
+	var valores []uint64
+	for _, arg := range args {
+		valor, err := strconv.ParseUint(arg, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s no es un numero", arg)
+		}
+		valores = append(valores, valor)
+	}
+	return valores, nil
+}
+
+func ejecutar(palabras []string) error {
+	comando, args := palabras[0], palabras[1:]
+	switch comando {
+	case "state":
+		return pedir(http.MethodGet, "/debug/state", nil)
+	case "break", "b":
+		valores, err := numeros(args, 2)
+		if err != nil {
+			return err
+		}
+		return pedir(http.MethodPost, "/debug/breakpoints", map[string]uint64{"pid": valores[0], "pc": valores[1]})
+	case "delete":
+		valores, err := numeros(args, 1)
+		if err != nil {
+			return err
+		}
+		query := url.Values{"pid": {strconv.FormatUint(valores[0], 10)}}
+		if len(valores) > 1 {
+			query.Set("pc", strconv.FormatUint(valores[1], 10))
+		}
+		return pedir(http.MethodDelete, "/debug/breakpoints?"+query.Encode(), nil)
+	case "pause":
+		return pedir(http.MethodPost, "/debug/pause", nil)
+	case "step", "s":
+		return pedir(http.MethodPost, "/debug/step", nil)
+	case "continue", "c":
+		return pedir(http.MethodPost, "/debug/continue", nil)
+	case "set":
+		if len(args) < 2 {
+			return fmt.Errorf("uso: set <registro> <valor>")
+		}
+		valores, err := numeros(args[1:2], 1)
+		if err != nil {
+			return err
+		}
+		return pedir(http.MethodPut, "/debug/registers", map[string]uint64{strings.ToUpper(args[0]): valores[0]})
+	case "translate", "t":
+		valores, err := numeros(args, 1)
+		if err != nil {
+			return err
+		}
+		query := url.Values{"address": {strconv.FormatUint(valores[0], 10)}}
+		if len(valores) > 1 {
+			query.Set("size", strconv.FormatUint(valores[1], 10))
+		}
+		return pedir(http.MethodGet, "/debug/translate?"+query.Encode(), nil)
+	case "help", "h":
+		fmt.Println(ayuda)
+		return nil
+	}
+	return fmt.Errorf("comando desconocido: %s (ver help)", comando)
+}
+
+func main() {
+	flag.StringVar(&cpu, "cpu", "localhost:8075", "IP:puerto de la CPU")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Uso: %s [-cpu ip:puerto] [comando args...]\n%s\n", os.Args[0], ayuda)
+	}
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		if err := ejecutar(flag.Args()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for fmt.Print("(cpu) "); scanner.Scan(); fmt.Print("(cpu) ") {
+		palabras := strings.Fields(scanner.Text())
+		if len(palabras) == 0 {
+			continue
+		}
+		if palabras[0] == "quit" || palabras[0] == "q" {
+			return
+		}
+		if err := ejecutar(palabras); err != nil {
+			fmt.Println("error:", err)
+		}
+	}
+	fmt.Println()
+}
@@ -0,0 +1,158 @@
+// Compara configuraciones de TLB con una traza de accesos grabada por la CPU (config "tlb_trace_path",
+// una linea "pid pagina" por busqueda en la TLB).
+//
+//	go run ./cmd/tlbbench tlb.trace                             // todos los algoritmos con 4, 8 y 16 entradas
+//	go run ./cmd/tlbbench -configs LRU:8,LRU:8:2,CLOCK:8 tlb.trace
+//	go run ./cmd/tlbbench -flush tlb.trace                      // vacia la TLB en cada cambio de proceso
+//
+// Las invalidaciones que pide memoria (paginas desalojadas o protegidas) no estan en la traza
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/tlb"
+)
+
+type acceso struct {
+	pid, pagina int
+}
+
+type configuracion struct {
+	algoritmo    string
+	tamaño, vias int
+}
+
+func (c configuracion) String() string {
+	if c.vias == 0 || c.vias == c.tamaño {
+		return fmt.Sprintf("%s:%d", c.algoritmo, c.tamaño)
+	}
+	return fmt.Sprintf("%s:%d:%d", c.algoritmo, c.tamaño, c.vias)
+}
+
+func leerTraza(path string) ([]acceso, error) {
+	archivo, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer archivo.Close()
+
+	var accesos []acceso
+	scanner := bufio.NewScanner(archivo)
+	for linea := 1; scanner.Scan(); linea++ {
+		campos := strings.Fields(scanner.Text())
+		if len(campos) == 0 {
+			continue
+		}
+		if len(campos) != 2 {
+			return nil, fmt.Errorf("%s:%d: se esperaba \"pid pagina\"", path, linea)
+		}
+		pid, err1 := strconv.Atoi(campos[0])
+		pagina, err2 := strconv.Atoi(campos[1])
+		if err1 != nil || err2 != nil || pagina < 0 {
+			return nil, fmt.Errorf("%s:%d: se esperaba \"pid pagina\"", path, linea)
+		}
+		accesos = append(accesos, acceso{pid, pagina})
+	}
+	return accesos, scanner.Err()
+}
+
+// ALGORITMO:TAMAÑO[:VIAS] separadas por coma
+func leerConfiguraciones(texto string) ([]configuracion, error) {
+	var configuraciones []configuracion
+	for _, parte := range strings.Split(texto, ",") {
+		campos := strings.Split(strings.TrimSpace(parte), ":")
+		if len(campos) < 2 || len(campos) > 3 {
+			return nil, fmt.Errorf("configuracion invalida %q, se espera ALGORITMO:TAMAÑO[:VIAS]", parte)
+		}
+		c := configuracion{algoritmo: strings.ToUpper(campos[0])}
+		var err error
+		if c.tamaño, err = strconv.Atoi(campos[1]); err != nil {
+			return nil, fmt.Errorf("configuracion invalida %q: %v", parte, err)
+		}
+		if len(campos) == 3 {
+			if c.vias, err = strconv.Atoi(campos[2]); err != nil {
+				return nil, fmt.Errorf("configuracion invalida %q: %v", parte, err)
+			}
+		}
+		configuraciones = append(configuraciones, c)
+	}
+	return configuraciones, nil
+}
+
+func configuracionesPorDefecto() []configuracion {
+	var configuraciones []configuracion
+	for _, tamaño := range []int{4, 8, 16} {
+		for _, algoritmo := range tlb.Algoritmos {
+			configuraciones = append(configuraciones, configuracion{algoritmo: algoritmo, tamaño: tamaño})
+		}
+	}
+	return configuraciones
+}
+
+// Repite los accesos como lo hace la MMU: si la pagina no esta se agrega
+func simular(c configuracion, accesos []acceso, flush bool, semilla int64) (tlb.Estadisticas, error) {
+	t, err := tlb.Nueva(c.tamaño, c.algoritmo, c.vias, semilla)
+	if err != nil {
+		return tlb.Estadisticas{}, err
+	}
+	ultimoPid := -1
+	for _, a := range accesos {
+		if flush && ultimoPid != -1 && a.pid != ultimoPid {
+			t.Vaciar(-1)
+		}
+		ultimoPid = a.pid
+		if _, _, found := t.Buscar(a.pid, a.pagina); !found {
+			t.Agregar(a.pid, a.pagina, 0, "")
+		}
+	}
+	return t.Total(), nil
+}
+
+func main() {
+	configs := flag.String("configs", "", "configuraciones ALGORITMO:TAMAÑO[:VIAS] separadas por coma (por defecto todos los algoritmos con 4, 8 y 16 entradas)")
+	flush := flag.Bool("flush", false, "vaciar la TLB en cada cambio de proceso (flush_tlb_on_switch)")
+	semilla := flag.Int64("seed", 0, "semilla de RANDOM")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Uso: %s [-configs ...] [-flush] [-seed n] traza\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	configuraciones := configuracionesPorDefecto()
+	if *configs != "" {
+		var err error
+		if configuraciones, err = leerConfiguraciones(*configs); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	accesos, err := leerTraza(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d accesos\n", len(accesos))
+
+	tabla := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tabla, "configuracion\thits\tmisses\treemplazos\ttasa de hits\t")
+	for _, c := range configuraciones {
+		total, err := simular(c, accesos, *flush, *semilla)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", c, err)
+			os.Exit(2)
+		}
+		fmt.Fprintf(tabla, "%s\t%d\t%d\t%d\t%.2f%%\t\n", c, total.Hits, total.Misses, total.Reemplazos, 100*total.TasaHits())
+	}
+	tabla.Flush()
+}
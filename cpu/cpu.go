@@ -17,13 +17,44 @@ func main() {
 	if globals.ClientConfig == nil {
 		log.Fatalf("No se pudo cargar la configuración")
 	}
+	utils.IniciarCPU()
 	puerto := globals.ClientConfig.Puerto
 
 	http.HandleFunc("/receivePCB", utils.ReceivePCB)
 	http.HandleFunc("POST /receiveDataFromMemory", utils.RecieveMOV_IN)
 	http.HandleFunc("/interrupt", utils.Checkinterrupts)
+	http.HandleFunc("GET /interrupts", utils.InterruptsHandler)
 	http.HandleFunc("/translate", utils.TranslateHandler)
 	http.HandleFunc("/recievePageTam", utils.ReceiveTamPage)
 	http.HandleFunc("POST /recieveFrame", utils.RecieveFramefromMemory)
+	http.HandleFunc("POST /invalidateTLB", utils.InvalidateTLBEntry)
+	http.HandleFunc("POST /invalidateFrames", utils.InvalidateFramesHandler)
+	http.HandleFunc("GET /tlb", utils.TLBHandler)
+	http.HandleFunc("DELETE /tlb", utils.FlushTLBHandler)
+	http.HandleFunc("POST /invalidateProgram", utils.InvalidateProgramHandler)
+	http.HandleFunc("GET /instructionCache", utils.InstructionCacheHandler)
+	http.HandleFunc("GET /dataCache", utils.DataCacheHandler)
+	http.HandleFunc("POST /replay", utils.ReplayHandler)
+	// Cada core por separado, las rutas de arriba sin id son del core 0
+	http.HandleFunc("/core/{id}/receivePCB", utils.ReceivePCB)
+	http.HandleFunc("/core/{id}/interrupt", utils.Checkinterrupts)
+	http.HandleFunc("GET /core/{id}/interrupts", utils.InterruptsHandler)
+	http.HandleFunc("GET /core/{id}/tlb", utils.TLBHandler)
+	http.HandleFunc("GET /core/{id}/instructionCache", utils.InstructionCacheHandler)
+	http.HandleFunc("GET /core/{id}/dataCache", utils.DataCacheHandler)
+	http.HandleFunc("POST /core/{id}/receiveDataFromMemory", utils.RecieveMOV_IN)
+	http.HandleFunc("POST /core/{id}/recieveFrame", utils.RecieveFramefromMemory)
+	if globals.ClientConfig.Debug {
+		for _, prefijo := range []string{"", "/core/{id}"} {
+			http.HandleFunc("GET "+prefijo+"/debug/state", utils.DebugStateHandler)
+			http.HandleFunc("POST "+prefijo+"/debug/breakpoints", utils.AddBreakpointHandler)
+			http.HandleFunc("DELETE "+prefijo+"/debug/breakpoints", utils.DeleteBreakpointHandler)
+			http.HandleFunc("POST "+prefijo+"/debug/pause", utils.PauseHandler)
+			http.HandleFunc("POST "+prefijo+"/debug/step", utils.StepHandler)
+			http.HandleFunc("POST "+prefijo+"/debug/continue", utils.ContinueHandler)
+			http.HandleFunc("PUT "+prefijo+"/debug/registers", utils.SetRegistersHandler)
+			http.HandleFunc("GET "+prefijo+"/debug/translate", utils.DebugTranslateHandler)
+		}
+	}
 	http.ListenAndServe(":"+strconv.Itoa(puerto), nil)
 }
@@ -1,13 +1,29 @@
 package globals
 
 type Config struct {
-	Puerto           int    `json:"port"`
-	IpKernel         string `json:"ip_kernel"`
-	IPMemory         string `json:"ip_memory"`
-	PortMemory       int    `json:"port_memory"`
-	PortKernel       int    `json:"port_kernel"`
-	NumberFellingTLB int    `json:"number_felling_tlb"`
-	AlgorithmTLB     string `json:"algorithm_tlb"`
+	Puerto                int            `json:"port"`
+	IpKernel              string         `json:"ip_kernel"`
+	IPMemory              string         `json:"ip_memory"`
+	PortMemory            int            `json:"port_memory"`
+	PortKernel            int            `json:"port_kernel"`
+	Cores                 int            `json:"cores"` // Cores que expone la CPU en /core/{id}, 0 o 1 es un solo core. El kernel usa solo el core 0
+	NumberFellingTLB      int            `json:"number_felling_tlb"`
+	AlgorithmTLB          string         `json:"algorithm_tlb"`           // FIFO, LRU, LFU, CLOCK o RANDOM
+	TLBWays               int            `json:"tlb_ways"`                // Entradas por conjunto, 0 es totalmente asociativa
+	TLBSeed               int64          `json:"tlb_seed"`                // Semilla de RANDOM (TLB y cache de datos), para poder repetir una corrida
+	TLBTracePath          string         `json:"tlb_trace_path"`          // Si esta, cada busqueda en la TLB se agrega a este archivo (ver cmd/tlbbench)
+	FlushTLBOnSwitch      bool           `json:"flush_tlb_on_switch"`     // Vaciar la TLB al cambiar de proceso en vez de conservar las entradas por PID
+	InstructionCacheBlock int            `json:"instruction_cache_block"` // Instrucciones que se piden juntas a memoria, 0 deshabilita el cache
+	DataCacheSize         int            `json:"data_cache_size"`         // Bytes de la cache L1 de datos, 0 la deshabilita
+	DataCacheLine         int            `json:"data_cache_line"`         // Bytes por linea
+	DataCacheWays         int            `json:"data_cache_ways"`         // Lineas por conjunto, 0 es totalmente asociativa
+	DataCacheWritePolicy  string         `json:"data_cache_write_policy"` // WRITE_BACK o WRITE_THROUGH
+	DataCacheAlgorithm    string         `json:"data_cache_algorithm"`    // FIFO, LRU, LFU, CLOCK o RANDOM
+	InterruptPriorities   map[string]int `json:"interrupt_priorities"`    // Prioridad de cada motivo de interrupcion, mayor se atiende primero
+	MaskableInterrupts    []string       `json:"maskable_interrupts"`     // Motivos que esperan mientras el proceso tiene las interrupciones enmascaradas (CLI)
+	Debug                 bool           `json:"debug"`                   // Habilita la API /debug (breakpoints, step, registros)
+	TracePath             string         `json:"trace_path"`              // Si esta, cada instruccion ejecutada se agrega a este archivo (ver POST /replay)
+	TraceDir              string         `json:"trace_dir"`               // Directorio de las trazas que se piden por nombre en POST /replay?path=
 }
 
 var ClientConfig *Config
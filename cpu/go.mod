@@ -1,3 +1,7 @@
 module github.com/sisoputnfrba/tp-golang/cpu
 
 go 1.22
+
+require github.com/sisoputnfrba/tp-golang/utils v0.0.0
+
+replace github.com/sisoputnfrba/tp-golang/utils => ../utils
@@ -0,0 +1,287 @@
+// Package tlb tiene la TLB de la CPU separada del resto de cpu/utils para poder usarla fuera del modulo
+// (cpu/cmd/tlbbench la usa para comparar configuraciones con trazas de accesos grabadas).
+package tlb
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Algoritmos de reemplazo
+const (
+	FIFO   = "FIFO"
+	LRU    = "LRU"
+	LFU    = "LFU"
+	CLOCK  = "CLOCK"
+	RANDOM = "RANDOM"
+)
+
+var Algoritmos = []string{FIFO, LRU, LFU, CLOCK, RANDOM}
+
+type Entrada struct {
+	PID          int
+	Pagina       int
+	Frame        int
+	Permisos     string    // Permisos de la pagina (R, W y X)
+	UltimoAcceso time.Time // Solo para mostrar, LRU usa acceso que no empata
+	posicionFila int       // Para FIFO
+	acceso       int       // Para LRU
+	usos         int       // Para LFU
+	referencia   bool      // Para CLOCK
+}
+
+// Una entrada con la informacion de reemplazo que muestra GET /tlb
+type EstadoEntrada struct {
+	Entrada
+	Conjunto     int
+	PosicionFIFO int // 0 es la proxima en salir del conjunto con FIFO
+	Usos         int
+	Referencia   bool
+}
+
+type Estadisticas struct {
+	Hits        int `json:"hits"`
+	Misses      int `json:"misses"`
+	Reemplazos  int `json:"evictions"` // Entradas del proceso que se reemplazaron por otra
+	Invalidadas int `json:"flushed"`   // Entradas del proceso que se borraron por flush o porque memoria lo pidio
+}
+
+func (e Estadisticas) TasaHits() float64 {
+	if e.Hits+e.Misses == 0 {
+		return 0
+	}
+	return float64(e.Hits) / float64(e.Hits+e.Misses)
+}
+
+// TLB asociativa por conjuntos. Con vias 0 (o igual al tamaño) hay un solo conjunto, totalmente asociativa
+// como la TLB original. La pagina elige el conjunto (pagina % cantidad de conjuntos) y el algoritmo de
+// reemplazo elige la victima dentro del conjunto
+type TLB struct {
+	mu           sync.Mutex
+	tamaño       int
+	vias         int
+	algoritmo    string
+	conjuntos    [][]Entrada
+	punteros     []int // Aguja de CLOCK de cada conjunto
+	posicionFila int
+	acceso       int
+	azar         *rand.Rand
+	estadisticas map[int]*Estadisticas
+}
+
+func Nueva(tamaño int, algoritmo string, vias int, semilla int64) (*TLB, error) {
+	if tamaño < 0 {
+		return nil, fmt.Errorf("tamaño de TLB invalido: %d", tamaño)
+	}
+	if tamaño > 0 && !slices.Contains(Algoritmos, algoritmo) {
+		return nil, fmt.Errorf("algoritmo de TLB desconocido: %s (se puede usar %v)", algoritmo, Algoritmos)
+	}
+	if vias <= 0 || vias > tamaño {
+		vias = tamaño
+	}
+	cantidad := 1
+	if tamaño > 0 {
+		if tamaño%vias != 0 {
+			return nil, fmt.Errorf("el tamaño de la TLB (%d) tiene que ser multiplo de las vias (%d)", tamaño, vias)
+		}
+		cantidad = tamaño / vias
+	}
+	return &TLB{
+		tamaño:       tamaño,
+		vias:         vias,
+		algoritmo:    algoritmo,
+		conjuntos:    make([][]Entrada, cantidad),
+		punteros:     make([]int, cantidad),
+		azar:         rand.New(rand.NewSource(semilla)),
+		estadisticas: make(map[int]*Estadisticas),
+	}, nil
+}
+
+func (t *TLB) Tamaño() int       { return t.tamaño }
+func (t *TLB) Vias() int         { return t.vias }
+func (t *TLB) Algoritmo() string { return t.algoritmo }
+
+// Hay que tener el mutex
+func (t *TLB) estadisticasProceso(pid int) *Estadisticas {
+	if t.estadisticas[pid] == nil {
+		t.estadisticas[pid] = &Estadisticas{}
+	}
+	return t.estadisticas[pid]
+}
+
+func (t *TLB) conjunto(pagina int) int {
+	return pagina % len(t.conjuntos)
+}
+
+// Busca la pagina y cuenta el hit o el miss. Si esta, actualiza lo que usa el algoritmo de reemplazo
+func (t *TLB) Buscar(pid, pagina int) (int, string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entradas := t.conjuntos[t.conjunto(pagina)]
+	for i := range entradas {
+		if entradas[i].PID == pid && entradas[i].Pagina == pagina {
+			t.acceso++
+			entradas[i].acceso = t.acceso
+			entradas[i].UltimoAcceso = time.Now()
+			entradas[i].usos++
+			entradas[i].referencia = true
+			t.estadisticasProceso(pid).Hits++
+			return entradas[i].Frame, entradas[i].Permisos, true
+		}
+	}
+	t.estadisticasProceso(pid).Misses++
+	return -1, "", false
+}
+
+// Agrega la traduccion, si el conjunto esta lleno reemplaza una entrada segun el algoritmo
+func (t *TLB) Agregar(pid, pagina, frame int, permisos string) {
+	if t.tamaño == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.acceso++
+	nueva := Entrada{
+		PID:          pid,
+		Pagina:       pagina,
+		Frame:        frame,
+		Permisos:     permisos,
+		UltimoAcceso: time.Now(),
+		posicionFila: t.posicionFila,
+		acceso:       t.acceso,
+		usos:         1,
+		referencia:   true,
+	}
+	t.posicionFila++
+
+	c := t.conjunto(pagina)
+	if len(t.conjuntos[c]) < t.vias {
+		t.conjuntos[c] = append(t.conjuntos[c], nueva)
+		return
+	}
+	victima := t.victima(c)
+	t.estadisticasProceso(t.conjuntos[c][victima].PID).Reemplazos++
+	t.conjuntos[c][victima] = nueva
+}
+
+// Hay que tener el mutex y el conjunto tiene que estar lleno
+func (t *TLB) victima(c int) int {
+	entradas := t.conjuntos[c]
+	victima := 0
+	switch t.algoritmo {
+	case FIFO:
+		for i, entrada := range entradas {
+			if entrada.posicionFila < entradas[victima].posicionFila {
+				victima = i
+			}
+		}
+	case LRU:
+		for i, entrada := range entradas {
+			if entrada.acceso < entradas[victima].acceso {
+				victima = i
+			}
+		}
+	case LFU: // Empate: la mas vieja
+		for i, entrada := range entradas {
+			if entrada.usos < entradas[victima].usos || (entrada.usos == entradas[victima].usos && entrada.posicionFila < entradas[victima].posicionFila) {
+				victima = i
+			}
+		}
+	case CLOCK: // Segunda oportunidad: la aguja limpia el bit de referencia hasta encontrar uno en 0
+		for entradas[t.punteros[c]].referencia {
+			entradas[t.punteros[c]].referencia = false
+			t.punteros[c] = (t.punteros[c] + 1) % len(entradas)
+		}
+		victima = t.punteros[c]
+		t.punteros[c] = (t.punteros[c] + 1) % len(entradas)
+	case RANDOM:
+		victima = t.azar.Intn(len(entradas))
+	}
+	return victima
+}
+
+// Borra la entrada de una pagina, devuelve si estaba
+func (t *TLB) Invalidar(pid, pagina int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.conjunto(pagina)
+	for i, entrada := range t.conjuntos[c] {
+		if entrada.PID == pid && entrada.Pagina == pagina {
+			t.borrar(c, i)
+			return true
+		}
+	}
+	return false
+}
+
+// Borra las entradas del PID (todas si pid es -1), devuelve cuantas borro
+func (t *TLB) Vaciar(pid int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	borradas := 0
+	for c := range t.conjuntos {
+		for i := 0; i < len(t.conjuntos[c]); i++ {
+			if pid == -1 || t.conjuntos[c][i].PID == pid {
+				t.borrar(c, i)
+				i--
+				borradas++
+			}
+		}
+	}
+	return borradas
+}
+
+// Hay que tener el mutex
+func (t *TLB) borrar(c int, i int) {
+	t.estadisticasProceso(t.conjuntos[c][i].PID).Invalidadas++
+	t.conjuntos[c] = append(t.conjuntos[c][:i], t.conjuntos[c][i+1:]...)
+	if i < t.punteros[c] {
+		t.punteros[c]--
+	}
+	if t.punteros[c] >= len(t.conjuntos[c]) {
+		t.punteros[c] = 0
+	}
+}
+
+func (t *TLB) Entradas() []EstadoEntrada {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var estado []EstadoEntrada
+	for c, entradas := range t.conjuntos {
+		for _, entrada := range entradas {
+			posicion := 0
+			for _, otra := range entradas {
+				if otra.posicionFila < entrada.posicionFila {
+					posicion++
+				}
+			}
+			estado = append(estado, EstadoEntrada{Entrada: entrada, Conjunto: c, PosicionFIFO: posicion, Usos: entrada.usos, Referencia: entrada.referencia})
+		}
+	}
+	return estado
+}
+
+func (t *TLB) Estadisticas() map[int]Estadisticas {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	copia := make(map[int]Estadisticas)
+	for pid, estadisticas := range t.estadisticas {
+		copia[pid] = *estadisticas
+	}
+	return copia
+}
+
+// Suma de las estadisticas de todos los procesos
+func (t *TLB) Total() Estadisticas {
+	var total Estadisticas
+	for _, e := range t.Estadisticas() {
+		total.Hits += e.Hits
+		total.Misses += e.Misses
+		total.Reemplazos += e.Reemplazos
+		total.Invalidadas += e.Invalidadas
+	}
+	return total
+}
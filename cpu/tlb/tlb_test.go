@@ -0,0 +1,148 @@
+package tlb
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// Hace lo mismo que la MMU: busca la pagina y si no esta la agrega con frame igual a la pagina
+func acceder(t *TLB, pid int, paginas ...int) {
+	for _, pagina := range paginas {
+		if _, _, hit := t.Buscar(pid, pagina); !hit {
+			t.Agregar(pid, pagina, pagina, "RW")
+		}
+	}
+}
+
+func paginasCargadas(t *TLB) []int {
+	var paginas []int
+	for _, entrada := range t.Entradas() {
+		paginas = append(paginas, entrada.Pagina)
+	}
+	slices.Sort(paginas)
+	return paginas
+}
+
+func TestReemplazo(t *testing.T) {
+	casos := []struct {
+		algoritmo string
+		accesos   []int
+		quedan    []int
+	}{
+		{FIFO, []int{0, 1, 2, 3, 1, 4}, []int{2, 3, 4}},
+		{LRU, []int{0, 1, 2, 3, 1, 4}, []int{1, 3, 4}},
+		{LFU, []int{0, 1, 2, 3, 1, 4}, []int{1, 3, 4}},
+		{CLOCK, []int{0, 1, 2, 3, 1, 4}, []int{1, 3, 4}},
+		{FIFO, []int{0, 0, 1, 2, 3}, []int{1, 2, 3}},
+		{LRU, []int{0, 0, 1, 2, 3}, []int{1, 2, 3}},
+		{LFU, []int{0, 0, 1, 2, 3}, []int{0, 2, 3}},
+		{CLOCK, []int{0, 0, 1, 2, 3}, []int{1, 2, 3}},
+		{LRU, []int{0, 1, 2, 0, 3}, []int{0, 2, 3}},
+		{LFU, []int{0, 1, 2, 2, 1, 3, 3, 4}, []int{2, 3, 4}}, // Empate de 1, 2 y 3 con dos usos: sale la mas vieja
+		{CLOCK, []int{0, 1, 2, 3, 4, 0, 5}, []int{0, 4, 5}},  // La aguja sigue donde quedo
+	}
+	for _, caso := range casos {
+		t.Run(caso.algoritmo, func(t *testing.T) {
+			tlb, err := Nueva(3, caso.algoritmo, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			acceder(tlb, 1, caso.accesos...)
+			if quedan := paginasCargadas(tlb); !reflect.DeepEqual(quedan, caso.quedan) {
+				t.Errorf("accesos %v: quedan %v, se esperaba %v", caso.accesos, quedan, caso.quedan)
+			}
+		})
+	}
+}
+
+func TestEstadisticas(t *testing.T) {
+	tlb, _ := Nueva(3, FIFO, 0, 0)
+	acceder(tlb, 1, 0, 1, 2, 3, 1, 4)
+	acceder(tlb, 2, 5)
+	esperadas := map[int]Estadisticas{
+		1: {Hits: 1, Misses: 5, Reemplazos: 3}, // La 5 del PID 2 reemplaza una entrada del PID 1
+		2: {Hits: 0, Misses: 1},
+	}
+	if estadisticas := tlb.Estadisticas(); !reflect.DeepEqual(estadisticas, esperadas) {
+		t.Errorf("estadisticas = %v, se esperaba %v", estadisticas, esperadas)
+	}
+	if borradas := tlb.Vaciar(1); borradas != 2 {
+		t.Errorf("Vaciar(1) borro %d entradas, se esperaban 2", borradas)
+	}
+	if total := tlb.Total(); total.Invalidadas != 2 || total.Misses != 6 {
+		t.Errorf("total = %+v", total)
+	}
+}
+
+func TestRandomConSemilla(t *testing.T) {
+	accesos := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	a, _ := Nueva(4, RANDOM, 0, 42)
+	b, _ := Nueva(4, RANDOM, 0, 42)
+	acceder(a, 1, accesos...)
+	acceder(b, 1, accesos...)
+	if !reflect.DeepEqual(paginasCargadas(a), paginasCargadas(b)) {
+		t.Errorf("con la misma semilla quedaron %v y %v", paginasCargadas(a), paginasCargadas(b))
+	}
+	if len(paginasCargadas(a)) != 4 {
+		t.Errorf("quedaron %v, se esperaban 4 entradas", paginasCargadas(a))
+	}
+}
+
+func TestAsociatividad(t *testing.T) {
+	// 4 entradas en 2 conjuntos de 2 vias: las paginas pares van al conjunto 0 y las impares al 1
+	tlb, err := Nueva(4, FIFO, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	acceder(tlb, 1, 1, 0, 2, 4)
+	if quedan := paginasCargadas(tlb); !reflect.DeepEqual(quedan, []int{1, 2, 4}) {
+		t.Errorf("quedan %v, se esperaba [1 2 4]: la 4 tiene que reemplazar a la 0 de su conjunto", quedan)
+	}
+	for _, entrada := range tlb.Entradas() {
+		if entrada.Conjunto != entrada.Pagina%2 {
+			t.Errorf("pagina %d en el conjunto %d", entrada.Pagina, entrada.Conjunto)
+		}
+	}
+	if !tlb.Invalidar(1, 2) || tlb.Invalidar(1, 2) {
+		t.Error("Invalidar tendria que encontrar la pagina 2 una sola vez")
+	}
+}
+
+func TestNueva(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		tamaño    int
+		algoritmo string
+		vias      int
+		valida    bool
+		viasFinal int
+	}{
+		{"totalmente asociativa", 4, LRU, 0, true, 4},
+		{"mas vias que entradas", 4, LRU, 8, true, 4},
+		{"asociativa por conjuntos", 8, CLOCK, 2, true, 2},
+		{"vias que no dividen", 4, FIFO, 3, false, 0},
+		{"tamaño negativo", -1, FIFO, 0, false, 0},
+		{"algoritmo desconocido", 4, "MRU", 0, false, 0},
+		{"sin TLB no importa el algoritmo", 0, "", 0, true, 0},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			tlb, err := Nueva(caso.tamaño, caso.algoritmo, caso.vias, 0)
+			if (err == nil) != caso.valida {
+				t.Fatalf("error = %v, se esperaba valida = %v", err, caso.valida)
+			}
+			if err == nil && tlb.Vias() != caso.viasFinal {
+				t.Errorf("vias = %d, se esperaba %d", tlb.Vias(), caso.viasFinal)
+			}
+		})
+	}
+}
+
+func TestSinEntradas(t *testing.T) {
+	tlb, _ := Nueva(0, "", 0, 0)
+	acceder(tlb, 1, 0, 0)
+	if len(tlb.Entradas()) != 0 || tlb.Total().Misses != 2 {
+		t.Errorf("una TLB de 0 entradas no tendria que guardar nada: %v, %+v", tlb.Entradas(), tlb.Total())
+	}
+}
@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+)
+
+// Bits del registro FLAGS
+const (
+	FlagZero     uint8 = 1 << iota // El resultado fue 0
+	FlagCarry                      // El resultado no entro en el registro (o hubo borrow en la resta)
+	FlagOverflow                   // Overflow con signo
+	FlagNegative                   // Bit mas significativo del resultado
+	FlagMascara                    // Interrupciones enmascaradas (CLI/STI), las operaciones no lo tocan
+	FlagPila                       // El SP ya se inicializo (primer PUSH/CALL o SET SP), las operaciones no lo tocan
+)
+
+// Devuelve el campo del registro y su ancho en bits (8 o 32)
+func campoRegistro(r *RegisterCPU, nombre string) (reflect.Value, uint, error) {
+	campo := reflect.ValueOf(r).Elem().FieldByName(nombre)
+	if !campo.IsValid() {
+		return campo, 0, fmt.Errorf("registro '%s' no encontrado en la estructura", nombre)
+	}
+	switch campo.Kind() {
+	case reflect.Uint8:
+		return campo, 8, nil
+	case reflect.Uint32:
+		return campo, 32, nil
+	}
+	return campo, 0, fmt.Errorf("registro '%s' no soportado", nombre)
+}
+
+// El segundo operando puede ser un registro o un numero
+func valorOperando(r *RegisterCPU, operando string) (uint64, error) {
+	if valor, err := strconv.ParseUint(operando, 10, 32); err == nil {
+		return valor, nil
+	}
+	campo, _, err := campoRegistro(r, operando)
+	if err != nil {
+		return 0, err
+	}
+	return campo.Uint(), nil
+}
+
+func mascara(ancho uint) uint64 {
+	return 1<<ancho - 1
+}
+
+func bitSigno(valor uint64, ancho uint) bool {
+	return valor>>(ancho-1)&1 == 1
+}
+
+// Actualiza Z y N con el resultado ya recortado al ancho del registro, C y O los decide cada operacion
+func setFlags(r *RegisterCPU, resultado uint64, ancho uint, carry bool, overflow bool) {
+	var flags uint8
+	if resultado == 0 {
+		flags |= FlagZero
+	}
+	if carry {
+		flags |= FlagCarry
+	}
+	if overflow {
+		flags |= FlagOverflow
+	}
+	if bitSigno(resultado, ancho) {
+		flags |= FlagNegative
+	}
+	r.FLAGS = flags | r.FLAGS&(FlagMascara|FlagPila)
+}
+
+// MUL, DIV, MOD, AND, OR, XOR, SHL, SHR: <reg_destino> <reg_origen|valor>
+func (c *Core) OperacionALU(contextoEjecucion *PCB, instruccion string, destino string, origen string) error {
+	r := &contextoEjecucion.CpuReg
+	campo, ancho, err := campoRegistro(r, destino)
+	if err != nil {
+		return err
+	}
+	a := campo.Uint()
+	b, err := valorOperando(r, origen)
+	if err != nil {
+		return err
+	}
+	m := mascara(ancho)
+
+	var resultado uint64
+	var carry bool
+	switch instruccion {
+	case "MUL":
+		resultado = a * (b & m)
+		carry = resultado > m
+	case "DIV", "MOD":
+		if b&m == 0 {
+			log.Printf("PID: %d - DIVISION BY ZERO - %s %s %s", contextoEjecucion.Pid, instruccion, destino, origen)
+			c.generarDivisionPorCero()
+			return nil
+		}
+		if instruccion == "DIV" {
+			resultado = a / (b & m)
+		} else {
+			resultado = a % (b & m)
+		}
+	case "AND":
+		resultado = a & b
+	case "OR":
+		resultado = a | b
+	case "XOR":
+		resultado = a ^ b
+	case "SHL":
+		if b > 0 && b <= uint64(ancho) {
+			carry = a>>(uint64(ancho)-b)&1 == 1 // Ultimo bit que sale por la izquierda
+		}
+		if b < uint64(ancho) {
+			resultado = a << b
+		}
+	case "SHR":
+		if b > 0 && b <= uint64(ancho) {
+			carry = a>>(b-1)&1 == 1 // Ultimo bit que sale por la derecha
+		}
+		if b < uint64(ancho) {
+			resultado = a >> b
+		}
+	default:
+		return fmt.Errorf("operacion %s no soportada", instruccion)
+	}
+
+	resultado &= m
+	campo.SetUint(resultado)
+	setFlags(r, resultado, ancho, carry, carry && instruccion == "MUL")
+	return nil
+}
+
+// NOT <reg>
+func NOT(contextoEjecucion *PCB, destino string) error {
+	r := &contextoEjecucion.CpuReg
+	campo, ancho, err := campoRegistro(r, destino)
+	if err != nil {
+		return err
+	}
+	resultado := ^campo.Uint() & mascara(ancho)
+	campo.SetUint(resultado)
+	setFlags(r, resultado, ancho, false, false)
+	return nil
+}
+
+// CMP <reg> <reg|valor>: hace la resta sin guardar el resultado, solo actualiza FLAGS
+func CMP(contextoEjecucion *PCB, registro string, origen string) error {
+	r := &contextoEjecucion.CpuReg
+	campo, ancho, err := campoRegistro(r, registro)
+	if err != nil {
+		return err
+	}
+	b, err := valorOperando(r, origen)
+	if err != nil {
+		return err
+	}
+	resultado, carry, overflow := restar(campo.Uint(), b, ancho)
+	setFlags(r, resultado, ancho, carry, overflow)
+	return nil
+}
+
+// Suma recortada al ancho del registro: C si no entro y O si cambio el signo sumando dos del mismo signo (SUM)
+func sumar(a uint64, b uint64, ancho uint) (uint64, bool, bool) {
+	m := mascara(ancho)
+	a, b = a&m, b&m
+	resultado := (a + b) & m
+	overflow := bitSigno(a, ancho) == bitSigno(b, ancho) && bitSigno(resultado, ancho) != bitSigno(a, ancho)
+	return resultado, a+b > m, overflow
+}
+
+// Resta recortada al ancho del registro: C si hubo borrow (a < b sin signo) y O el overflow con signo (SUB y CMP)
+func restar(a uint64, b uint64, ancho uint) (uint64, bool, bool) {
+	m := mascara(ancho)
+	a, b = a&m, b&m
+	resultado := (a - b) & m
+	overflow := bitSigno(a, ancho) != bitSigno(b, ancho) && bitSigno(resultado, ancho) != bitSigno(a, ancho)
+	return resultado, a < b, overflow
+}
+
+// JZ, JG, JL y JMP <etiqueta|pc>. JG y JL comparan sin signo, con los flags del ultimo CMP
+func SaltoCondicional(contextoEjecucion *PCB, instruccion string, destino string) error {
+	flags := contextoEjecucion.CpuReg.FLAGS
+	var saltar bool
+	switch instruccion {
+	case "JMP":
+		saltar = true
+	case "JZ":
+		saltar = flags&FlagZero != 0
+	case "JG":
+		saltar = flags&FlagZero == 0 && flags&FlagCarry == 0
+	case "JL":
+		saltar = flags&FlagCarry != 0
+	}
+	if !saltar {
+		return nil
+	}
+	pc, err := resolverDestino(contextoEjecucion.Pid, destino)
+	if err != nil {
+		return err
+	}
+	contextoEjecucion.CpuReg.PC = pc
+	return nil
+}
+
+// El kernel finaliza el proceso
+func (c *Core) generarDivisionPorCero() {
+	c.interrupt = true
+	c.request = KernelRequest{
+		MotivoDesalojo: "DIVISION_BY_ZERO",
+	}
+}
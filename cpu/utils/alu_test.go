@@ -0,0 +1,166 @@
+package utils
+
+import "testing"
+
+func TestSumarRestar(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		operacion func(a, b uint64, ancho uint) (uint64, bool, bool)
+		a, b      uint64
+		ancho     uint
+		resultado uint64
+		carry     bool
+		overflow  bool
+	}{
+		{"suma sin carry", sumar, 5, 3, 8, 8, false, false},
+		{"suma que da la vuelta", sumar, 200, 100, 8, 44, true, false},
+		{"suma al cero", sumar, 255, 1, 8, 0, true, false},
+		{"suma de positivos que da negativo", sumar, 100, 100, 8, 200, false, true},
+		{"suma de negativos que da positivo", sumar, 128, 128, 8, 0, true, true},
+		{"suma de 32 bits al cero", sumar, 0xFFFFFFFF, 1, 32, 0, true, false},
+		{"suma de 32 bits con overflow", sumar, 0x7FFFFFFF, 1, 32, 0x80000000, false, true},
+		{"suma recorta los operandos", sumar, 0x1FF, 1, 8, 0, true, false},
+		{"resta sin borrow", restar, 5, 3, 8, 2, false, false},
+		{"resta igual a cero", restar, 7, 7, 8, 0, false, false},
+		{"resta con borrow", restar, 3, 5, 8, 254, true, false},
+		{"resta de negativo menos positivo", restar, 0x80, 1, 8, 0x7F, false, true},
+		{"resta de positivo menos negativo", restar, 0x7F, 0xFF, 8, 0x80, true, true},
+		{"resta de 32 bits con borrow", restar, 0, 1, 32, 0xFFFFFFFF, true, false},
+		{"resta de 32 bits con overflow", restar, 0x80000000, 1, 32, 0x7FFFFFFF, false, true},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			resultado, carry, overflow := caso.operacion(caso.a, caso.b, caso.ancho)
+			if resultado != caso.resultado || carry != caso.carry || overflow != caso.overflow {
+				t.Errorf("= (%d, C=%v, O=%v), se esperaba (%d, C=%v, O=%v)", resultado, carry, overflow, caso.resultado, caso.carry, caso.overflow)
+			}
+		})
+	}
+}
+
+func TestSumaRestaRegistros(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		operacion func(*RegisterCPU, string, string) error
+		registros RegisterCPU
+		destino   string
+		origen    string
+		valor     uint64
+		flags     uint8
+	}{
+		{"SUM de 8 bits da la vuelta", Suma, RegisterCPU{AX: 250, BX: 10}, "AX", "BX", 4, FlagCarry},
+		{"SUM de 8 bits a cero", Suma, RegisterCPU{AX: 255, BX: 1}, "AX", "BX", 0, FlagZero | FlagCarry},
+		{"SUM de 8 bits con overflow", Suma, RegisterCPU{CX: 127, DX: 1}, "CX", "DX", 128, FlagOverflow | FlagNegative},
+		{"SUM de 32 bits", Suma, RegisterCPU{EAX: 0xFFFFFFFF, EBX: 2}, "EAX", "EBX", 1, FlagCarry},
+		{"SUB de 8 bits con borrow", Resta, RegisterCPU{AX: 1, BX: 2}, "AX", "BX", 255, FlagCarry | FlagNegative},
+		{"SUB de 32 bits a cero", Resta, RegisterCPU{SI: 9, DI: 9}, "SI", "DI", 0, FlagZero},
+		{"SUB conserva mascara y pila", Resta, RegisterCPU{AX: 3, BX: 3, FLAGS: FlagMascara | FlagPila | FlagCarry}, "AX", "BX", 0, FlagZero | FlagMascara | FlagPila},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			r := caso.registros
+			if err := caso.operacion(&r, caso.destino, caso.origen); err != nil {
+				t.Fatal(err)
+			}
+			campo, _, _ := campoRegistro(&r, caso.destino)
+			if campo.Uint() != caso.valor || r.FLAGS != caso.flags {
+				t.Errorf("%s = %d, FLAGS = %05b, se esperaba %d y %05b", caso.destino, campo.Uint(), r.FLAGS, caso.valor, caso.flags)
+			}
+		})
+	}
+}
+
+func TestSumaRestaAnchosDistintos(t *testing.T) {
+	r := RegisterCPU{AX: 1, EAX: 1}
+	if err := Suma(&r, "AX", "EAX"); err == nil {
+		t.Error("SUM AX EAX tendria que fallar")
+	}
+	if err := Resta(&r, "EAX", "BX"); err == nil {
+		t.Error("SUB EAX BX tendria que fallar")
+	}
+	if r.AX != 1 || r.EAX != 1 || r.FLAGS != 0 {
+		t.Errorf("los registros cambiaron: %+v", r)
+	}
+}
+
+func TestCMPYSaltos(t *testing.T) {
+	casos := []struct {
+		nombre     string
+		registros  RegisterCPU
+		registro   string
+		origen     string
+		jz, jg, jl bool
+	}{
+		{"mayor", RegisterCPU{AX: 5, BX: 3}, "AX", "BX", false, true, false},
+		{"menor", RegisterCPU{AX: 3, BX: 5}, "AX", "BX", false, false, true},
+		{"igual", RegisterCPU{AX: 7}, "AX", "7", true, false, false},
+		{"mayor sin signo", RegisterCPU{AX: 200}, "AX", "3", false, true, false},
+		{"menor sin signo", RegisterCPU{EAX: 1}, "EAX", "4294967295", false, false, true},
+		{"cero contra cero", RegisterCPU{}, "ECX", "0", true, false, false},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			contexto := PCB{CpuReg: caso.registros}
+			if err := CMP(&contexto, caso.registro, caso.origen); err != nil {
+				t.Fatal(err)
+			}
+			sinFlags := contexto.CpuReg
+			sinFlags.FLAGS = caso.registros.FLAGS
+			if sinFlags != caso.registros {
+				t.Errorf("CMP modifico algo mas que FLAGS: %+v", contexto.CpuReg)
+			}
+			for salto, esperado := range map[string]bool{"JZ": caso.jz, "JG": caso.jg, "JL": caso.jl} {
+				prueba := contexto
+				prueba.CpuReg.PC = 0
+				if err := SaltoCondicional(&prueba, salto, "42"); err != nil {
+					t.Fatal(err)
+				}
+				if salto := prueba.CpuReg.PC == 42; salto != esperado {
+					t.Errorf("FLAGS %05b: salto = %v, se esperaba %v", contexto.CpuReg.FLAGS, salto, esperado)
+				}
+			}
+		})
+	}
+}
+
+func TestOperacionALU(t *testing.T) {
+	casos := []struct {
+		nombre      string
+		instruccion string
+		registros   RegisterCPU
+		destino     string
+		origen      string
+		valor       uint64
+		flags       uint8
+		division    bool // Se espera DIVISION_BY_ZERO
+	}{
+		{"MUL que entra", "MUL", RegisterCPU{AX: 10}, "AX", "20", 200, FlagNegative, false},
+		{"MUL que no entra", "MUL", RegisterCPU{AX: 16, BX: 16}, "AX", "BX", 0, FlagZero | FlagCarry | FlagOverflow, false},
+		{"DIV", "DIV", RegisterCPU{EAX: 100}, "EAX", "7", 14, 0, false},
+		{"MOD", "MOD", RegisterCPU{EAX: 100}, "EAX", "7", 2, 0, false},
+		{"DIV por cero", "DIV", RegisterCPU{AX: 5}, "AX", "BX", 5, 0, true},
+		{"MOD por cero", "MOD", RegisterCPU{AX: 5}, "AX", "0", 5, 0, true},
+		{"AND", "AND", RegisterCPU{AX: 0b1100}, "AX", "10", 0b1000, 0, false},
+		{"XOR consigo mismo", "XOR", RegisterCPU{EDX: 77}, "EDX", "EDX", 0, FlagZero, false},
+		{"SHL saca un uno", "SHL", RegisterCPU{AX: 0b11000000}, "AX", "1", 0b10000000, FlagCarry | FlagNegative, false},
+		{"SHL de todo el ancho", "SHL", RegisterCPU{AX: 1}, "AX", "8", 0, FlagZero | FlagCarry, false},
+		{"SHR saca un uno", "SHR", RegisterCPU{AX: 0b11}, "AX", "1", 1, FlagCarry, false},
+		{"SHR de mas", "SHR", RegisterCPU{EAX: 0xFFFFFFFF}, "EAX", "40", 0, FlagZero, false},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			c := &Core{}
+			contexto := PCB{CpuReg: caso.registros}
+			if err := c.OperacionALU(&contexto, caso.instruccion, caso.destino, caso.origen); err != nil {
+				t.Fatal(err)
+			}
+			if division := c.request.MotivoDesalojo == "DIVISION_BY_ZERO"; division != caso.division {
+				t.Fatalf("DIVISION_BY_ZERO = %v, se esperaba %v", division, caso.division)
+			}
+			campo, _, _ := campoRegistro(&contexto.CpuReg, caso.destino)
+			if campo.Uint() != caso.valor || contexto.CpuReg.FLAGS != caso.flags {
+				t.Errorf("%s = %d, FLAGS = %05b, se esperaba %d y %05b", caso.destino, campo.Uint(), contexto.CpuReg.FLAGS, caso.valor, caso.flags)
+			}
+		})
+	}
+}
@@ -0,0 +1,469 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+	"github.com/sisoputnfrba/tp-golang/cpu/tlb"
+)
+
+const (
+	WriteBack    = "WRITE_BACK"
+	WriteThrough = "WRITE_THROUGH"
+)
+
+type EstadisticasCache struct {
+	Hits       int     `json:"hits"`
+	Misses     int     `json:"misses"`
+	Reemplazos int     `json:"evictions"`
+	Escrituras int     `json:"memory_writes"` // Escrituras que llegaron a memoria (write-through o lineas sucias)
+	TasaHits   float64 `json:"hit_rate"`
+}
+
+type BodyLineaCache struct {
+	Conjunto int  `json:"set"`
+	Pid      int  `json:"pid"`
+	Inicio   int  `json:"address"` // Primera direccion fisica de la linea
+	Sucia    bool `json:"dirty"`
+}
+
+type BodyEstadoCache struct {
+	Tamaño       int                       `json:"size"`
+	TamLinea     int                       `json:"line_size"`
+	Vias         int                       `json:"ways"`
+	Escritura    string                    `json:"write_policy"`
+	Algoritmo    string                    `json:"algorithm"`
+	Lineas       []BodyLineaCache          `json:"lines"`
+	Estadisticas map[int]EstadisticasCache `json:"stats"`
+}
+
+type BodyMarcos struct {
+	Marcos []int `json:"frames"`
+}
+
+type BodyLineaSucia struct {
+	Direccion int    `json:"address"` // Primera direccion fisica de la linea
+	Datos     []byte `json:"data"`
+}
+
+type lineaCache struct {
+	pid          int
+	numero       int // Direccion fisica / tamaño de linea
+	datos        []byte
+	sucia        bool
+	posicionFila int
+	acceso       int
+	usos         int
+	referencia   bool
+}
+
+// Cache L1 de datos entre la CPU y memoria, indexada por direccion fisica. Pasan por aca todas las lecturas
+// y escrituras de la CPU (MOV_IN, MOV_OUT, COPY_STRING, la pila y el fetch binario) para que no haya
+// copias distintas del mismo byte. Los reemplazos usan los mismos algoritmos que la TLB.
+// Cuando el proceso deja la CPU las lineas sucias se escriben en memoria y la cache queda vacia, asi el
+// kernel, los dispositivos de IO y el proximo proceso ven la memoria actualizada. Es una sola para todos
+// los cores, asi no hay copias distintas del mismo byte entre cores. Como esta indexada por direccion
+// fisica, memoria le avisa cuando un marco cambia de dueño (reemplazo, copy-on-write o RESIZE) igual que a la TLB
+var cacheDatos = struct {
+	sync.Mutex
+	invalidaciones int // Cuenta los avisos de memoria, una linea leida antes de un aviso no se agrega
+	tamLinea       int
+	vias           int
+	conjuntos      [][]lineaCache
+	punteros       []int
+	posicionFila   int
+	acceso         int
+	azar           *rand.Rand
+	estadisticas   map[int]*EstadisticasCache
+}{
+	estadisticas: make(map[int]*EstadisticasCache),
+}
+
+func cacheDatosHabilitada() bool {
+	return globals.ClientConfig.DataCacheSize > 0
+}
+
+func iniciarCacheDatos() {
+	config := globals.ClientConfig
+	if config.DataCacheSize <= 0 {
+		return
+	}
+	if config.DataCacheLine <= 0 || config.DataCacheSize%config.DataCacheLine != 0 {
+		log.Fatalf("Configuración de cache inválida: data_cache_size (%d) tiene que ser multiplo de data_cache_line (%d)", config.DataCacheSize, config.DataCacheLine)
+	}
+	if !slices.Contains(tlb.Algoritmos, config.DataCacheAlgorithm) {
+		log.Fatalf("Configuración de cache inválida: algoritmo desconocido %s (se puede usar %v)", config.DataCacheAlgorithm, tlb.Algoritmos)
+	}
+	if config.DataCacheWritePolicy != WriteBack && config.DataCacheWritePolicy != WriteThrough {
+		log.Fatalf("Configuración de cache inválida: data_cache_write_policy tiene que ser %s o %s", WriteBack, WriteThrough)
+	}
+	lineas := config.DataCacheSize / config.DataCacheLine
+	vias := config.DataCacheWays
+	if vias <= 0 || vias > lineas {
+		vias = lineas
+	}
+	if lineas%vias != 0 {
+		log.Fatalf("Configuración de cache inválida: las lineas (%d) tienen que ser multiplo de las vias (%d)", lineas, vias)
+	}
+	cacheDatos.tamLinea = config.DataCacheLine
+	cacheDatos.vias = vias
+	cacheDatos.conjuntos = make([][]lineaCache, lineas/vias)
+	cacheDatos.punteros = make([]int, lineas/vias)
+	cacheDatos.azar = rand.New(rand.NewSource(config.TLBSeed))
+}
+
+// Una linea no puede tener bytes de dos marcos: el marco de al lado puede ser de otro proceso y memoria
+// rechazaria la escritura de la linea sucia. El tamaño de pagina lo manda memoria cuando arranca
+func validarLineaCache(tamPagina int) error {
+	if cacheDatosHabilitada() && tamPagina%globals.ClientConfig.DataCacheLine != 0 {
+		return fmt.Errorf("data_cache_line (%d) tiene que dividir al tamaño de pagina (%d)", globals.ClientConfig.DataCacheLine, tamPagina)
+	}
+	return nil
+}
+
+// Hay que tener el mutex
+func estadisticasCache(pid int) *EstadisticasCache {
+	if cacheDatos.estadisticas[pid] == nil {
+		cacheDatos.estadisticas[pid] = &EstadisticasCache{}
+	}
+	return cacheDatos.estadisticas[pid]
+}
+
+// Hay que tener el mutex. Devuelve la linea (no una copia) o nil
+func buscarLinea(numero int) *lineaCache {
+	conjunto := cacheDatos.conjuntos[numero%len(cacheDatos.conjuntos)]
+	for i := range conjunto {
+		if conjunto[i].numero == numero {
+			cacheDatos.acceso++
+			conjunto[i].acceso = cacheDatos.acceso
+			conjunto[i].usos++
+			conjunto[i].referencia = true
+			return &conjunto[i]
+		}
+	}
+	return nil
+}
+
+// Hay que tener el mutex. Si el conjunto esta lleno devuelve la linea reemplazada para escribirla si esta sucia
+func agregarLinea(pid int, numero int, datos []byte) lineaCache {
+	cacheDatos.acceso++
+	nueva := lineaCache{pid: pid, numero: numero, datos: datos, posicionFila: cacheDatos.posicionFila, acceso: cacheDatos.acceso, usos: 1, referencia: true}
+	cacheDatos.posicionFila++
+	c := numero % len(cacheDatos.conjuntos)
+	if len(cacheDatos.conjuntos[c]) < cacheDatos.vias {
+		cacheDatos.conjuntos[c] = append(cacheDatos.conjuntos[c], nueva)
+		return lineaCache{}
+	}
+	victima := lineaVictima(c)
+	reemplazada := cacheDatos.conjuntos[c][victima]
+	estadisticasCache(reemplazada.pid).Reemplazos++
+	cacheDatos.conjuntos[c][victima] = nueva
+	return reemplazada
+}
+
+// Hay que tener el mutex y el conjunto tiene que estar lleno
+func lineaVictima(c int) int {
+	lineas := cacheDatos.conjuntos[c]
+	victima := 0
+	switch globals.ClientConfig.DataCacheAlgorithm {
+	case tlb.FIFO:
+		for i, linea := range lineas {
+			if linea.posicionFila < lineas[victima].posicionFila {
+				victima = i
+			}
+		}
+	case tlb.LRU:
+		for i, linea := range lineas {
+			if linea.acceso < lineas[victima].acceso {
+				victima = i
+			}
+		}
+	case tlb.LFU:
+		for i, linea := range lineas {
+			if linea.usos < lineas[victima].usos || (linea.usos == lineas[victima].usos && linea.posicionFila < lineas[victima].posicionFila) {
+				victima = i
+			}
+		}
+	case tlb.CLOCK:
+		for lineas[cacheDatos.punteros[c]].referencia {
+			lineas[cacheDatos.punteros[c]].referencia = false
+			cacheDatos.punteros[c] = (cacheDatos.punteros[c] + 1) % len(lineas)
+		}
+		victima = cacheDatos.punteros[c]
+		cacheDatos.punteros[c] = (cacheDatos.punteros[c] + 1) % len(lineas)
+	case tlb.RANDOM:
+		victima = cacheDatos.azar.Intn(len(lineas))
+	}
+	return victima
+}
+
+func direccionesLinea(numero int) []int {
+	direcciones := make([]int, cacheDatos.tamLinea)
+	for i := range direcciones {
+		direcciones[i] = numero*cacheDatos.tamLinea + i
+	}
+	return direcciones
+}
+
+func escribirLinea(linea lineaCache) error {
+	err := escribirMemoriaFisica(linea.pid, direccionesLinea(linea.numero), linea.datos)
+	cacheDatos.Lock()
+	estadisticasCache(linea.pid).Escrituras++
+	cacheDatos.Unlock()
+	return err
+}
+
+// Trae la linea de memoria si no esta, memoria controla el acceso (R o X) solo en los misses: en los hits
+// la MMU ya verifico los permisos de la pagina. El mutex no se tiene durante los pedidos a memoria, si
+// mientras tanto memoria invalido algun marco la linea no se agrega (puede ser de un marco que ya no es del proceso)
+func (c *Core) cargarLinea(pid int, numero int, acceso string) error {
+	cacheDatos.Lock()
+	if buscarLinea(numero) != nil {
+		estadisticasCache(pid).Hits++
+		cacheDatos.Unlock()
+		return nil
+	}
+	estadisticasCache(pid).Misses++
+	invalidaciones := cacheDatos.invalidaciones
+	cacheDatos.Unlock()
+
+	datos, err := c.leerMemoriaFisica(pid, direccionesLinea(numero), acceso)
+	if err != nil {
+		return err
+	}
+	if len(datos) < cacheDatos.tamLinea {
+		return fmt.Errorf("memoria devolvio %d bytes de %d", len(datos), cacheDatos.tamLinea)
+	}
+	cacheDatos.Lock()
+	if invalidaciones != cacheDatos.invalidaciones {
+		cacheDatos.Unlock()
+		return nil // Quien la pidio no la encuentra y va directo a memoria
+	}
+	reemplazada := agregarLinea(pid, numero, slices.Clone(datos[:cacheDatos.tamLinea]))
+	cacheDatos.Unlock()
+	if reemplazada.sucia {
+		return escribirLinea(reemplazada)
+	}
+	return nil
+}
+
+// Lineas de las direcciones, sin repetir y en orden de acceso
+func lineasDe(direcciones []int) []int {
+	var lineas []int
+	for _, direccion := range direcciones {
+		numero := direccion / cacheDatos.tamLinea
+		if !slices.Contains(lineas, numero) {
+			lineas = append(lineas, numero)
+		}
+	}
+	return lineas
+}
+
+// Devuelve los bytes de las direcciones, como leerMemoriaFisica. Si una linea no se puede traer
+// completa (en segmentacion puede pasarse del segmento) se lee directo de memoria sin cache
+func (c *Core) leerConCache(pid int, direcciones []int, acceso string) ([]byte, error) {
+	for _, numero := range lineasDe(direcciones) {
+		if err := c.cargarLinea(pid, numero, acceso); err != nil {
+			log.Printf("PID: %d - CACHE - No se pudo cargar la linea %d, se lee sin cache: %v", pid, numero, err)
+			return c.leerMemoriaFisica(pid, direcciones, acceso)
+		}
+	}
+
+	cacheDatos.Lock()
+	datos := make([]byte, len(direcciones))
+	for i, direccion := range direcciones {
+		linea := buscarLinea(direccion / cacheDatos.tamLinea)
+		if linea == nil { // Otra linea del mismo pedido la reemplazo (cache chica) o memoria invalido el marco
+			datos = nil
+			break
+		}
+		datos[i] = linea.datos[direccion%cacheDatos.tamLinea]
+	}
+	cacheDatos.Unlock()
+	if datos == nil {
+		return c.leerMemoriaFisica(pid, direcciones, acceso)
+	}
+	return datos, nil
+}
+
+// Write-through escribe en memoria y actualiza las lineas que esten (no las trae). Write-back trae las
+// lineas y las marca sucias, llegan a memoria cuando se reemplazan o cuando el proceso deja la CPU
+func (c *Core) escribirConCache(pid int, direcciones []int, data []byte) error {
+	if len(data) < len(direcciones) {
+		direcciones = direcciones[:len(data)]
+	}
+	if globals.ClientConfig.DataCacheWritePolicy == WriteThrough {
+		if err := escribirMemoriaFisica(pid, direcciones, data); err != nil {
+			return err
+		}
+		cacheDatos.Lock()
+		defer cacheDatos.Unlock()
+		estadisticasCache(pid).Escrituras++
+		for _, numero := range lineasDe(direcciones) {
+			if buscarLinea(numero) != nil {
+				estadisticasCache(pid).Hits++
+			} else {
+				estadisticasCache(pid).Misses++
+			}
+		}
+		for i, direccion := range direcciones {
+			if linea := buscarLinea(direccion / cacheDatos.tamLinea); linea != nil {
+				linea.datos[direccion%cacheDatos.tamLinea] = data[i]
+			}
+		}
+		return nil
+	}
+
+	for i, direccion := range direcciones {
+		numero := direccion / cacheDatos.tamLinea
+		if i == 0 || numero != direcciones[i-1]/cacheDatos.tamLinea {
+			if err := c.cargarLinea(pid, numero, "R"); err != nil {
+				log.Printf("PID: %d - CACHE - No se pudo cargar la linea %d, se escribe sin cache: %v", pid, numero, err)
+				return escribirMemoriaFisica(pid, direcciones[i:], data[i:])
+			}
+		}
+		cacheDatos.Lock()
+		linea := buscarLinea(numero)
+		if linea == nil {
+			cacheDatos.Unlock()
+			return escribirMemoriaFisica(pid, direcciones[i:], data[i:])
+		}
+		linea.datos[direccion%cacheDatos.tamLinea] = data[i]
+		linea.sucia = true
+		cacheDatos.Unlock()
+	}
+	return nil
+}
+
+// Escribe las lineas sucias en memoria y vacia la cache. Se llama cuando el proceso deja la CPU.
+// Las lineas siguen en la cache (limpias) mientras se escriben, asi otro core que las lea en ese momento
+// no va a memoria a buscar bytes viejos. Las que otro core ensucio mientras tanto se quedan
+func vaciarCacheDatos() {
+	if !cacheDatosHabilitada() {
+		return
+	}
+	cacheDatos.Lock()
+	var sucias []lineaCache
+	for c := range cacheDatos.conjuntos {
+		for i := range cacheDatos.conjuntos[c] {
+			if linea := &cacheDatos.conjuntos[c][i]; linea.sucia {
+				copia := *linea
+				copia.datos = slices.Clone(linea.datos)
+				sucias = append(sucias, copia)
+				linea.sucia = false
+			}
+		}
+	}
+	cacheDatos.Unlock()
+
+	for _, linea := range sucias {
+		// Si el proceso ya fue finalizado (por ejemplo por OUT_OF_MEMORY con KILL) memoria la rechaza
+		if err := escribirLinea(linea); err != nil {
+			log.Printf("PID: %d - CACHE - No se pudo escribir la linea %d: %v", linea.pid, linea.numero, err)
+		}
+	}
+
+	cacheDatos.Lock()
+	for c := range cacheDatos.conjuntos {
+		cacheDatos.conjuntos[c] = slices.DeleteFunc(cacheDatos.conjuntos[c], func(linea lineaCache) bool { return !linea.sucia })
+		cacheDatos.punteros[c] = 0
+	}
+	cacheDatos.Unlock()
+	if len(sucias) > 0 {
+		log.Printf("CACHE FLUSH - Lineas escritas en memoria: %d", len(sucias))
+	}
+}
+
+// Saca de la cache las lineas con bytes de los marcos y devuelve las que estaban sucias. Memoria lo pide
+// antes de desalojar, copiar o liberar un marco y escribe ella misma las lineas sucias: tiene su mutex
+// tomado, asi que la CPU no puede mandarlas a /writeMemory
+func invalidarMarcos(marcos []int) []BodyLineaSucia {
+	sucias := []BodyLineaSucia{}
+	if !cacheDatosHabilitada() || GLOBALpageTam <= 0 {
+		return sucias
+	}
+	cacheDatos.Lock()
+	defer cacheDatos.Unlock()
+	cacheDatos.invalidaciones++
+	enMarcos := func(linea lineaCache) bool {
+		inicio := linea.numero * cacheDatos.tamLinea
+		for _, marco := range marcos { // Una linea puede tener bytes de mas de un marco y al reves
+			if inicio < (marco+1)*GLOBALpageTam && marco*GLOBALpageTam < inicio+cacheDatos.tamLinea {
+				return true
+			}
+		}
+		return false
+	}
+	borradas := 0
+	for c := range cacheDatos.conjuntos {
+		cantidad := len(cacheDatos.conjuntos[c])
+		cacheDatos.conjuntos[c] = slices.DeleteFunc(cacheDatos.conjuntos[c], func(linea lineaCache) bool {
+			if !enMarcos(linea) {
+				return false
+			}
+			if linea.sucia {
+				sucias = append(sucias, BodyLineaSucia{Direccion: linea.numero * cacheDatos.tamLinea, Datos: linea.datos})
+			}
+			return true
+		})
+		borradas += cantidad - len(cacheDatos.conjuntos[c])
+		if cacheDatos.punteros[c] >= len(cacheDatos.conjuntos[c]) {
+			cacheDatos.punteros[c] = 0
+		}
+	}
+	if borradas > 0 {
+		log.Printf("CACHE - Marcos invalidados: %v - Lineas: %d - Sucias: %d", marcos, borradas, len(sucias))
+	}
+	return sucias
+}
+
+// POST /invalidateFrames {"frames":[3]}, responde las lineas sucias que memoria tiene que escribir
+func InvalidateFramesHandler(w http.ResponseWriter, r *http.Request) {
+	var body BodyMarcos
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invalidarMarcos(body.Marcos))
+}
+
+// GET /dataCache o GET /core/{id}/dataCache. La cache es una sola, todos los cores ven lo mismo
+func DataCacheHandler(w http.ResponseWriter, r *http.Request) {
+	if coreDelPedido(w, r) == nil {
+		return
+	}
+	config := globals.ClientConfig
+	estado := BodyEstadoCache{
+		Tamaño:       config.DataCacheSize,
+		TamLinea:     config.DataCacheLine,
+		Escritura:    config.DataCacheWritePolicy,
+		Algoritmo:    config.DataCacheAlgorithm,
+		Lineas:       []BodyLineaCache{},
+		Estadisticas: make(map[int]EstadisticasCache),
+	}
+	cacheDatos.Lock()
+	estado.Vias = cacheDatos.vias
+	for c, conjunto := range cacheDatos.conjuntos {
+		for _, linea := range conjunto {
+			estado.Lineas = append(estado.Lineas, BodyLineaCache{Conjunto: c, Pid: linea.pid, Inicio: linea.numero * cacheDatos.tamLinea, Sucia: linea.sucia})
+		}
+	}
+	for pid, estadisticas := range cacheDatos.estadisticas {
+		copia := *estadisticas
+		if copia.Hits+copia.Misses > 0 {
+			copia.TasaHits = float64(copia.Hits) / float64(copia.Hits+copia.Misses)
+		}
+		estado.Estadisticas[pid] = copia
+	}
+	cacheDatos.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(estado)
+}
@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+	"github.com/sisoputnfrba/tp-golang/cpu/tlb"
+)
+
+// Deja la cache vacia con la configuracion pedida y la restaura al terminar el test
+func configurarCache(t *testing.T, tamaño, tamLinea, vias int, algoritmo string, tamPagina int) {
+	t.Helper()
+	config, pagina := globals.ClientConfig, GLOBALpageTam
+	t.Cleanup(func() { globals.ClientConfig, GLOBALpageTam = config, pagina })
+
+	globals.ClientConfig = &globals.Config{
+		DataCacheSize:        tamaño,
+		DataCacheLine:        tamLinea,
+		DataCacheWays:        vias,
+		DataCacheAlgorithm:   algoritmo,
+		DataCacheWritePolicy: WriteBack,
+	}
+	GLOBALpageTam = tamPagina
+	cacheDatos.invalidaciones, cacheDatos.posicionFila, cacheDatos.acceso = 0, 0, 0
+	cacheDatos.estadisticas = make(map[int]*EstadisticasCache)
+	cacheDatos.azar = rand.New(rand.NewSource(0))
+	iniciarCacheDatos()
+}
+
+// Hace lo mismo que cargarLinea sin pedirle nada a memoria
+func accederLineas(pid int, numeros ...int) {
+	cacheDatos.Lock()
+	defer cacheDatos.Unlock()
+	for _, numero := range numeros {
+		if buscarLinea(numero) == nil {
+			agregarLinea(pid, numero, make([]byte, cacheDatos.tamLinea))
+		}
+	}
+}
+
+func lineasCargadas() []int {
+	var numeros []int
+	for _, conjunto := range cacheDatos.conjuntos {
+		for _, linea := range conjunto {
+			numeros = append(numeros, linea.numero)
+		}
+	}
+	slices.Sort(numeros)
+	return numeros
+}
+
+func TestReemplazoCache(t *testing.T) {
+	casos := []struct {
+		algoritmo string
+		accesos   []int
+		quedan    []int
+	}{
+		{tlb.FIFO, []int{0, 1, 2, 3, 1, 4}, []int{2, 3, 4}},
+		{tlb.LRU, []int{0, 1, 2, 3, 1, 4}, []int{1, 3, 4}},
+		{tlb.LFU, []int{0, 0, 1, 2, 3}, []int{0, 2, 3}},
+		{tlb.LRU, []int{0, 0, 1, 2, 3}, []int{1, 2, 3}},
+		{tlb.CLOCK, []int{0, 1, 2, 3, 1, 4}, []int{1, 3, 4}},
+		{tlb.CLOCK, []int{0, 1, 2, 3, 4, 0, 5}, []int{0, 4, 5}},
+	}
+	for _, caso := range casos {
+		t.Run(caso.algoritmo, func(t *testing.T) {
+			configurarCache(t, 12, 4, 0, caso.algoritmo, 16)
+			accederLineas(1, caso.accesos...)
+			if quedan := lineasCargadas(); !reflect.DeepEqual(quedan, caso.quedan) {
+				t.Errorf("accesos %v: quedan %v, se esperaba %v", caso.accesos, quedan, caso.quedan)
+			}
+		})
+	}
+}
+
+func TestRandomCacheCompleta(t *testing.T) {
+	configurarCache(t, 16, 4, 0, tlb.RANDOM, 16)
+	accederLineas(1, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	if quedan := lineasCargadas(); len(quedan) != 4 || !slices.Contains(quedan, 9) {
+		t.Errorf("quedan %v, se esperaban 4 lineas con la ultima que se agrego", quedan)
+	}
+}
+
+func TestAgregarLineaDevuelveReemplazada(t *testing.T) {
+	// 4 lineas en 2 conjuntos de 2 vias: las lineas pares van al conjunto 0 y las impares al 1
+	configurarCache(t, 16, 4, 2, tlb.FIFO, 16)
+	accederLineas(1, 0, 1, 2)
+	cacheDatos.Lock()
+	buscarLinea(0).sucia = true
+	reemplazada := agregarLinea(2, 4, make([]byte, 4))
+	libre := agregarLinea(2, 3, make([]byte, 4))
+	cacheDatos.Unlock()
+
+	if !reemplazada.sucia || reemplazada.numero != 0 || reemplazada.pid != 1 {
+		t.Errorf("reemplazada = %+v, se esperaba la linea 0 sucia del PID 1", reemplazada)
+	}
+	if libre.sucia || libre.datos != nil {
+		t.Errorf("con lugar en el conjunto no se reemplaza nada y llego %+v", libre)
+	}
+	if quedan := lineasCargadas(); !reflect.DeepEqual(quedan, []int{1, 2, 3, 4}) {
+		t.Errorf("quedan %v, se esperaba [1 2 3 4]", quedan)
+	}
+	if reemplazos := cacheDatos.estadisticas[1].Reemplazos; reemplazos != 1 {
+		t.Errorf("reemplazos del PID 1 = %d, se esperaba 1", reemplazos)
+	}
+}
+
+func TestInvalidarMarcos(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		tamLinea  int
+		tamPagina int
+		cargadas  []int
+		sucias    []int
+		marcos    []int
+		quedan    []int
+		escribir  []int // Direcciones de las lineas sucias que vuelven a memoria
+	}{
+		{"dos lineas por marco", 4, 8, []int{0, 1, 2, 3, 4}, []int{2, 4}, []int{1}, []int{0, 1, 4}, []int{8}},
+		{"varios marcos", 4, 8, []int{0, 1, 2, 3, 4}, []int{0, 3}, []int{0, 2}, []int{2, 3}, []int{0}},
+		{"linea con dos marcos", 16, 8, []int{0, 1}, []int{0}, []int{1}, []int{1}, []int{0}},
+		{"marco sin lineas", 4, 8, []int{0, 1}, []int{0}, []int{5}, []int{0, 1}, []int{}},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			configurarCache(t, 8*caso.tamLinea, caso.tamLinea, 0, tlb.FIFO, caso.tamPagina)
+			accederLineas(1, caso.cargadas...)
+			cacheDatos.Lock()
+			for _, numero := range caso.sucias {
+				buscarLinea(numero).sucia = true
+			}
+			cacheDatos.Unlock()
+
+			escribir := []int{}
+			for _, linea := range invalidarMarcos(caso.marcos) {
+				escribir = append(escribir, linea.Direccion)
+			}
+			slices.Sort(escribir)
+			if quedan := lineasCargadas(); !reflect.DeepEqual(quedan, caso.quedan) {
+				t.Errorf("quedan %v, se esperaba %v", quedan, caso.quedan)
+			}
+			if !reflect.DeepEqual(escribir, caso.escribir) {
+				t.Errorf("lineas sucias %v, se esperaba %v", escribir, caso.escribir)
+			}
+			if cacheDatos.invalidaciones != 1 {
+				t.Errorf("invalidaciones = %d, se esperaba 1", cacheDatos.invalidaciones)
+			}
+		})
+	}
+}
+
+func TestInvalidarMarcosSinCache(t *testing.T) {
+	configurarCache(t, 0, 0, 0, "", 8)
+	if sucias := invalidarMarcos([]int{0}); sucias == nil || len(sucias) != 0 {
+		t.Errorf("sin cache se esperaba una lista vacia y llego %v", sucias)
+	}
+}
+
+func TestVaciarCacheDatos(t *testing.T) {
+	configurarCache(t, 16, 4, 0, tlb.FIFO, 16)
+	escritas := make(map[int]string)
+	memoria := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req MemoryReadRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		escritas[req.Address[0]] = string(req.Data)
+		cacheDatos.Lock()
+		defer cacheDatos.Unlock()
+		// Mientras se escribe la linea sigue en la cache, y otro core ensucia la linea 1
+		if linea := buscarLinea(req.Address[0] / 4); linea == nil {
+			t.Errorf("la linea de %d no esta en la cache mientras se escribe", req.Address[0])
+		}
+		if linea := buscarLinea(1); linea != nil {
+			linea.datos[0], linea.sucia = 'Z', true
+		}
+	}))
+	defer memoria.Close()
+	url, _ := neturl.Parse(memoria.URL)
+	globals.ClientConfig.IPMemory = url.Hostname()
+	globals.ClientConfig.PortMemory, _ = strconv.Atoi(url.Port())
+
+	accederLineas(1, 0, 1, 2)
+	cacheDatos.Lock()
+	copy(buscarLinea(0).datos, "abcd")
+	buscarLinea(0).sucia = true
+	cacheDatos.Unlock()
+
+	vaciarCacheDatos()
+	if escritas[0] != "abcd" || len(escritas) != 1 {
+		t.Errorf("escritas = %v, se esperaba solo la linea 0", escritas)
+	}
+	if quedan := lineasCargadas(); !reflect.DeepEqual(quedan, []int{1}) {
+		t.Errorf("quedan %v, se esperaba solo la linea que ensucio otro core", quedan)
+	}
+}
+
+func TestValidarLineaCache(t *testing.T) {
+	casos := []struct {
+		tamaño, tamLinea, tamPagina int
+		valida                      bool
+	}{
+		{64, 16, 32, true},
+		{64, 32, 32, true},
+		{64, 16, 24, false},
+		{64, 64, 32, false},
+		{0, 0, 24, true}, // Sin cache no importa
+	}
+	for _, caso := range casos {
+		configurarCache(t, caso.tamaño, caso.tamLinea, 0, tlb.FIFO, 0)
+		if err := validarLineaCache(caso.tamPagina); (err == nil) != caso.valida {
+			t.Errorf("linea %d y pagina %d: error = %v, se esperaba valida = %v", caso.tamLinea, caso.tamPagina, err, caso.valida)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+	"github.com/sisoputnfrba/tp-golang/cpu/tlb"
+)
+
+// Un core de la CPU: el contexto del proceso que ejecuta, su linea de interrupcion, su TLB, su cache de
+// instrucciones y su estado del depurador. Todos los cores comparten el puerto y la cache de datos
+type Core struct {
+	ID int
+
+	ocupado   sync.Mutex    // Tomado mientras el core ejecuta un proceso
+	contexto  PCB           // PCB recibido desde kernel
+	request   KernelRequest // Lo que se le devuelve al kernel cuando el proceso deja el core
+	dispatch  int           // Id del despacho del proceso en ejecucion, el kernel lo usa para reconocer la respuesta
+	interrupt bool          // El proceso tiene que dejar el core despues de esta instruccion
+
+	interrupciones controladorInterrupciones // Interrupciones que mando el kernel y todavia no se atendieron
+
+	tlb       *tlb.TLB
+	ultimoPid int // PID del ultimo proceso que ejecuto, para saber si hubo cambio de contexto
+
+	pidPrefetch     int // Proceso de los bloques del cache de instrucciones (con el mutex de prefetch)
+	bloquesPrefetch map[int][]string
+
+	registroTraza *RegistroTraza // Instruccion en ejecucion, nil fuera de Execute (con el mutex de traza)
+
+	depuracion depuracionCore // Pausa del core (con el mutex de debug)
+
+	// Memoria devuelve las lecturas y los marcos llamando a /core/{id}/receiveDataFromMemory y
+	// /core/{id}/recieveFrame. El mutex se tiene desde el pedido hasta leer la respuesta, asi cada core
+	// espera solo sus propios pedidos
+	bus          sync.Mutex
+	datosMemoria []byte
+	marcoMemoria BodyFrame
+}
+
+var cores []*Core
+
+func nuevoCore(id int) *Core {
+	t, err := tlb.Nueva(globals.ClientConfig.NumberFellingTLB, globals.ClientConfig.AlgorithmTLB, globals.ClientConfig.TLBWays, globals.ClientConfig.TLBSeed)
+	if err != nil {
+		log.Fatalf("Configuración de TLB inválida: %v", err)
+	}
+	return &Core{
+		ID:              id,
+		tlb:             t,
+		ultimoPid:       -1,
+		pidPrefetch:     -1,
+		bloquesPrefetch: make(map[int][]string),
+		interrupciones:  controladorInterrupciones{pid: -1},
+		depuracion:      nuevaDepuracionCore(),
+	}
+}
+
+func iniciarCores() {
+	cantidad := max(globals.ClientConfig.Cores, 1)
+	cores = make([]*Core, cantidad)
+	for id := range cores {
+		cores[id] = nuevoCore(id)
+	}
+	if cantidad > 1 {
+		log.Printf("CPU con %d cores", cantidad)
+	}
+}
+
+// Core del pedido: /core/{id}/... o el core 0 en las rutas sin id (una CPU de un core como antes).
+// Si el id no existe responde 404 y devuelve nil
+func coreDelPedido(w http.ResponseWriter, r *http.Request) *Core {
+	idStr := r.PathValue("id")
+	if idStr == "" {
+		return cores[0]
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id < 0 || id >= len(cores) {
+		http.Error(w, fmt.Sprintf("el core %s no existe", idStr), http.StatusNotFound)
+		return nil
+	}
+	return cores[id]
+}
@@ -0,0 +1,304 @@
+package utils
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+)
+
+type BodyBreakpoint struct {
+	Pid int    `json:"pid"`
+	PC  uint32 `json:"pc"`
+}
+
+type BodyEstadoDebug struct {
+	Pausado     bool             `json:"paused"`
+	Pid         int              `json:"pid"`
+	Core        int              `json:"core"`
+	Registros   *RegisterCPU     `json:"registers,omitempty"`
+	Breakpoints []BodyBreakpoint `json:"breakpoints"`
+}
+
+type BodyTraduccionDebug struct {
+	Pid         int    `json:"pid"`
+	Direccion   int    `json:"address"`
+	Tamaño      int    `json:"size"`
+	Direcciones []int  `json:"physical_addresses"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Cuanto espera step/continue a que la CPU vuelva a frenar antes de responder
+const esperaDebug = 5 * time.Second
+
+// Estado del depurador. Los breakpoints son por proceso y frenan al core que lo este ejecutando
+var debug = struct {
+	sync.Mutex
+	breakpoints map[int]map[uint32]bool
+}{
+	breakpoints: make(map[int]map[uint32]bool),
+}
+
+// Pausa de un core (con el mutex de debug). Mientras el core esta pausado su ciclo de instruccion queda
+// bloqueado en puntoDeParada y los handlers pueden leer y modificar el contexto, los demas cores siguen
+type depuracionCore struct {
+	pausarSiguiente bool // Step o pause: frena antes de la proxima instruccion del core
+	contexto        *PCB // Contexto del proceso pausado, nil si el core no esta pausado
+	reanudar        chan struct{}
+	detenida        chan struct{} // Avisa que el core se pauso o que el proceso dejo el core
+}
+
+func nuevaDepuracionCore() depuracionCore {
+	return depuracionCore{
+		reanudar: make(chan struct{}),
+		detenida: make(chan struct{}, 1),
+	}
+}
+
+func debugHabilitado() bool {
+	return globals.ClientConfig != nil && globals.ClientConfig.Debug
+}
+
+// Se llama antes de cada FETCH. Si hay que frenar, bloquea hasta que llegue step o continue
+func (c *Core) puntoDeParada(contexto *PCB) {
+	if !debugHabilitado() {
+		return
+	}
+	debug.Lock()
+	if !c.depuracion.pausarSiguiente && !debug.breakpoints[contexto.Pid][contexto.CpuReg.PC] {
+		debug.Unlock()
+		return
+	}
+	c.depuracion.pausarSiguiente = false
+	c.depuracion.contexto = contexto
+	debug.Unlock()
+
+	log.Printf("PID: %d - DEBUG - Pausado - Core: %d - Program Counter: %d", contexto.Pid, c.ID, contexto.CpuReg.PC)
+	c.avisarDetencion()
+	<-c.depuracion.reanudar
+}
+
+func (c *Core) avisarDetencion() {
+	select {
+	case c.depuracion.detenida <- struct{}{}:
+	default:
+	}
+}
+
+// Al terminar InstructionCycle, para que step/continue no esperen de mas
+func (c *Core) procesoFueraDeCPU() {
+	if debugHabilitado() {
+		c.avisarDetencion()
+	}
+}
+
+func (c *Core) estadoDebug() BodyEstadoDebug {
+	debug.Lock()
+	defer debug.Unlock()
+	estado := BodyEstadoDebug{Core: c.ID, Breakpoints: []BodyBreakpoint{}}
+	if contexto := c.depuracion.contexto; contexto != nil {
+		registros := contexto.CpuReg
+		estado.Pausado = true
+		estado.Pid = contexto.Pid
+		estado.Registros = &registros
+	}
+	for pid, pcs := range debug.breakpoints {
+		for pc := range pcs {
+			estado.Breakpoints = append(estado.Breakpoints, BodyBreakpoint{Pid: pid, PC: pc})
+		}
+	}
+	sort.Slice(estado.Breakpoints, func(i, j int) bool {
+		a, b := estado.Breakpoints[i], estado.Breakpoints[j]
+		return a.Pid < b.Pid || (a.Pid == b.Pid && a.PC < b.PC)
+	})
+	return estado
+}
+
+func responderEstadoDebug(w http.ResponseWriter, c *Core) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.estadoDebug())
+}
+
+// GET /debug/state (core 0) o GET /core/{id}/debug/state
+func DebugStateHandler(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
+	responderEstadoDebug(w, c)
+}
+
+// POST /debug/breakpoints {"pid":1,"pc":4}. Frena al core que este ejecutando el proceso
+func AddBreakpointHandler(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
+	var body BodyBreakpoint
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	debug.Lock()
+	if debug.breakpoints[body.Pid] == nil {
+		debug.breakpoints[body.Pid] = make(map[uint32]bool)
+	}
+	debug.breakpoints[body.Pid][body.PC] = true
+	debug.Unlock()
+	log.Printf("PID: %d - DEBUG - Breakpoint en PC: %d", body.Pid, body.PC)
+	responderEstadoDebug(w, c)
+}
+
+// DELETE /debug/breakpoints?pid=&pc= (sin pc borra todos los del proceso)
+func DeleteBreakpointHandler(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
+	pid, err := strconv.Atoi(r.URL.Query().Get("pid"))
+	if err != nil {
+		http.Error(w, "PID inválido", http.StatusBadRequest)
+		return
+	}
+	debug.Lock()
+	if pcStr := r.URL.Query().Get("pc"); pcStr != "" {
+		pc, err := strconv.ParseUint(pcStr, 10, 32)
+		if err != nil {
+			debug.Unlock()
+			http.Error(w, "PC inválido", http.StatusBadRequest)
+			return
+		}
+		delete(debug.breakpoints[pid], uint32(pc))
+	} else {
+		delete(debug.breakpoints, pid)
+	}
+	debug.Unlock()
+	responderEstadoDebug(w, c)
+}
+
+// POST /debug/pause: frena el core antes de la proxima instruccion que ejecute
+func PauseHandler(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
+	debug.Lock()
+	c.depuracion.pausarSiguiente = true
+	debug.Unlock()
+	responderEstadoDebug(w, c)
+}
+
+// POST /debug/step ejecuta una instruccion, POST /debug/continue sigue hasta el proximo breakpoint.
+// Responden cuando el core vuelve a frenar, cuando el proceso deja el core o a los 5 segundos
+func reanudarHandler(paso bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := coreDelPedido(w, r)
+		if c == nil {
+			return
+		}
+		debug.Lock()
+		if c.depuracion.contexto == nil {
+			debug.Unlock()
+			http.Error(w, "el core no está pausado", http.StatusConflict)
+			return
+		}
+		c.depuracion.contexto = nil
+		c.depuracion.pausarSiguiente = paso
+		debug.Unlock()
+
+		select { // Se descarta un aviso viejo
+		case <-c.depuracion.detenida:
+		default:
+		}
+		c.depuracion.reanudar <- struct{}{}
+		select {
+		case <-c.depuracion.detenida:
+		case <-time.After(esperaDebug):
+		}
+		responderEstadoDebug(w, c)
+	}
+}
+
+var StepHandler = reanudarHandler(true)
+var ContinueHandler = reanudarHandler(false)
+
+// PUT /debug/registers {"AX": 5, "PC": 0} modifica los registros del proceso pausado
+func SetRegistersHandler(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
+	var registros map[string]uint32
+	if err := json.NewDecoder(r.Body).Decode(&registros); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	debug.Lock()
+	contexto := c.depuracion.contexto
+	if contexto == nil {
+		debug.Unlock()
+		http.Error(w, "el core no está pausado", http.StatusConflict)
+		return
+	}
+	copia := contexto.CpuReg // Si algun registro falla no se cambia ninguno
+	for nombre, valor := range registros {
+		if err := SetCampo(&copia, nombre, valor); err != nil {
+			debug.Unlock()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	contexto.CpuReg = copia
+	log.Printf("PID: %d - DEBUG - Registros modificados: %v", contexto.Pid, registros)
+	debug.Unlock()
+	responderEstadoDebug(w, c)
+}
+
+// GET /debug/translate?address=&size= traduce con la MMU para el proceso pausado. Si la traduccion
+// da PAGE_FAULT o SEGMENTATION_FAULT se informa sin desalojar al proceso
+func DebugTranslateHandler(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
+	direccion, err := strconv.Atoi(r.URL.Query().Get("address"))
+	if err != nil {
+		http.Error(w, "Dirección inválida", http.StatusBadRequest)
+		return
+	}
+	tam := 1
+	if tamStr := r.URL.Query().Get("size"); tamStr != "" {
+		if tam, err = strconv.Atoi(tamStr); err != nil || tam <= 0 {
+			http.Error(w, "Tamaño inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	debug.Lock()
+	defer debug.Unlock()
+	contexto := c.depuracion.contexto
+	if contexto == nil {
+		http.Error(w, "el core no está pausado", http.StatusConflict)
+		return
+	}
+
+	traduccion := BodyTraduccionDebug{Pid: contexto.Pid, Direccion: direccion, Tamaño: tam}
+	interruptAntes, requestAntes := c.interrupt, c.request
+	traduccion.Direcciones = c.TranslateAddress(contexto.Pid, direccion, GLOBALpageTam, tam)
+	if traduccion.Direcciones == nil {
+		traduccion.Error = c.request.MotivoDesalojo
+		if traduccion.Error == "" {
+			traduccion.Error = "no se pudo traducir la dirección"
+		}
+	}
+	c.interrupt, c.request = interruptAntes, requestAntes
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(traduccion)
+}
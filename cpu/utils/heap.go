@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+)
+
+type BodyMalloc struct {
+	Pid       int    `json:"pid"`
+	Size      int    `json:"size,omitempty"`
+	Direccion int    `json:"address"`
+	Resultado string `json:"result,omitempty"`
+	Politica  string `json:"policy,omitempty"`
+}
+
+// MALLOC <reg_size> <reg_dest>: reserva un bloque en el heap del proceso y deja la direccion logica en reg_dest
+func (c *Core) MALLOC(words []string, contextoEjecucion *PCB) error {
+	if len(words) < 3 {
+		return fmt.Errorf("MALLOC necesita el registro de tamaño y el de destino")
+	}
+	if tamRegistro(words[2]) != 4 { // En un registro de 8 bits la direccion se truncaria y el bloque quedaria perdido
+		return fmt.Errorf("MALLOC necesita un registro de 32 bits de destino, %s no lo es", words[2])
+	}
+	tam := verificarRegistro(words[1], contextoEjecucion)
+
+	respuesta, err := pedirHeap("malloc", BodyMalloc{Pid: contextoEjecucion.Pid, Size: tam})
+	if err != nil {
+		return err
+	}
+	var bloque BodyMalloc
+	if err := json.NewDecoder(bytes.NewReader(respuesta)).Decode(&bloque); err != nil {
+		return fmt.Errorf("error al decodificar la respuesta de memoria: %v", err)
+	}
+
+	// Si no hay memoria se hace lo mismo que con RESIZE segun la politica de memoria
+	c.resultadoResize(BodyResize{Resultado: bloque.Resultado, Politica: bloque.Politica}, contextoEjecucion)
+	if bloque.Resultado != "OK" {
+		return nil
+	}
+	log.Printf("PID: %d - MALLOC - Tamaño: %d - Direccion: %d", contextoEjecucion.Pid, tam, bloque.Direccion)
+	return SetCampo(&contextoEjecucion.CpuReg, words[2], bloque.Direccion)
+}
+
+// FREE <reg_addr>: libera el bloque, si la direccion no es de un bloque reservado es SEGMENTATION_FAULT
+func (c *Core) FREE(words []string, contextoEjecucion *PCB) error {
+	if len(words) < 2 {
+		return fmt.Errorf("FREE necesita el registro con la direccion")
+	}
+	direccion := verificarRegistro(words[1], contextoEjecucion)
+
+	if _, err := pedirHeap("free", BodyMalloc{Pid: contextoEjecucion.Pid, Direccion: direccion}); err != nil {
+		log.Printf("PID: %d - SEGMENTATION FAULT - FREE de la direccion %d: %v", contextoEjecucion.Pid, direccion, err)
+		c.generarSegFault()
+		return nil
+	}
+	log.Printf("PID: %d - FREE - Direccion: %d", contextoEjecucion.Pid, direccion)
+	return nil
+}
+
+func pedirHeap(endpoint string, body BodyMalloc) ([]byte, error) {
+	memoriaURL := fmt.Sprintf("http://%s:%d/%s", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory, endpoint)
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Post(memoriaURL, "application/json", bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var respuesta bytes.Buffer
+	respuesta.ReadFrom(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", bytes.TrimSpace(respuesta.Bytes()))
+	}
+	return respuesta.Bytes(), nil
+}
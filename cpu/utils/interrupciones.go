@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+)
+
+type BodyInterrupcion struct {
+	Pid          int    `json:"pid"`
+	Motivo       string `json:"motivo"`
+	Prioridad    int    `json:"priority"`
+	Enmascarable bool   `json:"maskable"`
+}
+
+type BodyEstadoInterrupciones struct {
+	Core        int                `json:"core"`
+	Pid         int                `json:"pid"`     // -1 si el core no esta ejecutando
+	Pendientes  []BodyInterrupcion `json:"pending"` // En el orden en que se atenderian
+	Descartadas int                `json:"dropped"`
+	Despacho    int                `json:"dispatch"`  // Despacho en ejecucion, 0 si el core esta libre
+	Enmascarado bool               `json:"masked"`    // El proceso tiene las interrupciones enmascaradas (CLI)
+	Depurando   bool               `json:"debugging"` // El core esta pausado por el depurador
+}
+
+// Se usan si la config no trae interrupt_priorities o maskable_interrupts
+var prioridadesPorDefecto = map[string]int{"INTERRUPTED_BY_USER": 2, "CLOCK": 1}
+var enmascarablesPorDefecto = []string{"CLOCK"}
+
+// Controlador de interrupciones de un core. Las interrupciones del kernel quedan pendientes hasta el final
+// de la instruccion en curso y se atiende la de mayor prioridad (a igual prioridad la que llego primero).
+// Con la mascara (CLI) las enmascarables siguen pendientes hasta el STI. Una interrupcion es para un PID:
+// si ese proceso no es el que esta en el core se descarta, asi un CLOCK atrasado no desaloja al siguiente
+type controladorInterrupciones struct {
+	sync.Mutex
+	pid         int // Proceso en ejecucion, -1 si el core esta libre
+	despacho    int // Despacho del proceso en ejecucion, el kernel lo consulta antes de dar el despacho por perdido
+	enmascarado bool
+	pendientes  []ResponseInterrupt
+	descartadas int
+}
+
+func prioridadInterrupcion(motivo string) int {
+	if prioridad, exists := globals.ClientConfig.InterruptPriorities[motivo]; exists {
+		return prioridad
+	}
+	return prioridadesPorDefecto[motivo]
+}
+
+func interrupcionEnmascarable(motivo string) bool {
+	if globals.ClientConfig.MaskableInterrupts != nil {
+		return slices.Contains(globals.ClientConfig.MaskableInterrupts, motivo)
+	}
+	return slices.Contains(enmascarablesPorDefecto, motivo)
+}
+
+// Hay que tener el mutex
+func (ci *controladorInterrupciones) descartar(i int, causa string) {
+	interrupcion := ci.pendientes[i]
+	log.Printf("PID: %d - Interrupción descartada - Motivo: %s - %s", interrupcion.Pid, interrupcion.Motivo, causa)
+	ci.pendientes = slices.Delete(ci.pendientes, i, i+1)
+	ci.descartadas++
+}
+
+// Llega una interrupcion del kernel. Si el core esta libre queda pendiente: puede ser para el proceso que
+// el kernel esta por mandar
+func (ci *controladorInterrupciones) recibir(interrupcion ResponseInterrupt) {
+	ci.Lock()
+	defer ci.Unlock()
+	if !interrupcion.Interrupt {
+		return
+	}
+	ci.pendientes = append(ci.pendientes, interrupcion)
+	if ci.pid != -1 && interrupcion.Pid != ci.pid {
+		ci.descartar(len(ci.pendientes)-1, "el proceso no está en ejecución")
+	}
+}
+
+// Empieza a ejecutar un proceso: se descartan las interrupciones de otros procesos
+func (ci *controladorInterrupciones) iniciar(pid int, despacho int, enmascarado bool) {
+	ci.Lock()
+	defer ci.Unlock()
+	ci.pid = pid
+	ci.despacho = despacho
+	ci.enmascarado = enmascarado
+	for i := len(ci.pendientes) - 1; i >= 0; i-- {
+		if ci.pendientes[i].Pid != pid {
+			ci.descartar(i, "llegó para un proceso anterior")
+		}
+	}
+}
+
+// El proceso deja el core: lo que quedo pendiente ya no aplica, el kernel recibe el motivo real del desalojo
+func (ci *controladorInterrupciones) terminar() {
+	ci.Lock()
+	defer ci.Unlock()
+	for i := len(ci.pendientes) - 1; i >= 0; i-- {
+		ci.descartar(i, "el proceso dejó la CPU")
+	}
+	ci.pid = -1
+	ci.despacho = 0
+	ci.enmascarado = false
+}
+
+// Saca la interrupcion que hay que atender despues de la instruccion, si hay alguna
+func (ci *controladorInterrupciones) atender(enmascaradas bool) (ResponseInterrupt, bool) {
+	ci.Lock()
+	defer ci.Unlock()
+	ci.enmascarado = enmascaradas
+	elegida := -1
+	for i, interrupcion := range ci.pendientes {
+		if enmascaradas && interrupcionEnmascarable(interrupcion.Motivo) {
+			continue
+		}
+		if elegida == -1 || prioridadInterrupcion(interrupcion.Motivo) > prioridadInterrupcion(ci.pendientes[elegida].Motivo) {
+			elegida = i
+		}
+	}
+	if elegida == -1 {
+		return ResponseInterrupt{}, false
+	}
+	interrupcion := ci.pendientes[elegida]
+	ci.pendientes = slices.Delete(ci.pendientes, elegida, elegida+1)
+	return interrupcion, true
+}
+
+func (ci *controladorInterrupciones) estado() BodyEstadoInterrupciones {
+	ci.Lock()
+	defer ci.Unlock()
+	estado := BodyEstadoInterrupciones{Pid: ci.pid, Pendientes: []BodyInterrupcion{}, Descartadas: ci.descartadas, Despacho: ci.despacho, Enmascarado: ci.enmascarado}
+	for _, interrupcion := range ci.pendientes {
+		estado.Pendientes = append(estado.Pendientes, BodyInterrupcion{
+			Pid:          interrupcion.Pid,
+			Motivo:       interrupcion.Motivo,
+			Prioridad:    prioridadInterrupcion(interrupcion.Motivo),
+			Enmascarable: interrupcionEnmascarable(interrupcion.Motivo),
+		})
+	}
+	slices.SortStableFunc(estado.Pendientes, func(a, b BodyInterrupcion) int { return b.Prioridad - a.Prioridad })
+	return estado
+}
+
+// GET /interrupts (core 0) o GET /core/{id}/interrupts. El kernel lo consulta cuando un despacho no
+// termina a tiempo: mientras el core tenga el despacho (enmascarado, en el depurador o ejecutando) lo espera
+func InterruptsHandler(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
+	estado := c.interrupciones.estado()
+	estado.Core = c.ID
+	debug.Lock()
+	estado.Depurando = c.depuracion.contexto != nil
+	debug.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(estado)
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+)
+
+func interrupcion(pid int, motivo string) ResponseInterrupt {
+	return ResponseInterrupt{Interrupt: true, Pid: pid, Motivo: motivo}
+}
+
+func TestAtenderInterrupciones(t *testing.T) {
+	casos := []struct {
+		nombre      string
+		config      globals.Config
+		antes       []ResponseInterrupt // Llegan con el core libre
+		durante     []ResponseInterrupt // Llegan con el PID 1 en el core
+		enmascarado bool
+		atendidas   []string // Motivos en el orden en que se atienden
+		descartadas int
+		quedan      int // Pendientes despues de atender todo lo posible
+	}{
+		{"la de mayor prioridad primero", globals.Config{}, nil,
+			[]ResponseInterrupt{interrupcion(1, "CLOCK"), interrupcion(1, "INTERRUPTED_BY_USER")}, false,
+			[]string{"INTERRUPTED_BY_USER", "CLOCK"}, 0, 0},
+		{"a igual prioridad la que llego primero", globals.Config{}, nil,
+			[]ResponseInterrupt{interrupcion(1, "CLOCK"), interrupcion(1, "DISPATCH_TIMEOUT"), interrupcion(1, "OTRA")}, false,
+			[]string{"CLOCK", "DISPATCH_TIMEOUT", "OTRA"}, 0, 0},
+		{"prioridades de la config", globals.Config{InterruptPriorities: map[string]int{"CLOCK": 5}}, nil,
+			[]ResponseInterrupt{interrupcion(1, "INTERRUPTED_BY_USER"), interrupcion(1, "CLOCK")}, false,
+			[]string{"CLOCK", "INTERRUPTED_BY_USER"}, 0, 0},
+		{"enmascarada queda pendiente", globals.Config{}, nil,
+			[]ResponseInterrupt{interrupcion(1, "CLOCK"), interrupcion(1, "INTERRUPTED_BY_USER")}, true,
+			[]string{"INTERRUPTED_BY_USER"}, 0, 1},
+		{"enmascarables de la config", globals.Config{MaskableInterrupts: []string{}}, nil,
+			[]ResponseInterrupt{interrupcion(1, "CLOCK")}, true,
+			[]string{"CLOCK"}, 0, 0},
+		{"de otro proceso se descarta", globals.Config{}, nil,
+			[]ResponseInterrupt{interrupcion(2, "CLOCK"), interrupcion(1, "CLOCK")}, false,
+			[]string{"CLOCK"}, 1, 0},
+		{"pendiente de un proceso anterior", globals.Config{}, []ResponseInterrupt{interrupcion(3, "CLOCK"), interrupcion(1, "INTERRUPTED_BY_USER")},
+			nil, false,
+			[]string{"INTERRUPTED_BY_USER"}, 1, 0},
+		{"sin interrupt no queda pendiente", globals.Config{}, nil,
+			[]ResponseInterrupt{{Pid: 1, Motivo: "CLOCK"}}, false,
+			nil, 0, 0},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			config := globals.ClientConfig
+			t.Cleanup(func() { globals.ClientConfig = config })
+			globals.ClientConfig = &caso.config
+
+			ci := controladorInterrupciones{pid: -1}
+			for _, i := range caso.antes {
+				ci.recibir(i)
+			}
+			ci.iniciar(1, 7, caso.enmascarado)
+			for _, i := range caso.durante {
+				ci.recibir(i)
+			}
+
+			var atendidas []string
+			for {
+				i, ok := ci.atender(caso.enmascarado)
+				if !ok {
+					break
+				}
+				atendidas = append(atendidas, i.Motivo)
+			}
+			if !reflect.DeepEqual(atendidas, caso.atendidas) {
+				t.Errorf("atendidas %v, se esperaba %v", atendidas, caso.atendidas)
+			}
+			estado := ci.estado()
+			if estado.Descartadas != caso.descartadas || len(estado.Pendientes) != caso.quedan {
+				t.Errorf("descartadas %d y pendientes %v, se esperaba %d y %d", estado.Descartadas, estado.Pendientes, caso.descartadas, caso.quedan)
+			}
+			if estado.Despacho != 7 || estado.Enmascarado != caso.enmascarado {
+				t.Errorf("estado = %+v", estado)
+			}
+
+			// Con STI se atiende lo que quedo enmascarado, y al dejar el core no queda nada
+			if _, ok := ci.atender(false); ok != (caso.quedan > 0) {
+				t.Errorf("despues del STI se atendio = %v, quedaban %d", ok, caso.quedan)
+			}
+			ci.recibir(interrupcion(1, "CLOCK"))
+			ci.terminar()
+			if estado := ci.estado(); len(estado.Pendientes) != 0 || estado.Pid != -1 || estado.Despacho != 0 {
+				t.Errorf("despues de terminar: %+v", estado)
+			}
+		})
+	}
+}
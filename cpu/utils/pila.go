@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+	"github.com/sisoputnfrba/tp-golang/utils/instrucciones"
+)
+
+type BodyPila struct {
+	Base int `json:"base"`
+	Tope int `json:"top"`
+}
+
+type BodyEtiqueta struct {
+	PC int `json:"pc"`
+}
+
+// PUSH <reg>: el SP baja el tamaño del registro y el valor queda en memoria en big endian, igual que MOV_OUT
+func (c *Core) PUSH(words []string, contextoEjecucion *PCB) error {
+	if len(words) < 2 {
+		return fmt.Errorf("PUSH necesita un registro")
+	}
+	valor := verificarRegistro(words[1], contextoEjecucion)
+	datos, err := bytesDeRegistro(words[1], uint32(valor))
+	if err != nil {
+		return err
+	}
+	_, err = c.apilar(contextoEjecucion, datos)
+	return err
+}
+
+// POP <reg>
+func (c *Core) POP(words []string, contextoEjecucion *PCB) error {
+	if len(words) < 2 {
+		return fmt.Errorf("POP necesita un registro")
+	}
+	tam := tamRegistro(words[1])
+	if tam == 0 {
+		return fmt.Errorf("registro no soportado: %s", words[1])
+	}
+	datos, err := c.desapilar(contextoEjecucion, tam)
+	if err != nil || datos == nil {
+		return err
+	}
+	if tam == 1 {
+		return SetCampo(&contextoEjecucion.CpuReg, words[1], datos[0])
+	}
+	return SetCampo(&contextoEjecucion.CpuReg, words[1], binary.BigEndian.Uint32(datos))
+}
+
+// CALL <etiqueta|pc>: apila la direccion de retorno (el PC ya apunta a la instruccion siguiente) y salta
+func (c *Core) CALL(words []string, contextoEjecucion *PCB) error {
+	if len(words) < 2 {
+		return fmt.Errorf("CALL necesita una etiqueta o un numero de instruccion")
+	}
+	destino, err := resolverDestino(contextoEjecucion.Pid, words[1])
+	if err != nil {
+		return err
+	}
+
+	retorno := make([]byte, 4)
+	binary.BigEndian.PutUint32(retorno, contextoEjecucion.CpuReg.PC)
+	if apilado, err := c.apilar(contextoEjecucion, retorno); err != nil || !apilado {
+		return err // Si no se pudo apilar (stack overflow) no se salta
+	}
+	log.Printf("PID: %d - CALL - Destino: %d - Retorno: %d", contextoEjecucion.Pid, destino, contextoEjecucion.CpuReg.PC)
+	contextoEjecucion.CpuReg.PC = destino
+	return nil
+}
+
+// RET: desapila la direccion de retorno
+func (c *Core) RET(contextoEjecucion *PCB) error {
+	datos, err := c.desapilar(contextoEjecucion, 4)
+	if err != nil || datos == nil {
+		return err
+	}
+	contextoEjecucion.CpuReg.PC = binary.BigEndian.Uint32(datos)
+	log.Printf("PID: %d - RET - Retorno: %d", contextoEjecucion.Pid, contextoEjecucion.CpuReg.PC)
+	return nil
+}
+
+func tamRegistro(registro string) int {
+	return instrucciones.Registros[registro]
+}
+
+func bytesDeRegistro(registro string, valor uint32) ([]byte, error) {
+	switch tamRegistro(registro) {
+	case 4:
+		datos := make([]byte, 4)
+		binary.BigEndian.PutUint32(datos, valor)
+		return datos, nil
+	case 1:
+		return []byte{uint8(valor)}, nil
+	}
+	return nil, fmt.Errorf("registro no soportado: %s", registro)
+}
+
+// Sin FlagPila la pila todavia no se uso, el SP arranca en el tope que informa memoria
+func (c *Core) iniciarSP(contextoEjecucion *PCB) (BodyPila, error) {
+	pila, err := pedirLimitesPila(contextoEjecucion.Pid)
+	if err != nil {
+		return pila, err
+	}
+	if contextoEjecucion.CpuReg.FLAGS&FlagPila == 0 {
+		contextoEjecucion.CpuReg.SP = uint32(pila.Tope)
+		contextoEjecucion.CpuReg.FLAGS |= FlagPila
+		log.Printf("PID: %d - Pila inicializada - SP: %d - Base: %d", contextoEjecucion.Pid, pila.Tope, pila.Base)
+	}
+	return pila, nil
+}
+
+// Escribe los datos debajo del SP. El SP solo cambia si la escritura se pudo hacer
+func (c *Core) apilar(contextoEjecucion *PCB, datos []byte) (bool, error) {
+	pila, err := c.iniciarSP(contextoEjecucion)
+	if err != nil {
+		return false, err
+	}
+	nuevoSP := int(contextoEjecucion.CpuReg.SP) - len(datos)
+	if nuevoSP < pila.Base || nuevoSP < 0 {
+		log.Printf("PID: %d - STACK OVERFLOW - SP: %d - Base: %d", contextoEjecucion.Pid, contextoEjecucion.CpuReg.SP, pila.Base)
+		c.generarStackOverflow()
+		return false, nil
+	}
+
+	direcciones := c.TranslateAddressAcceso(contextoEjecucion.Pid, nuevoSP, GLOBALpageTam, len(datos), "W")
+	if direcciones == nil {
+		return false, fmt.Errorf("no se pudo traducir la dirección %d", nuevoSP)
+	}
+	if err := c.EscribirMemoria(contextoEjecucion.Pid, direcciones, datos); err != nil {
+		return false, err
+	}
+	contextoEjecucion.CpuReg.SP = uint32(nuevoSP)
+	log.Printf("PID: %d - PUSH - SP: %d - Dirección Física: %d", contextoEjecucion.Pid, nuevoSP, direcciones[0])
+	return true, nil
+}
+
+// Lee tam bytes del tope de la pila. Devuelve nil sin error si el proceso fue desalojado
+func (c *Core) desapilar(contextoEjecucion *PCB, tam int) ([]byte, error) {
+	pila, err := c.iniciarSP(contextoEjecucion)
+	if err != nil {
+		return nil, err
+	}
+	sp := int(contextoEjecucion.CpuReg.SP)
+	if sp+tam > pila.Tope { // Pila vacia
+		log.Printf("PID: %d - SEGMENTATION FAULT - POP con la pila vacia - SP: %d", contextoEjecucion.Pid, sp)
+		c.generarSegFault()
+		return nil, nil
+	}
+
+	direcciones := c.TranslateAddress(contextoEjecucion.Pid, sp, GLOBALpageTam, tam)
+	if direcciones == nil {
+		return nil, fmt.Errorf("no se pudo traducir la dirección %d", sp)
+	}
+	datos, err := c.LeerMemoria(contextoEjecucion.Pid, direcciones, tam)
+	if err != nil {
+		return nil, err
+	}
+	if len(datos) < tam {
+		return nil, fmt.Errorf("memoria devolvio %d bytes de %d", len(datos), tam)
+	}
+	contextoEjecucion.CpuReg.SP = uint32(sp + tam)
+	log.Printf("PID: %d - POP - SP: %d - Dirección Física: %d", contextoEjecucion.Pid, sp+tam, direcciones[0])
+	return datos[:tam], nil
+}
+
+// El destino de CALL puede ser un numero de instruccion o una etiqueta "nombre:" del script
+func resolverDestino(pid int, destino string) (uint32, error) {
+	if pc, err := strconv.ParseUint(destino, 10, 32); err == nil {
+		return uint32(pc), nil
+	}
+
+	memoriaURL := fmt.Sprintf("http://%s:%d/label?pid=%d&name=%s", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory, pid, url.QueryEscape(destino))
+	resp, err := http.Get(memoriaURL)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("la etiqueta %s no existe", destino)
+	}
+	var etiqueta BodyEtiqueta
+	if err := json.NewDecoder(resp.Body).Decode(&etiqueta); err != nil {
+		return 0, err
+	}
+	return uint32(etiqueta.PC), nil
+}
+
+func pedirLimitesPila(pid int) (BodyPila, error) {
+	var pila BodyPila
+	memoriaURL := fmt.Sprintf("http://%s:%d/stack?pid=%d", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory, pid)
+	resp, err := http.Get(memoriaURL)
+	if err != nil {
+		return pila, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return pila, fmt.Errorf("error en la respuesta del módulo de memoria: %v", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&pila)
+	return pila, err
+}
+
+// La pila llego a su base, el kernel finaliza el proceso
+func (c *Core) generarStackOverflow() {
+	c.interrupt = true
+	c.request = KernelRequest{
+		MotivoDesalojo: "STACK_OVERFLOW",
+	}
+}
@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+)
+
+type BodyInstrucciones struct {
+	Instructions []string `json:"instructions"`
+}
+
+type EstadisticasPrefetch struct {
+	Hits     int     `json:"hits"`
+	Misses   int     `json:"misses"` // Pedidos a memoria
+	TasaHits float64 `json:"hit_rate"`
+}
+
+type BodyEstadoPrefetch struct {
+	Core         int                          `json:"core"`
+	Bloque       int                          `json:"block_size"`
+	Pid          int                          `json:"pid"`
+	Bloques      []int                        `json:"cached_blocks"` // Numero de bloque (PC / block_size)
+	Estadisticas map[int]EstadisticasPrefetch `json:"stats"`
+}
+
+// Cache de instrucciones de los programas de texto. Cada core guarda bloques alineados de instruction_cache_block
+// instrucciones del proceso que ejecuta (Core.bloquesPrefetch) y los descarta al cambiar de proceso o si memoria
+// recarga el programa. El mutex protege tambien los bloques de los cores.
+// Los programas binarios no pasan por aca, su fetch ya lee varios bytes de memoria de usuario
+var prefetch = struct {
+	sync.Mutex
+	estadisticas map[int]*EstadisticasPrefetch
+}{
+	estadisticas: make(map[int]*EstadisticasPrefetch),
+}
+
+func prefetchHabilitado() bool {
+	return globals.ClientConfig.InstructionCacheBlock > 0
+}
+
+// Hay que tener el mutex
+func estadisticasPrefetch(pid int) *EstadisticasPrefetch {
+	if prefetch.estadisticas[pid] == nil {
+		prefetch.estadisticas[pid] = &EstadisticasPrefetch{}
+	}
+	return prefetch.estadisticas[pid]
+}
+
+func (c *Core) cambioDeContextoPrefetch(pid int) {
+	prefetch.Lock()
+	defer prefetch.Unlock()
+	if c.pidPrefetch != pid {
+		c.bloquesPrefetch = make(map[int][]string)
+		c.pidPrefetch = pid
+	}
+}
+
+func (c *Core) fetchPrefetch(pc int, pid int) ([]string, error) {
+	tam := globals.ClientConfig.InstructionCacheBlock
+	bloque := pc / tam
+	prefetch.Lock()
+	instrucciones, exists := c.bloquesPrefetch[bloque]
+	if exists && c.pidPrefetch == pid {
+		estadisticasPrefetch(pid).Hits++
+		prefetch.Unlock()
+		return instruccionDelBloque(instrucciones, pc-bloque*tam, pid, pc)
+	}
+	estadisticasPrefetch(pid).Misses++
+	prefetch.Unlock()
+
+	instrucciones, err := pedirBloque(pid, bloque*tam, tam)
+	if err != nil {
+		return nil, err
+	}
+	prefetch.Lock()
+	if c.pidPrefetch == pid {
+		c.bloquesPrefetch[bloque] = instrucciones
+	}
+	prefetch.Unlock()
+	return instruccionDelBloque(instrucciones, pc-bloque*tam, pid, pc)
+}
+
+func instruccionDelBloque(instrucciones []string, i int, pid int, pc int) ([]string, error) {
+	if i >= len(instrucciones) { // El bloque es el ultimo del programa y el PC se paso del final
+		return nil, fmt.Errorf("el PID %d no tiene la instrucción %d", pid, pc)
+	}
+	return []string{instrucciones[i]}, nil
+}
+
+func pedirBloque(pid int, desde int, cantidad int) ([]string, error) {
+	memoriaURL := fmt.Sprintf("http://%s:%d/getInstructionsFromPid?pid=%d&programCounter=%d&count=%d", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory, pid, desde, cantidad)
+	resp, err := http.Get(memoriaURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden {
+		log.Printf("PID: %d - SEGMENTATION FAULT - Fetch sin permiso de ejecución - Instrucciones: %d a %d", pid, desde, desde+cantidad-1)
+		return nil, errSinPermisoEjecucion
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error en la respuesta del módulo de memoria: %v", resp.StatusCode)
+	}
+
+	var body BodyInstrucciones
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	log.Printf("PID: %d - PREFETCH - Instrucciones: %d a %d", pid, desde, desde+len(body.Instructions)-1)
+	return body.Instructions, nil
+}
+
+// POST /invalidateProgram?pid= memoria avisa que volvio a cargar el programa del proceso
+func InvalidateProgramHandler(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.Atoi(r.URL.Query().Get("pid"))
+	if err != nil {
+		http.Error(w, "PID inválido", http.StatusBadRequest)
+		return
+	}
+	olvidarPrograma(pid)
+	prefetch.Lock()
+	for _, c := range cores {
+		if c.pidPrefetch == pid {
+			c.bloquesPrefetch = make(map[int][]string)
+		}
+	}
+	prefetch.Unlock()
+	log.Printf("PID: %d - Programa recargado, se descarta el cache de instrucciones", pid)
+	w.WriteHeader(http.StatusOK)
+}
+
+// GET /instructionCache (core 0) o GET /core/{id}/instructionCache. Las estadisticas son de todos los cores
+func InstructionCacheHandler(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
+	prefetch.Lock()
+	estado := BodyEstadoPrefetch{
+		Core:         c.ID,
+		Bloque:       globals.ClientConfig.InstructionCacheBlock,
+		Pid:          c.pidPrefetch,
+		Bloques:      []int{},
+		Estadisticas: make(map[int]EstadisticasPrefetch),
+	}
+	for bloque := range c.bloquesPrefetch {
+		estado.Bloques = append(estado.Bloques, bloque)
+	}
+	for pid, estadisticas := range prefetch.estadisticas {
+		copia := *estadisticas
+		if copia.Hits+copia.Misses > 0 {
+			copia.TasaHits = float64(copia.Hits) / float64(copia.Hits+copia.Misses)
+		}
+		estado.Estadisticas[pid] = copia
+	}
+	prefetch.Unlock()
+	slices.Sort(estado.Bloques)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(estado)
+}
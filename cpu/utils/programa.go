@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+	"github.com/sisoputnfrba/tp-golang/utils/instrucciones"
+)
+
+type BodyPrograma struct {
+	Pid     int    `json:"pid"`
+	Formato string `json:"format"` // TEXT o BINARY
+	Tamaño  int    `json:"size"`
+	Datos   int    `json:"data"` // Donde empiezan los datos del programa, despues del codigo
+}
+
+// Memoria no deja hacer el fetch: el codigo no tiene permiso de ejecucion
+var errSinPermisoEjecucion = errors.New("sin permiso de ejecución")
+
+// Cuantos bytes se leen en el primer intento de fetch binario, casi todas las instrucciones entran
+const ventanaFetch = 16
+
+// Formato del programa de cada proceso, se pide una sola vez salvo que memoria avise que lo recargo
+var programas = make(map[int]BodyPrograma)
+var mutexProgramas sync.Mutex
+
+func pedirPrograma(pid int) (BodyPrograma, error) {
+	mutexProgramas.Lock()
+	programa, exists := programas[pid]
+	mutexProgramas.Unlock()
+	if exists {
+		return programa, nil
+	}
+
+	memoriaURL := fmt.Sprintf("http://%s:%d/program?pid=%d", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory, pid)
+	resp, err := http.Get(memoriaURL)
+	if err != nil {
+		return programa, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return programa, fmt.Errorf("error en la respuesta del módulo de memoria: %v", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&programa); err != nil {
+		return programa, err
+	}
+	mutexProgramas.Lock()
+	programas[pid] = programa
+	mutexProgramas.Unlock()
+	return programa, nil
+}
+
+func olvidarPrograma(pid int) {
+	mutexProgramas.Lock()
+	delete(programas, pid)
+	mutexProgramas.Unlock()
+}
+
+// Traduce una direccion de los datos del programa (MOV_IN, MOV_OUT, COPY_STRING e IO): la direccion 0
+// es la primera despues del codigo. La pila y el fetch usan la direccion logica del proceso directamente
+func (c *Core) TranslateDataAddress(pid, direccion, tam int, acceso string) []int {
+	programa, err := pedirPrograma(pid)
+	if err != nil {
+		log.Printf("PID: %d - Error al pedir el programa a memoria: %v", pid, err)
+		return nil
+	}
+	return c.TranslateAddressAcceso(pid, direccion+programa.Datos, GLOBALpageTam, tam, acceso)
+}
+
+// El codigo esta en la memoria del proceso desde la direccion 0, se lee como cualquier dato pero con acceso X
+func (c *Core) fetchBinario(pc int, pid int, tamCodigo int) ([]string, int, error) {
+	if pc < 0 || pc >= tamCodigo {
+		return nil, 0, fmt.Errorf("el PC %d esta fuera del codigo (%d bytes)", pc, tamCodigo)
+	}
+	restante := tamCodigo - pc
+	leer := min(ventanaFetch, restante)
+	for {
+		direcciones := c.TranslateAddressAcceso(pid, pc, GLOBALpageTam, leer, "X")
+		if direcciones == nil {
+			return nil, 0, fmt.Errorf("no se pudo traducir la dirección %d", pc)
+		}
+		datos, err := c.leerMemoriaAcceso(pid, direcciones, leer, "X")
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(datos) < leer {
+			return nil, 0, fmt.Errorf("memoria devolvio %d bytes de %d", len(datos), leer)
+		}
+
+		texto, tam, err := instrucciones.Decodificar(datos[:leer])
+		if errors.Is(err, instrucciones.ErrInstruccionIncompleta) && leer < min(instrucciones.TamMaxInstruccion, restante) {
+			leer = min(instrucciones.TamMaxInstruccion, restante) // Instruccion larga (nombres de IO), se lee de nuevo
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		return []string{texto}, tam, nil
+	}
+}
+
+// La instruccion no se pudo leer o decodificar, el kernel finaliza el proceso
+func (c *Core) generarInstruccionInvalida(pid int, err error) {
+	log.Printf("PID: %d - INSTRUCCION INVALIDA - %v", pid, err)
+	c.interrupt = true
+	c.request = KernelRequest{
+		MotivoDesalojo: "INVALID_INSTRUCTION",
+	}
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+	"github.com/sisoputnfrba/tp-golang/cpu/tlb"
+)
+
+type BodyEntradaTLB struct {
+	PID          int       `json:"pid"`
+	Pagina       int       `json:"page"`
+	Frame        int       `json:"frame"`
+	Permisos     string    `json:"permissions,omitempty"`
+	Conjunto     int       `json:"set"`
+	PosicionFIFO int       `json:"fifo_position"` // 0 es la proxima en salir del conjunto con FIFO
+	UltimoAcceso time.Time `json:"last_access"`
+	Usos         int       `json:"uses"`      // LFU
+	Referencia   bool      `json:"reference"` // CLOCK
+}
+
+type BodyEstadoTLB struct {
+	Core          int                      `json:"core"`
+	Tamaño        int                      `json:"size"`
+	Vias          int                      `json:"ways"`
+	Algoritmo     string                   `json:"algorithm"`
+	FlushAlCambio bool                     `json:"flush_on_switch"`
+	Entradas      []BodyEntradaTLB         `json:"entries"`
+	Estadisticas  map[int]tlb.Estadisticas `json:"stats"`
+}
+
+// Con tlb_trace_path cada busqueda en la TLB se agrega al archivo como "pid pagina", para cpu/cmd/tlbbench
+var trazaTLB *os.File
+var mutexTrazaTLB sync.Mutex
+
+func (c *Core) CheckTLB(pid, page int) (int, string, bool) { //Verifica si la entrada ya estaba en la TLB, cuenta el hit o miss
+	registrarAccesoTLB(pid, page)
+	return c.tlb.Buscar(pid, page)
+}
+
+func (c *Core) ReplaceTLBEntry(pid, page, frame int, permisos string) { //Agrega la entrada, si no hay lugar reemplaza segun el algoritmo
+	c.tlb.Agregar(pid, page, frame, permisos)
+}
+
+func registrarAccesoTLB(pid, page int) {
+	if globals.ClientConfig.TLBTracePath == "" {
+		return
+	}
+	mutexTrazaTLB.Lock()
+	defer mutexTrazaTLB.Unlock()
+	if trazaTLB == nil {
+		archivo, err := os.OpenFile(globals.ClientConfig.TLBTracePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Printf("No se pudo abrir el archivo de traza de la TLB %s: %v", globals.ClientConfig.TLBTracePath, err)
+			return
+		}
+		trazaTLB = archivo
+	}
+	fmt.Fprintf(trazaTLB, "%d %d\n", pid, page)
+}
+
+// Se llama al recibir un PCB (solo lo llama el core que ejecuta). Con flush_tlb_on_switch la TLB arranca
+// vacia para cada proceso nuevo, si no las entradas de otros procesos quedan y se distinguen por PID
+func (c *Core) cambioDeContextoTLB(pid int) {
+	if pid == c.ultimoPid {
+		return
+	}
+	if c.ultimoPid != -1 && globals.ClientConfig.FlushTLBOnSwitch {
+		log.Printf("PID: %d - TLB FLUSH - Core: %d - Cambio de contexto - Entradas: %d", pid, c.ID, c.tlb.Vaciar(-1))
+	}
+	c.ultimoPid = pid
+}
+
+func (c *Core) estadoTLB() BodyEstadoTLB {
+	estado := BodyEstadoTLB{
+		Core:          c.ID,
+		Tamaño:        c.tlb.Tamaño(),
+		Vias:          c.tlb.Vias(),
+		Algoritmo:     c.tlb.Algoritmo(),
+		FlushAlCambio: globals.ClientConfig.FlushTLBOnSwitch,
+		Entradas:      []BodyEntradaTLB{},
+		Estadisticas:  c.tlb.Estadisticas(),
+	}
+	for _, entrada := range c.tlb.Entradas() {
+		estado.Entradas = append(estado.Entradas, BodyEntradaTLB{
+			PID:          entrada.PID,
+			Pagina:       entrada.Pagina,
+			Frame:        entrada.Frame,
+			Permisos:     entrada.Permisos,
+			Conjunto:     entrada.Conjunto,
+			PosicionFIFO: entrada.PosicionFIFO,
+			UltimoAcceso: entrada.UltimoAcceso,
+			Usos:         entrada.Usos,
+			Referencia:   entrada.Referencia,
+		})
+	}
+	return estado
+}
+
+// GET /tlb (core 0) o GET /core/{id}/tlb
+func TLBHandler(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.estadoTLB())
+}
+
+// DELETE /tlb?pid= (sin pid vacia toda la TLB) en todos los cores. Memoria lo usa cuando achica un proceso.
+// Responde como GET /tlb
+func FlushTLBHandler(w http.ResponseWriter, r *http.Request) {
+	pid := -1
+	if pidStr := r.URL.Query().Get("pid"); pidStr != "" {
+		var err error
+		if pid, err = strconv.Atoi(pidStr); err != nil || pid < 0 {
+			http.Error(w, "PID inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	borradas := 0
+	for _, c := range cores {
+		borradas += c.tlb.Vaciar(pid)
+	}
+	if pid == -1 {
+		log.Printf("TLB FLUSH - Entradas: %d", borradas)
+	} else {
+		log.Printf("PID: %d - TLB FLUSH - Entradas: %d", pid, borradas)
+	}
+	TLBHandler(w, r)
+}
@@ -0,0 +1,430 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+)
+
+type AccesoTraza struct {
+	Direcciones []int  `json:"physical_addresses"`
+	Datos       []byte `json:"data"`
+}
+
+// Una linea del archivo de traza: una instruccion ejecutada por un proceso
+type RegistroTraza struct {
+	Pid         int               `json:"pid"`
+	Secuencia   int               `json:"seq"` // Numero de instruccion ejecutada por el proceso
+	PC          uint32            `json:"pc"`
+	Tamaño      int               `json:"size"` // Cuanto avanzo el PC en el fetch
+	Instruccion string            `json:"instruction"`
+	Registros   map[string]uint32 `json:"registers,omitempty"` // Registros que cambio la instruccion, con su valor nuevo
+	Lecturas    []AccesoTraza     `json:"reads,omitempty"`
+	Escrituras  []AccesoTraza     `json:"writes,omitempty"`
+	Resultado   string            `json:"result,omitempty"`        // Respuesta de memoria a RESIZE y MALLOC
+	Desalojo    string            `json:"eviction,omitempty"`      // Motivo de desalojo que genero la instruccion
+	Pagina      int               `json:"page,omitempty"`          // Pagina del PAGE_FAULT
+	Dispositivo []AccesoTraza     `json:"device_writes,omitempty"` // Lo que escribio un dispositivo de IO (IO_STDIN_READ, IO_FS_READ) antes de esta instruccion
+}
+
+type Divergencia struct {
+	Pid         int    `json:"pid"`
+	Secuencia   int    `json:"seq"`
+	PC          uint32 `json:"pc"`
+	Instruccion string `json:"instruction"`
+	Campo       string `json:"field"`
+	Esperado    string `json:"expected"`
+	Obtenido    string `json:"got"`
+}
+
+type ReporteReplay struct {
+	Instrucciones int          `json:"instructions"` // Instrucciones reproducidas sin diferencias
+	Divergencia   *Divergencia `json:"divergence,omitempty"`
+}
+
+// Estas instrucciones dependen de la respuesta del kernel (o de un dispositivo de IO), en el replay no se
+// ejecutan: se aplica lo que quedo grabado
+var instruccionesDelKernel = []string{"WAIT", "SIGNAL", "EXIT", "IO_GEN_SLEEP", "IO_STDIN_READ", "IO_STDOUT_WRITE",
+	"IO_FS_CREATE", "IO_FS_DELETE", "IO_FS_TRUNCATE", "IO_FS_WRITE", "IO_FS_READ"}
+
+var traza = struct {
+	sync.Mutex
+	archivo       *os.File
+	secuencias    map[int]int
+	reproduciendo bool            // Durante el replay se arman los registros pero no se escriben
+	dispositivo   map[int][][]int // Direcciones donde va a escribir un dispositivo de IO, por PID
+}{
+	secuencias:  make(map[int]int),
+	dispositivo: make(map[int][][]int),
+}
+
+func trazaHabilitada() bool {
+	return traza.reproduciendo || (globals.ClientConfig != nil && globals.ClientConfig.TracePath != "")
+}
+
+// Arranca el registro de la instruccion que se va a ejecutar. pc es el de la instruccion (antes del fetch)
+func (c *Core) iniciarRegistroTraza(contexto *PCB, pc uint32, tam int, instruccion []string) {
+	if !trazaHabilitada() {
+		return
+	}
+	dispositivo := c.leerEscriturasDispositivo(contexto.Pid)
+	traza.Lock()
+	defer traza.Unlock()
+	traza.secuencias[contexto.Pid]++
+	c.registroTraza = &RegistroTraza{
+		Pid:         contexto.Pid,
+		Secuencia:   traza.secuencias[contexto.Pid],
+		PC:          pc,
+		Tamaño:      tam,
+		Instruccion: strings.Join(instruccion, ","),
+		Dispositivo: dispositivo,
+	}
+}
+
+// IO_STDIN_READ e IO_FS_READ: el dispositivo escribe en memoria sin pasar por la CPU. Cuando el proceso
+// vuelve a ejecutar se leen esas direcciones y quedan en la traza, asi el replay las puede cargar
+func esperarEscrituraDispositivo(pid int, direcciones []int) {
+	if !trazaHabilitada() {
+		return
+	}
+	traza.Lock()
+	defer traza.Unlock()
+	if !traza.reproduciendo {
+		traza.dispositivo[pid] = append(traza.dispositivo[pid], slices.Clone(direcciones))
+	}
+}
+
+func (c *Core) leerEscriturasDispositivo(pid int) []AccesoTraza {
+	traza.Lock()
+	pendientes := traza.dispositivo[pid]
+	delete(traza.dispositivo, pid)
+	traza.Unlock()
+
+	var accesos []AccesoTraza
+	for _, direcciones := range pendientes {
+		datos, err := c.leerMemoriaFisica(pid, direcciones, "R")
+		if err != nil {
+			log.Printf("PID: %d - No se pudo leer lo que escribio el dispositivo para la traza: %v", pid, err)
+			continue
+		}
+		accesos = append(accesos, AccesoTraza{Direcciones: direcciones, Datos: datos})
+	}
+	return accesos
+}
+
+func (c *Core) registrarLectura(direcciones []int, datos []byte) {
+	traza.Lock()
+	defer traza.Unlock()
+	if c.registroTraza != nil {
+		c.registroTraza.Lecturas = append(c.registroTraza.Lecturas, AccesoTraza{Direcciones: slices.Clone(direcciones), Datos: slices.Clone(datos)})
+	}
+}
+
+func (c *Core) registrarEscritura(direcciones []int, datos []byte) {
+	traza.Lock()
+	defer traza.Unlock()
+	if c.registroTraza != nil {
+		c.registroTraza.Escrituras = append(c.registroTraza.Escrituras, AccesoTraza{Direcciones: slices.Clone(direcciones), Datos: slices.Clone(datos)})
+	}
+}
+
+func (c *Core) registrarResultado(resultado string) {
+	traza.Lock()
+	defer traza.Unlock()
+	if c.registroTraza != nil {
+		c.registroTraza.Resultado = resultado
+	}
+}
+
+// Registros que cambiaron entre antes y despues, por nombre
+func diferenciaRegistros(antes RegisterCPU, despues RegisterCPU) map[string]uint32 {
+	diferencia := make(map[string]uint32)
+	valorAntes, valorDespues := reflect.ValueOf(antes), reflect.ValueOf(despues)
+	for i := 0; i < valorAntes.NumField(); i++ {
+		if valorAntes.Field(i).Uint() != valorDespues.Field(i).Uint() {
+			diferencia[valorAntes.Type().Field(i).Name] = uint32(valorDespues.Field(i).Uint())
+		}
+	}
+	if len(diferencia) == 0 {
+		return nil
+	}
+	return diferencia
+}
+
+func aplicarRegistros(r *RegisterCPU, registros map[string]uint32) {
+	for nombre, valor := range registros {
+		SetCampo(r, nombre, valor)
+	}
+}
+
+// Cierra el registro de la instruccion. antes son los registros despues del fetch (con el PC ya avanzado)
+func (c *Core) terminarRegistroTraza(contexto *PCB, antes RegisterCPU) *RegistroTraza {
+	if !trazaHabilitada() {
+		return nil
+	}
+	traza.Lock()
+	defer traza.Unlock()
+	registro := c.registroTraza
+	c.registroTraza = nil
+	if registro == nil {
+		return nil
+	}
+	registro.Registros = diferenciaRegistros(antes, contexto.CpuReg)
+	if c.interrupt {
+		registro.Desalojo = c.request.MotivoDesalojo
+		if registro.Desalojo == "PAGE_FAULT" {
+			registro.Pagina = c.request.Pagina
+		}
+	}
+	if !traza.reproduciendo {
+		escribirTraza(registro)
+	}
+	return registro
+}
+
+func escribirTraza(registro *RegistroTraza) {
+	if traza.archivo == nil {
+		archivo, err := os.OpenFile(globals.ClientConfig.TracePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Printf("No se pudo abrir el archivo de traza %s: %v", globals.ClientConfig.TracePath, err)
+			return
+		}
+		traza.archivo = archivo
+	}
+	linea, err := json.Marshal(registro)
+	if err != nil {
+		log.Printf("Error al serializar la traza: %v", err)
+		return
+	}
+	traza.archivo.Write(append(linea, '\n'))
+}
+
+func leerTraza(path string) ([]RegistroTraza, error) {
+	archivo, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer archivo.Close()
+
+	var registros []RegistroTraza
+	scanner := bufio.NewScanner(archivo)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024) // Las lecturas largas (COPY_STRING, IO) hacen lineas largas
+	for linea := 1; scanner.Scan(); linea++ {
+		var registro RegistroTraza
+		if err := json.Unmarshal(scanner.Bytes(), &registro); err != nil {
+			return nil, fmt.Errorf("linea %d: %v", linea, err)
+		}
+		registros = append(registros, registro)
+	}
+	return registros, scanner.Err()
+}
+
+func jsonTexto(valor any) string {
+	texto, _ := json.Marshal(valor)
+	return string(texto)
+}
+
+// Compara lo grabado con lo que paso en el replay, devuelve el primer campo distinto
+func compararRegistros(esperado RegistroTraza, obtenido RegistroTraza) *Divergencia {
+	campos := []struct {
+		nombre             string
+		esperado, obtenido any
+	}{
+		{"eviction", esperado.Desalojo, obtenido.Desalojo},
+		{"page", esperado.Pagina, obtenido.Pagina},
+		{"result", esperado.Resultado, obtenido.Resultado},
+		{"reads", esperado.Lecturas, obtenido.Lecturas},
+		{"writes", esperado.Escrituras, obtenido.Escrituras},
+		{"registers", esperado.Registros, obtenido.Registros},
+	}
+	for _, campo := range campos {
+		textoEsperado, textoObtenido := jsonTexto(campo.esperado), jsonTexto(campo.obtenido)
+		if textoEsperado != textoObtenido {
+			return &Divergencia{
+				Pid:         esperado.Pid,
+				Secuencia:   esperado.Secuencia,
+				PC:          esperado.PC,
+				Instruccion: esperado.Instruccion,
+				Campo:       campo.nombre,
+				Esperado:    textoEsperado,
+				Obtenido:    textoObtenido,
+			}
+		}
+	}
+	return nil
+}
+
+func crearProcesoReplay(pid int) error {
+	memoriaURL := fmt.Sprintf("http://%s:%d/createProcess", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory)
+	body, _ := json.Marshal(map[string]int{"pid": pid, "pages": 0})
+	resp, err := http.Post(memoriaURL, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("error en la respuesta del módulo de memoria: %v", resp.StatusCode)
+	}
+	// El proceso no tiene programa cargado en memoria: los datos arrancan en la direccion 0
+	mutexProgramas.Lock()
+	programas[pid] = BodyPrograma{Pid: pid, Formato: "TEXT"}
+	mutexProgramas.Unlock()
+	return nil
+}
+
+// Lo que hace el kernel con un PAGE_FAULT: pedirle a memoria que cargue la pagina
+func cargarPaginaReplay(pid int, pagina int) error {
+	memoriaURL := fmt.Sprintf("http://%s:%d/pageFault", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory)
+	body, _ := json.Marshal(map[string]int{"pid": pid, "page": pagina})
+	resp, err := http.Post(memoriaURL, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error en la respuesta del módulo de memoria: %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// Vuelve a ejecutar la traza en orden contra una memoria recien levantada (sin procesos) y frena en la primera
+// diferencia. Los procesos se crean vacios en memoria, con sus datos desde la direccion 0, y el codigo de los
+// programas binarios no se carga. Lo que escribieron los dispositivos de IO se carga en memoria antes de la
+// instruccion donde quedo grabado
+func Replay(path string) (ReporteReplay, error) {
+	var reporte ReporteReplay
+	registros, err := leerTraza(path)
+	if err != nil {
+		return reporte, err
+	}
+	c := cores[0] // Las invalidaciones de memoria llegan a la TLB de todos los cores, no a un core aparte
+	if !c.ocupado.TryLock() {
+		return reporte, fmt.Errorf("el core %d está ejecutando un proceso", c.ID)
+	}
+	defer c.ocupado.Unlock()
+
+	traza.Lock()
+	traza.reproduciendo = true
+	traza.secuencias = make(map[int]int)
+	traza.Unlock()
+	defer func() {
+		traza.Lock()
+		traza.reproduciendo = false
+		c.registroTraza = nil
+		traza.Unlock()
+	}()
+	c.tlb.Vaciar(-1) // Los procesos de la traza son nuevos para esta memoria
+
+	contextos := make(map[int]*PCB)
+	for _, esperado := range registros {
+		contexto, exists := contextos[esperado.Pid]
+		if !exists {
+			if err := crearProcesoReplay(esperado.Pid); err != nil {
+				return reporte, fmt.Errorf("no se pudo crear el PID %d en memoria: %v", esperado.Pid, err)
+			}
+			contexto = &PCB{Pid: esperado.Pid}
+			contextos[esperado.Pid] = contexto
+		}
+		for _, acceso := range esperado.Dispositivo {
+			if err := escribirMemoriaFisica(esperado.Pid, acceso.Direcciones, acceso.Datos); err != nil {
+				return reporte, fmt.Errorf("no se pudo cargar lo que escribio el dispositivo para el PID %d: %v", esperado.Pid, err)
+			}
+		}
+		if contexto.CpuReg.PC != esperado.PC {
+			reporte.Divergencia = &Divergencia{Pid: esperado.Pid, Secuencia: esperado.Secuencia, PC: esperado.PC, Instruccion: esperado.Instruccion,
+				Campo: "pc", Esperado: fmt.Sprint(esperado.PC), Obtenido: fmt.Sprint(contexto.CpuReg.PC)}
+			return reporte, nil
+		}
+
+		line := strings.Split(esperado.Instruccion, ",")
+		c.interrupt = false
+		c.request = KernelRequest{}
+		c.iniciarRegistroTraza(contexto, contexto.CpuReg.PC, esperado.Tamaño, line)
+		contexto.CpuReg.PC += uint32(esperado.Tamaño)
+		antes := contexto.CpuReg
+
+		instruction, err := Decode(line)
+		if err != nil {
+			return reporte, fmt.Errorf("PID %d seq %d: %v", esperado.Pid, esperado.Secuencia, err)
+		}
+		if slices.Contains(instruccionesDelKernel, instruction) {
+			aplicarRegistros(&contexto.CpuReg, esperado.Registros) // Lo que hizo el kernel no se puede repetir
+			c.interrupt = esperado.Desalojo != ""
+			c.request.MotivoDesalojo, c.request.Pagina = esperado.Desalojo, esperado.Pagina
+			traza.Lock()
+			c.registroTraza.Lecturas, c.registroTraza.Escrituras = esperado.Lecturas, esperado.Escrituras
+			traza.Unlock()
+		} else {
+			c.Execute(instruction, line, contexto)
+			if reintentaInstruccion(c.request.MotivoDesalojo) {
+				contexto.CpuReg.PC -= uint32(esperado.Tamaño)
+			}
+		}
+		obtenido := c.terminarRegistroTraza(contexto, antes)
+
+		if divergencia := compararRegistros(esperado, *obtenido); divergencia != nil {
+			reporte.Divergencia = divergencia
+			log.Printf("PID: %d - REPLAY - Divergencia en seq %d (%s): %s", divergencia.Pid, divergencia.Secuencia, divergencia.Campo, divergencia.Instruccion)
+			return reporte, nil
+		}
+		if obtenido.Desalojo != "" {
+			vaciarCacheDatos() // El proceso habria dejado la CPU
+		}
+		if obtenido.Desalojo == "PAGE_FAULT" {
+			if err := cargarPaginaReplay(contexto.Pid, obtenido.Pagina); err != nil {
+				return reporte, err
+			}
+		}
+		reporte.Instrucciones++
+	}
+	c.interrupt = false
+	c.request = KernelRequest{}
+	log.Printf("REPLAY - %d instrucciones sin diferencias", reporte.Instrucciones)
+	return reporte, nil
+}
+
+// Con ?path= solo se puede elegir el nombre del archivo dentro de trace_dir, sin directorios
+func rutaTraza(r *http.Request) (string, error) {
+	nombre := r.URL.Query().Get("path")
+	if nombre == "" {
+		if globals.ClientConfig.TracePath == "" {
+			return "", fmt.Errorf("falta el archivo de traza")
+		}
+		return globals.ClientConfig.TracePath, nil
+	}
+	if globals.ClientConfig.TraceDir == "" {
+		return "", fmt.Errorf("no hay trace_dir configurado, solo se puede usar trace_path")
+	}
+	if strings.ContainsAny(nombre, `/\`) || nombre == "." || nombre == ".." {
+		return "", fmt.Errorf("nombre de traza invalido: %s", nombre)
+	}
+	return filepath.Join(globals.ClientConfig.TraceDir, nombre), nil
+}
+
+// POST /replay?path= (por defecto el trace_path de la config)
+func ReplayHandler(w http.ResponseWriter, r *http.Request) {
+	path, err := rutaTraza(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reporte, err := Replay(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reporte)
+}
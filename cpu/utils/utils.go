@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -16,6 +17,8 @@ import (
 	"time"
 
 	"github.com/sisoputnfrba/tp-golang/cpu/globals"
+	"github.com/sisoputnfrba/tp-golang/utils/instrucciones"
+	"github.com/sisoputnfrba/tp-golang/utils/paginacion"
 )
 
 /*---------------------------------------------- STRUCTS --------------------------------------------------------*/
@@ -26,6 +29,21 @@ type KernelRequest struct {
 	Interface      string `json:"interface"`
 	IoType         string `json:"ioType"`
 	Recurso        string `json:"recurso"`
+	Pagina         int    `json:"pagina"`   // Pagina que genero el PAGE_FAULT
+	Core           int    `json:"core"`     // Core que ejecuto el proceso
+	Dispatch       int    `json:"dispatch"` // Id del despacho que mando el kernel con el PCB
+}
+
+// Lo que manda el kernel a /receivePCB: el PCB y el id del despacho, que se le devuelve en /syscall
+type BodyDispatch struct {
+	PCB
+	Dispatch int `json:"dispatch"`
+}
+
+// Respuesta de /receivePCB: el core ya tomo el proceso, el desalojo llega despues por /syscall
+type BodyAckDispatch struct {
+	Core     int `json:"core"`
+	Dispatch int `json:"dispatch"`
 }
 
 type PCB struct { //ESTO NO VA ACA
@@ -43,6 +61,9 @@ type ExecutionContext struct {
 type RegisterCPU struct {
 	PC, EAX, EBX, ECX, EDX, SI, DI uint32
 	AX, BX, CX, DX                 uint8
+	ERR                            uint8  // 1 si fallo el ultimo RESIZE (politica FAIL de memoria)
+	SP                             uint32 // Se inicializa con el tope de la pila en el primer PUSH/CALL (ver FlagPila)
+	FLAGS                          uint8  // Zero, Carry, Overflow, Negative y la mascara de interrupciones (ver alu.go)
 }
 
 type BodyResponseInstruction struct {
@@ -71,26 +92,28 @@ type TranslationResponse struct {
 	DireccionesFisicas []int `json:"physical_addresses"`
 }
 
-type TLBEntry struct {
-	PID                int
-	Pagina             int
-	Frame              int
-	UltimoAcceso       time.Time // Para LRU
-	globalPosicionFila int       // Para FIFO
-}
-
 type bodyProcess struct {
 	Pid   int `json:"pid"`
 	Pages int `json:"pages,omitempty"`
 }
 
+type BodyResize struct {
+	Resultado string `json:"result"` // OK u OUT_OF_MEMORY
+	Politica  string `json:"policy"` // KILL, BLOCK o FAIL
+}
+
 type bodyPageTable struct {
-	Pid  int `json:"pid"`
-	Page int `json:"page"`
+	Pid     int   `json:"pid"`
+	Page    int   `json:"page"`
+	Indices []int `json:"indices,omitempty"` // Indice de cada nivel de tablas
+	Core    int   `json:"core"`              // Core al que memoria le manda el marco
 }
 
 type BodyFrame struct {
-	Frame int `json:"frame"`
+	Frame     int    `json:"frame"`
+	PageFault bool   `json:"page_fault,omitempty"`
+	SegFault  bool   `json:"segfault,omitempty"`
+	Permisos  string `json:"permisos,omitempty"`
 }
 type bodyRegisters struct {
 	Pid       int   `json:"iopid"`
@@ -99,7 +122,25 @@ type bodyRegisters struct {
 }
 
 type BodyPageTam struct {
-	PageTam int `json:"pageTam"`
+	PageTam          int    `json:"pageTam"`
+	Niveles          int    `json:"levels,omitempty"`
+	EntradasPorTabla int    `json:"entries_per_table,omitempty"`
+	ModoMemoria      string `json:"memory_mode,omitempty"`
+	TamMaxSegmento   int    `json:"max_segment_size,omitempty"`
+}
+
+type BodySegmentRequest struct {
+	Pid      int    `json:"pid"`
+	Segmento int    `json:"segment"`
+	Offset   int    `json:"offset"`
+	Size     int    `json:"size"`
+	Acceso   string `json:"access"`
+}
+
+type BodySegmentResponse struct {
+	Direcciones []int  `json:"physical_addresses"`
+	SegFault    bool   `json:"segfault"`
+	Motivo      string `json:"motivo,omitempty"`
 }
 
 type BodyContent struct {
@@ -112,6 +153,8 @@ type MemoryReadRequest struct {
 	Size    int    `json:"size,omitempty"` //Si es 0, se omite (Util para creacion y terminacion de procesos)
 	Data    []byte `json:"data,omitempty"` //Si es 0, se omite Util para creacion y terminacion de procesos)
 	Type    string `json:"type"`
+	Acceso  string `json:"access,omitempty"` // R o X, memoria verifica los permisos de la pagina o del segmento
+	Core    int    `json:"core"`             // Core al que memoria le manda lo que leyo
 }
 
 type FSstructure struct {
@@ -124,19 +167,11 @@ type FSstructure struct {
 
 /*------------------------------------------------- VAR GLOBALES --------------------------------------------------------*/
 
-var globalTLB []TLBEntry
-var globalTLBsize int
-var replacementAlgorithm string
-var globalPosicionFila int
-var interrupt bool = false
-var GLOBALrequestCPU KernelRequest
-var GLOBALcontextoDeEjecucion PCB //PCB recibido desde kernel
-var MemoryFrame int
 var GLOBALpageTam int
-var GLOBALdataMOV_IN []byte
-
-// var requestCPU KernelRequest
-var responseInterruptGlobal ResponseInterrupt
+var GLOBALnivelesTablas int // Mayor a 1 si memoria usa tablas multinivel
+var GLOBALentradasPorTabla int
+var GLOBALmodoMemoria string // Vacio si memoria usa paginacion
+var GLOBALtamMaxSegmento int
 
 /*func init() {
 	globals.ClientConfig = IniciarConfiguracion(os.Args[1]) // tiene que prender la confi cuando arranca
@@ -152,15 +187,11 @@ func ConfigurarLogger() {
 	log.SetOutput(mw)
 }
 
-func init() {
-	globals.ClientConfig = IniciarConfiguracion(os.Args[1]) // tiene que prender la confi cuando arranca
-
-	if globals.ClientConfig != nil {
-		globalTLBsize = globals.ClientConfig.NumberFellingTLB
-		replacementAlgorithm = globals.ClientConfig.AlgorithmTLB
-	} else {
-		log.Fatal("ClientConfig is not initialized")
-	}
+// Arma los cores y la cache de datos con la config ya cargada. Lo llama main, asi los tests del paquete
+// no necesitan una config en os.Args
+func IniciarCPU() {
+	iniciarCores()
+	iniciarCacheDatos()
 }
 
 func IniciarConfiguracion(filePath string) *globals.Config {
@@ -179,44 +210,91 @@ func IniciarConfiguracion(filePath string) *globals.Config {
 
 func ReceivePCB(w http.ResponseWriter, r *http.Request) {
 	// HAGO UN LOG PARA CHEQUEAR RECEPCION
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
 
-	err := json.NewDecoder(r.Body).Decode(&GLOBALcontextoDeEjecucion)
+	var despacho BodyDispatch
+	err := json.NewDecoder(r.Body).Decode(&despacho)
 	if err != nil {
 		http.Error(w, "Error al decodificar los datos JSON", http.StatusInternalServerError)
 		return
 	}
+	if !c.ocupado.TryLock() {
+		http.Error(w, fmt.Sprintf("el core %d ya está ejecutando un proceso", c.ID), http.StatusConflict)
+		return
+	}
 
-	InstructionCycle(GLOBALcontextoDeEjecucion)
-	w.WriteHeader(http.StatusOK)
+	// El kernel no espera a que el proceso deje la CPU: se confirma el despacho y el ciclo corre aparte
+	c.contexto = despacho.PCB
+	c.dispatch = despacho.Dispatch
+	go func() {
+		c.InstructionCycle(c.contexto)
+		request := c.request
+		c.ocupado.Unlock() // Antes de avisar: con el desalojo el kernel ya puede despachar otro proceso a este core
+		responsePCBtoKernel(request)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(BodyAckDispatch{Core: c.ID, Dispatch: despacho.Dispatch})
 }
 
-func InstructionCycle(contextoDeEjecucion PCB) {
-	GLOBALrequestCPU = KernelRequest{}
+func (c *Core) InstructionCycle(contextoDeEjecucion PCB) {
+	c.request = KernelRequest{}
+	c.cambioDeContextoTLB(contextoDeEjecucion.Pid)
+	c.cambioDeContextoPrefetch(contextoDeEjecucion.Pid)
+	c.interrupciones.iniciar(contextoDeEjecucion.Pid, c.dispatch, contextoDeEjecucion.CpuReg.FLAGS&FlagMascara != 0)
 
 	for {
+		c.puntoDeParada(&contextoDeEjecucion) // Breakpoints y step del depurador
 		log.Printf("PID: %d - FETCH - Program Counter: %d\n", contextoDeEjecucion.Pid, contextoDeEjecucion.CpuReg.PC)
-		line, _ := Fetch(int(contextoDeEjecucion.CpuReg.PC), contextoDeEjecucion.Pid)
+		line, tamInstruccion, err := c.Fetch(int(contextoDeEjecucion.CpuReg.PC), contextoDeEjecucion.Pid)
+		if err != nil {
+			if !c.interrupt { // Con PAGE_FAULT o SEGMENTATION_FAULT en el fetch ya hay motivo de desalojo
+				c.generarInstruccionInvalida(contextoDeEjecucion.Pid, err)
+			}
+			c.request.PcbUpdated = contextoDeEjecucion
+			c.interrupt = false
+			break // El PC queda en la instruccion que no se pudo leer
+		}
 
-		contextoDeEjecucion.CpuReg.PC++
-		GLOBALrequestCPU.PcbUpdated = contextoDeEjecucion
-		instruction, _ := Decode(line)
+		c.iniciarRegistroTraza(&contextoDeEjecucion, contextoDeEjecucion.CpuReg.PC, tamInstruccion, line)
+		contextoDeEjecucion.CpuReg.PC += uint32(tamInstruccion)
+		c.request.PcbUpdated = contextoDeEjecucion
+		registrosAntes := contextoDeEjecucion.CpuReg
+		instruction, err := Decode(line)
+		if err != nil {
+			log.Printf("PID: %d - Instrucción inválida: %s - %v", contextoDeEjecucion.Pid, line, err)
+		}
 
 		log.Printf("PID: %d - Ejecutando: %s - %s.", contextoDeEjecucion.Pid, instruction, line)
-		Execute(instruction, line, &contextoDeEjecucion)
+		c.Execute(instruction, line, &contextoDeEjecucion)
+
+		if reintentaInstruccion(c.request.MotivoDesalojo) {
+			contextoDeEjecucion.CpuReg.PC -= uint32(tamInstruccion) // El PC queda en la instruccion que fallo, con PAGE_FAULT se vuelve a ejecutar
+		}
+		c.terminarRegistroTraza(&contextoDeEjecucion, registrosAntes)
 
-		if (responseInterruptGlobal.Interrupt && responseInterruptGlobal.Pid == contextoDeEjecucion.Pid) || interrupt {
-			responseInterruptGlobal.Interrupt = false
-			interrupt = false
+		if c.interrupt {
+			c.interrupt = false
+			break
+		}
+		enmascaradas := contextoDeEjecucion.CpuReg.FLAGS&FlagMascara != 0
+		if interrupcion, ok := c.interrupciones.atender(enmascaradas); ok {
+			log.Printf("PID: %d - Atendiendo interrupción - Motivo: %s", contextoDeEjecucion.Pid, interrupcion.Motivo)
+			c.request.MotivoDesalojo = interrupcion.Motivo
 			break
 		}
 
 	}
-	if GLOBALrequestCPU.MotivoDesalojo == "" {
-		GLOBALrequestCPU.MotivoDesalojo = responseInterruptGlobal.Motivo
-	}
-	GLOBALrequestCPU.PcbUpdated = contextoDeEjecucion
-	responsePCBtoKernel(GLOBALrequestCPU)
-
+	c.interrupciones.terminar()
+	c.request.PcbUpdated = contextoDeEjecucion
+	c.request.Core = c.ID
+	c.request.Dispatch = c.dispatch
+	vaciarCacheDatos() // Antes de avisarle al kernel, que puede mandar a un dispositivo de IO a leer la memoria
+	c.procesoFueraDeCPU()
 }
 
 func responsePCBtoKernel(requestCPU KernelRequest) {
@@ -228,51 +306,81 @@ func responsePCBtoKernel(requestCPU KernelRequest) {
 	}
 	resp, err := http.Post(kernelURL, "application/json", bytes.NewBuffer(requestJSON))
 	if err != nil {
+		log.Printf("PID: %d - No se pudo avisar el desalojo al kernel (despacho %d): %v", requestCPU.PcbUpdated.Pid, requestCPU.Dispatch, err)
 		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		log.Printf("PID: %d - El kernel rechazó el desalojo (despacho %d): %v", requestCPU.PcbUpdated.Pid, requestCPU.Dispatch, resp.StatusCode)
 		return
 	}
 }
 
-func Fetch(pc int, pid int) ([]string, error) {
+// Devuelve la instruccion y cuanto avanza el PC (1 en los programas de texto, el tamaño en bytes en los binarios)
+func (c *Core) Fetch(pc int, pid int) ([]string, int, error) {
+	programa, err := pedirPrograma(pid)
+	if err != nil {
+		return nil, 0, err
+	}
+	if programa.Formato == "BINARY" {
+		return c.fetchBinario(pc, pid, programa.Tamaño)
+	}
+	if prefetchHabilitado() {
+		instructions, err := c.fetchPrefetch(pc, pid)
+		if errors.Is(err, errSinPermisoEjecucion) {
+			c.generarSegFault()
+		}
+		if err != nil {
+			log.Println(err)
+			return nil, 0, err
+		}
+		return strings.Split(instructions[0], ","), 1, nil
+	}
+
 	memoriaURL := fmt.Sprintf("http://%s:%d/getInstructionFromPid?pid=%d&programCounter=%d", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory, pid, pc)
 	resp, err := http.Get(memoriaURL)
 	if err != nil {
 		log.Fatalf("error al enviar la solicitud al módulo de memoria: %v", err)
-		return nil, err
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden {
+		log.Printf("PID: %d - SEGMENTATION FAULT - Fetch sin permiso de ejecución - PC: %d", pid, pc)
+		c.generarSegFault()
+		return nil, 0, errSinPermisoEjecucion
+	}
 	if resp.StatusCode != http.StatusOK {
 		err := fmt.Errorf("error en la respuesta del módulo de memoria: %v", resp.StatusCode)
 		log.Println(err)
-		return nil, err
+		return nil, 0, err
 	}
 
 	var response BodyResponseInstruction
 	err = json.NewDecoder(resp.Body).Decode(&response)
 	if err != nil {
 		log.Println("error al decodificar la respuesta del módulo de memoria:", err)
-		return nil, err
+		return nil, 0, err
 	}
 
 	instructions := strings.Split(response.Instruction, ",") // split the string into a slice
-	return instructions, nil
+	return instructions, 1, nil
 }
 
 func Decode(instruction []string) (string, error) {
 	// Esta función se va a complejizar con la traducción de las direcciones fisicas y logicas
-	words := strings.Fields(instruction[0])
 	if len(instruction) == 0 {
 		return "nil", fmt.Errorf("instrucción vacía")
 	}
-	return words[0], nil
+	// Memoria ya ensamblo el programa con la misma tabla, esto solo atrapa instrucciones que no deberian llegar
+	if err := instrucciones.Validar(instruction[0], nil); err != nil {
+		return "nil", err
+	}
+	return strings.Fields(instruction[0])[0], nil
 }
 
-func Execute(instruction string, line []string, contextoDeEjecucion *PCB) error {
+func (c *Core) Execute(instruction string, line []string, contextoDeEjecucion *PCB) error {
 
 	words := strings.Fields(line[0])
 
@@ -297,18 +405,38 @@ func Execute(instruction string, line []string, contextoDeEjecucion *PCB) error
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
+	case "MUL", "DIV", "MOD", "AND", "OR", "XOR", "SHL", "SHR":
+		err := c.OperacionALU(contextoDeEjecucion, instruction, words[1], words[2])
+		if err != nil {
+			return fmt.Errorf("error en execute: %s", err)
+		}
+	case "NOT":
+		err := NOT(contextoDeEjecucion, words[1])
+		if err != nil {
+			return fmt.Errorf("error en execute: %s", err)
+		}
+	case "CMP":
+		err := CMP(contextoDeEjecucion, words[1], words[2])
+		if err != nil {
+			return fmt.Errorf("error en execute: %s", err)
+		}
+	case "JZ", "JG", "JL", "JMP":
+		err := SaltoCondicional(contextoDeEjecucion, instruction, words[1])
+		if err != nil {
+			return fmt.Errorf("error en execute: %s", err)
+		}
 	case "IO_GEN_SLEEP":
-		err := IO(instruction, words, contextoDeEjecucion)
+		err := c.IO(instruction, words, contextoDeEjecucion)
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
 	case "IO_STDIN_READ":
-		err := IO(instruction, words, contextoDeEjecucion)
+		err := c.IO(instruction, words, contextoDeEjecucion)
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
 	case "IO_STDOUT_WRITE":
-		err := IO(instruction, words, contextoDeEjecucion)
+		err := c.IO(instruction, words, contextoDeEjecucion)
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
@@ -317,61 +445,101 @@ func Execute(instruction string, line []string, contextoDeEjecucion *PCB) error
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
-		sendResizeMemory(tam)
+		resultado, err := sendResizeMemory(contextoDeEjecucion.Pid, tam)
+		if err != nil {
+			return fmt.Errorf("error en execute: %s", err)
+		}
+		c.resultadoResize(resultado, contextoDeEjecucion)
 
+	case "PUSH":
+		err := c.PUSH(words, contextoDeEjecucion)
+		if err != nil {
+			return fmt.Errorf("error en execute: %s", err)
+		}
+	case "POP":
+		err := c.POP(words, contextoDeEjecucion)
+		if err != nil {
+			return fmt.Errorf("error en execute: %s", err)
+		}
+	case "CALL":
+		err := c.CALL(words, contextoDeEjecucion)
+		if err != nil {
+			return fmt.Errorf("error en execute: %s", err)
+		}
+	case "RET":
+		err := c.RET(contextoDeEjecucion)
+		if err != nil {
+			return fmt.Errorf("error en execute: %s", err)
+		}
+	case "MALLOC":
+		err := c.MALLOC(words, contextoDeEjecucion)
+		if err != nil {
+			return fmt.Errorf("error en execute: %s", err)
+		}
+	case "FREE":
+		err := c.FREE(words, contextoDeEjecucion)
+		if err != nil {
+			return fmt.Errorf("error en execute: %s", err)
+		}
 	case "MOV_IN":
-		err := MOV_IN(words, contextoDeEjecucion)
+		err := c.MOV_IN(words, contextoDeEjecucion)
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
 	case "MOV_OUT":
-		err := MOV_OUT(words, contextoDeEjecucion)
+		err := c.MOV_OUT(words, contextoDeEjecucion)
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
 	case "COPY_STRING":
-		err := COPY_STRING(words, contextoDeEjecucion)
+		err := c.COPY_STRING(words, contextoDeEjecucion)
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
 	case "WAIT":
-		err := CheckWait(nil, nil, contextoDeEjecucion, words[1])
+		err := c.CheckWait(nil, nil, contextoDeEjecucion, words[1])
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
 	case "SIGNAL":
-		err := CheckSignal(nil, nil, contextoDeEjecucion.Pid, instruction, words[1])
+		err := c.CheckSignal(nil, nil, contextoDeEjecucion.Pid, instruction, words[1])
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 
 		}
 	case "IO_FS_CREATE":
-		err := IO(instruction, words, contextoDeEjecucion)
+		err := c.IO(instruction, words, contextoDeEjecucion)
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
 	case "IO_FS_DELETE":
-		err := IO(instruction, words, contextoDeEjecucion)
+		err := c.IO(instruction, words, contextoDeEjecucion)
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
 	case "IO_FS_TRUNCATE":
-		err := IO(instruction, words, contextoDeEjecucion)
+		err := c.IO(instruction, words, contextoDeEjecucion)
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
 	case "IO_FS_WRITE":
-		err := IO(instruction, words, contextoDeEjecucion)
+		err := c.IO(instruction, words, contextoDeEjecucion)
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
 	case "IO_FS_READ":
-		err := IO(instruction, words, contextoDeEjecucion)
+		err := c.IO(instruction, words, contextoDeEjecucion)
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
+	case "CLI": // Las interrupciones enmascarables quedan pendientes hasta el STI
+		contextoDeEjecucion.CpuReg.FLAGS |= FlagMascara
+		log.Printf("PID: %d - Interrupciones enmascaradas", contextoDeEjecucion.Pid)
+	case "STI":
+		contextoDeEjecucion.CpuReg.FLAGS &^= FlagMascara
+		log.Printf("PID: %d - Interrupciones habilitadas", contextoDeEjecucion.Pid)
 	case "EXIT":
-		err := TerminarProceso(&contextoDeEjecucion.CpuReg, "FINALIZADO")
+		err := c.TerminarProceso(&contextoDeEjecucion.CpuReg, "FINALIZADO")
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
@@ -381,12 +549,12 @@ func Execute(instruction string, line []string, contextoDeEjecucion *PCB) error
 	return nil
 }
 
-func TerminarProceso(registerCPU *RegisterCPU, motivo string) error {
-	GLOBALrequestCPU = KernelRequest{
+func (c *Core) TerminarProceso(registerCPU *RegisterCPU, motivo string) error {
+	c.request = KernelRequest{
 		MotivoDesalojo: motivo,
 	}
 
-	interrupt = true // Aquí va el valor booleano que quieres enviar al kernel
+	c.interrupt = true // Aquí va el valor booleano que quieres enviar al kernel
 	registerCPU.PC--
 	return nil
 }
@@ -432,92 +600,38 @@ func SetCampo(r *RegisterCPU, campo string, valor interface{}) error {
 	default:
 		return fmt.Errorf("tipo de dato del campo '%s' no soportado", tipoCampo)
 	}
+	if campo == "SP" { // El programa eligio su SP, el primer PUSH no lo pisa con el tope de la pila
+		r.FLAGS |= FlagPila
+	}
 
 	return nil
 }
 
+// SUM y SUB dan la vuelta en el ancho del registro y dejan C y O igual que CMP (ver alu.go)
 func Suma(registerCPU *RegisterCPU, s1, s2 string) error {
-	valorRef := reflect.ValueOf(registerCPU)
-
-	campoDestinoRef := valorRef.Elem().FieldByName(s1)
-
-	if !campoDestinoRef.IsValid() {
-		return fmt.Errorf("campo destino '%s' no encontrado en la estructura", s1)
-	}
-
-	tipoCampoDestino := campoDestinoRef.Type()
-
-	campoOrigenRef := valorRef.Elem().FieldByName(s2)
-
-	if !campoOrigenRef.IsValid() {
-		return fmt.Errorf("campo origen '%s' no encontrado en la estructura", s2)
-	}
-
-	tipoCampoOrigen := campoOrigenRef.Type()
-	if tipoCampoDestino != tipoCampoOrigen {
-		return fmt.Errorf("los campos '%s' y '%s' no son del mismo tipo", s1, s2)
-	}
-
-	switch tipoCampoDestino.Kind() {
-	case reflect.Uint8:
-		valorDestino := campoDestinoRef.Uint()
-		valorOrigen := campoOrigenRef.Uint()
-		suma := valorDestino + valorOrigen
-		if suma > 255 {
-			suma = 255
-		}
-		campoDestinoRef.SetUint(suma)
-	case reflect.Uint32:
-		valorDestino := campoDestinoRef.Uint()
-		valorOrigen := campoOrigenRef.Uint()
-		suma := valorDestino + valorOrigen
-		if suma > 4294967295 { // Máximo valor para uint32
-			suma = 4294967295
-		}
-		campoDestinoRef.SetUint(suma)
-	}
-	return nil
+	return operacionAritmetica(registerCPU, s1, s2, sumar)
 }
 
 func Resta(registerCPU *RegisterCPU, s1, s2 string) error {
-	valorRef := reflect.ValueOf(registerCPU)
+	return operacionAritmetica(registerCPU, s1, s2, restar)
+}
 
-	campoDestinoRef := valorRef.Elem().FieldByName(s1)
-	if !campoDestinoRef.IsValid() {
+func operacionAritmetica(registerCPU *RegisterCPU, s1, s2 string, operacion func(a, b uint64, ancho uint) (uint64, bool, bool)) error {
+	campoDestinoRef, ancho, err := campoRegistro(registerCPU, s1)
+	if err != nil {
 		return fmt.Errorf("campo destino '%s' no encontrado en la estructura", s1)
 	}
-
-	tipoCampoDestino := campoDestinoRef.Type()
-
-	campoOrigenRef := valorRef.Elem().FieldByName(s2)
-	if !campoOrigenRef.IsValid() {
+	campoOrigenRef, anchoOrigen, err := campoRegistro(registerCPU, s2)
+	if err != nil {
 		return fmt.Errorf("campo origen '%s' no encontrado en la estructura", s2)
 	}
-
-	tipoCampoOrigen := campoOrigenRef.Type()
-	if tipoCampoDestino != tipoCampoOrigen {
+	if ancho != anchoOrigen {
 		return fmt.Errorf("los campos '%s' y '%s' no son del mismo tipo", s1, s2)
 	}
 
-	switch tipoCampoDestino.Kind() {
-	case reflect.Uint8:
-		valorDestino := campoDestinoRef.Uint()
-		valorOrigen := campoOrigenRef.Uint()
-		resta := valorDestino - valorOrigen
-		if resta <= 0 {
-			resta = 0
-		}
-		campoDestinoRef.SetUint(resta)
-	case reflect.Uint32:
-		valorDestino := campoDestinoRef.Uint()
-		valorOrigen := campoOrigenRef.Uint()
-		if valorDestino < valorOrigen {
-			campoDestinoRef.SetUint(0)
-		} else {
-			resta := valorDestino - valorOrigen
-			campoDestinoRef.SetUint(resta)
-		}
-	}
+	resultado, carry, overflow := operacion(campoDestinoRef.Uint(), campoOrigenRef.Uint(), ancho)
+	campoDestinoRef.SetUint(resultado)
+	setFlags(registerCPU, resultado, ancho, carry, overflow)
 	return nil
 }
 
@@ -546,7 +660,7 @@ func JNZ(registerCPU *RegisterCPU, reg, valor string) error {
 	return nil
 }
 
-func MOV_IN(words []string, contextoEjecucion *PCB) error {
+func (c *Core) MOV_IN(words []string, contextoEjecucion *PCB) error {
 	REGdireccion := words[2]
 	valueDireccion := verificarRegistro(REGdireccion, contextoEjecucion)
 
@@ -555,31 +669,34 @@ func MOV_IN(words []string, contextoEjecucion *PCB) error {
 	// Verificar el tipo de dato del registro en RegisterCPU
 	var tamREGdatos int
 	switch REGdatos {
-	case "PC", "EAX", "EBX", "ECX", "EDX", "SI", "DI":
+	case "PC", "EAX", "EBX", "ECX", "EDX", "SI", "DI", "SP":
 		tamREGdatos = 4 // uint32
-	case "AX", "BX", "CX", "DX":
+	case "AX", "BX", "CX", "DX", "ERR", "FLAGS":
 		tamREGdatos = 1 // uint8
 	default:
 		return fmt.Errorf("registro no soportado: %s", REGdatos)
 	}
 
-	direcciones := TranslateAddress(contextoEjecucion.Pid, valueDireccion, GLOBALpageTam, tamREGdatos)
+	direcciones := c.TranslateDataAddress(contextoEjecucion.Pid, valueDireccion, tamREGdatos, "R")
+	if direcciones == nil {
+		return fmt.Errorf("no se pudo traducir la dirección %d", valueDireccion)
+	}
 
-	err1 := LeerMemoria(contextoEjecucion.Pid, direcciones, tamREGdatos)
+	datos, err1 := c.LeerMemoria(contextoEjecucion.Pid, direcciones, tamREGdatos)
 	if err1 != nil {
 		return fmt.Errorf("error leyendo memoria: %s", err1)
 	}
 
-	//buf := bytes.NewReader(GLOBALdataMOV_IN)
+	//buf := bytes.NewReader(datos)
 	if tamREGdatos == 1 {
-		result := stringToUint8(string(GLOBALdataMOV_IN))
+		result := stringToUint8(string(datos))
 		log.Printf("PID: %d - Acción: LEER - Dirección Física: %d - Valor: %d", contextoEjecucion.Pid, direcciones[0], result)
 		err3 := SetCampo(&contextoEjecucion.CpuReg, REGdatos, result)
 		if err3 != nil {
 			return fmt.Errorf("error en execute: %s", err3)
 		}
 	} else {
-		result := stringToInteger(string(GLOBALdataMOV_IN))
+		result := stringToInteger(string(datos))
 		log.Printf("PID: %d - Acción: LEER - Dirección Física: %d - Valor: %d", contextoEjecucion.Pid, direcciones[0], result)
 		err3 := SetCampo(&contextoEjecucion.CpuReg, REGdatos, result)
 		if err3 != nil {
@@ -609,7 +726,7 @@ func stringToUint8(s string) uint8 {
 	return uint8(s[0])
 }
 
-func MOV_OUT(words []string, contextoEjecucion *PCB) error {
+func (c *Core) MOV_OUT(words []string, contextoEjecucion *PCB) error {
 	REGdireccion := words[1]
 	valueDireccion := verificarRegistro(REGdireccion, contextoEjecucion)
 
@@ -618,16 +735,19 @@ func MOV_OUT(words []string, contextoEjecucion *PCB) error {
 
 	var valueDatosBytes []byte
 	switch REGdatos {
-	case "PC", "EAX", "EBX", "ECX", "EDX", "SI", "DI":
+	case "PC", "EAX", "EBX", "ECX", "EDX", "SI", "DI", "SP":
 		valueDatosBytes = make([]byte, 4)
 		binary.BigEndian.PutUint32(valueDatosBytes, uint32(valueDatos))
-	case "AX", "BX", "CX", "DX":
+	case "AX", "BX", "CX", "DX", "ERR", "FLAGS":
 		valueDatosBytes = []byte{uint8(valueDatos)}
 	default:
 		return fmt.Errorf("registro no soportado: %s", REGdatos)
 	}
-	direcciones := TranslateAddress(contextoEjecucion.Pid, valueDireccion, GLOBALpageTam, len(valueDatosBytes))
-	err := EscribirMemoria(contextoEjecucion.Pid, direcciones, valueDatosBytes)
+	direcciones := c.TranslateDataAddress(contextoEjecucion.Pid, valueDireccion, len(valueDatosBytes), "W")
+	if direcciones == nil {
+		return fmt.Errorf("no se pudo traducir la dirección %d", valueDireccion)
+	}
+	err := c.EscribirMemoria(contextoEjecucion.Pid, direcciones, valueDatosBytes)
 	if err != nil {
 		return err
 	}
@@ -635,58 +755,95 @@ func MOV_OUT(words []string, contextoEjecucion *PCB) error {
 	return nil
 }
 
-func COPY_STRING(words []string, contextoEjecucion *PCB) error {
+func (c *Core) COPY_STRING(words []string, contextoEjecucion *PCB) error {
 	tamString := words[1]
 	tam, err := strconv.Atoi(tamString)
 	if err != nil {
 		return err
 	}
 	valorSI := verificarRegistro("SI", contextoEjecucion)
-	direccionesSI := TranslateAddress(contextoEjecucion.Pid, valorSI, GLOBALpageTam, tam)
+	direccionesSI := c.TranslateDataAddress(contextoEjecucion.Pid, valorSI, tam, "R")
+	if direccionesSI == nil {
+		return fmt.Errorf("no se pudo traducir la dirección %d", valorSI)
+	}
 
-	err1 := LeerMemoria(contextoEjecucion.Pid, direccionesSI, tam)
+	datos, err1 := c.LeerMemoria(contextoEjecucion.Pid, direccionesSI, tam)
 	if err1 != nil {
 		return err1
 	}
-	log.Printf("PID: %d - Acción: LEER - Dirección Física: %v - Valor: %s", contextoEjecucion.Pid, direccionesSI[0], GLOBALdataMOV_IN)
+	log.Printf("PID: %d - Acción: LEER - Dirección Física: %v - Valor: %s", contextoEjecucion.Pid, direccionesSI[0], datos)
 	valorDI := verificarRegistro("DI", contextoEjecucion)
-	direccionesDI := TranslateAddress(contextoEjecucion.Pid, valorDI, GLOBALpageTam, tam)
-	err2 := EscribirMemoria(contextoEjecucion.Pid, direccionesDI, GLOBALdataMOV_IN)
+	direccionesDI := c.TranslateDataAddress(contextoEjecucion.Pid, valorDI, tam, "W")
+	if direccionesDI == nil {
+		return fmt.Errorf("no se pudo traducir la dirección %d", valorDI)
+	}
+	err2 := c.EscribirMemoria(contextoEjecucion.Pid, direccionesDI, datos)
 	if err2 != nil {
 		return err2
 	}
-	log.Printf("PID: %d - Acción: ESCRIBIR - Dirección Física: %d - Valor: %s", contextoEjecucion.Pid, direccionesDI[0], GLOBALdataMOV_IN)
+	log.Printf("PID: %d - Acción: ESCRIBIR - Dirección Física: %d - Valor: %s", contextoEjecucion.Pid, direccionesDI[0], datos)
 	return nil
 }
 
-func LeerMemoria(pid int, direccion []int, size int) error {
+func (c *Core) LeerMemoria(pid int, direccion []int, size int) ([]byte, error) {
+	return c.leerMemoriaAcceso(pid, direccion, size, "R")
+}
+
+// El fetch binario lee con acceso X, asi memoria tambien controla el permiso de ejecucion
+func (c *Core) leerMemoriaAcceso(pid int, direccion []int, size int, acceso string) ([]byte, error) {
+	var datos []byte
+	var err error
+	if cacheDatosHabilitada() {
+		datos, err = c.leerConCache(pid, direccion, acceso)
+	} else {
+		datos, err = c.leerMemoriaFisica(pid, direccion, acceso)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.registrarLectura(direccion, datos[:min(size, len(datos))])
+	return datos, nil
+}
+
+// Memoria manda los datos a /core/{id}/receiveDataFromMemory antes de responder
+func (c *Core) leerMemoriaFisica(pid int, direccion []int, acceso string) ([]byte, error) {
 	memoriaURL := fmt.Sprintf("http://%s:%d/readMemory", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory)
 	req := MemoryReadRequest{
 		PID:     pid,
 		Address: direccion,
-		Size:    size,
+		Size:    len(direccion),
 		Type:    "CPU",
+		Acceso:  acceso,
+		Core:    c.ID,
 	}
 
 	reqJSON, err := json.Marshal(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	c.bus.Lock()
+	defer c.bus.Unlock()
+	c.datosMemoria = nil
 	resp, err := http.Post(memoriaURL, "application/json", bytes.NewBuffer(reqJSON))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("error en la respuesta del módulo de memoria: %v", resp.StatusCode)
+		return nil, fmt.Errorf("error en la respuesta del módulo de memoria: %v", resp.StatusCode)
 	}
 
-	return nil
+	return c.datosMemoria, nil
 }
 
+// POST /core/{id}/receiveDataFromMemory (o sin id para el core 0)
 func RecieveMOV_IN(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
 	var Content []byte
 	err := json.NewDecoder(r.Body).Decode(&Content)
 	if err != nil {
@@ -694,11 +851,25 @@ func RecieveMOV_IN(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	GLOBALdataMOV_IN = Content
+	c.datosMemoria = Content
 	w.WriteHeader(http.StatusOK)
 }
 
-func EscribirMemoria(pid int, direcciones []int, data []byte) error {
+func (c *Core) EscribirMemoria(pid int, direcciones []int, data []byte) error {
+	var err error
+	if cacheDatosHabilitada() {
+		err = c.escribirConCache(pid, direcciones, data)
+	} else {
+		err = escribirMemoriaFisica(pid, direcciones, data)
+	}
+	if err != nil {
+		return err
+	}
+	c.registrarEscritura(direcciones, data)
+	return nil
+}
+
+func escribirMemoriaFisica(pid int, direcciones []int, data []byte) error {
 	memoriaURL := fmt.Sprintf("http://%s:%d/writeMemory", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory)
 	var req MemoryReadRequest
 	req.PID = pid
@@ -723,8 +894,8 @@ func EscribirMemoria(pid int, direcciones []int, data []byte) error {
 	return nil
 }
 
-func IO(kind string, words []string, contextoEjecucion *PCB) error {
-	interrupt = true
+func (c *Core) IO(kind string, words []string, contextoEjecucion *PCB) error {
+	c.interrupt = true
 
 	switch kind {
 	case "IO_GEN_SLEEP":
@@ -732,7 +903,7 @@ func IO(kind string, words []string, contextoEjecucion *PCB) error {
 		if err != nil {
 			return err
 		}
-		GLOBALrequestCPU = KernelRequest{
+		c.request = KernelRequest{
 			PcbUpdated:     *contextoEjecucion,
 			MotivoDesalojo: "INTERRUPCION POR IO",
 			IoType:         "GENERICA",
@@ -746,9 +917,13 @@ func IO(kind string, words []string, contextoEjecucion *PCB) error {
 		lengthREG := words[3]
 		valueLength1 := verificarRegistro(lengthREG, contextoEjecucion)
 
-		direcciones := TranslateAddress(contextoEjecucion.Pid, valueAdress1, GLOBALpageTam, valueLength1)
+		direcciones := c.TranslateDataAddress(contextoEjecucion.Pid, valueAdress1, valueLength1, "W")
+		if direcciones == nil {
+			return fmt.Errorf("no se pudo traducir la dirección %d", valueAdress1)
+		}
+		esperarEscrituraDispositivo(contextoEjecucion.Pid, direcciones)
 		sendREGtoKernel(direcciones, valueLength1, contextoEjecucion.Pid)
-		GLOBALrequestCPU = KernelRequest{
+		c.request = KernelRequest{
 			PcbUpdated:     *contextoEjecucion,
 			MotivoDesalojo: "INTERRUPCION POR IO",
 			IoType:         "STDIN",
@@ -762,9 +937,12 @@ func IO(kind string, words []string, contextoEjecucion *PCB) error {
 		lengthREG := words[3]
 		valueLength := verificarRegistro(lengthREG, contextoEjecucion)
 
-		direcciones := TranslateAddress(contextoEjecucion.Pid, valueAdress, GLOBALpageTam, valueLength)
+		direcciones := c.TranslateDataAddress(contextoEjecucion.Pid, valueAdress, valueLength, "R")
+		if direcciones == nil {
+			return fmt.Errorf("no se pudo traducir la dirección %d", valueAdress)
+		}
 		sendREGtoKernel(direcciones, valueLength, contextoEjecucion.Pid)
-		GLOBALrequestCPU = KernelRequest{
+		c.request = KernelRequest{
 			PcbUpdated:     *contextoEjecucion,
 			MotivoDesalojo: "INTERRUPCION POR IO",
 			IoType:         "STDOUT",
@@ -773,7 +951,7 @@ func IO(kind string, words []string, contextoEjecucion *PCB) error {
 		}
 	case "IO_FS_CREATE":
 		fileName := words[2]
-		GLOBALrequestCPU = KernelRequest{
+		c.request = KernelRequest{
 			PcbUpdated:     *contextoEjecucion,
 			MotivoDesalojo: "INTERRUPCION POR IO",
 			IoType:         "DialFS",
@@ -785,7 +963,7 @@ func IO(kind string, words []string, contextoEjecucion *PCB) error {
 
 	case "IO_FS_DELETE":
 		fileName := words[2]
-		GLOBALrequestCPU = KernelRequest{
+		c.request = KernelRequest{
 			PcbUpdated:     *contextoEjecucion,
 			MotivoDesalojo: "INTERRUPCION POR IO",
 			IoType:         "DialFS",
@@ -798,7 +976,7 @@ func IO(kind string, words []string, contextoEjecucion *PCB) error {
 		fileName := words[2]
 		regTamano := words[3]
 		valueLength := verificarRegistro(regTamano, contextoEjecucion)
-		GLOBALrequestCPU = KernelRequest{
+		c.request = KernelRequest{
 			PcbUpdated:     *contextoEjecucion,
 			MotivoDesalojo: "INTERRUPCION POR IO",
 			IoType:         "DialFS",
@@ -819,9 +997,12 @@ func IO(kind string, words []string, contextoEjecucion *PCB) error {
 		regPuntero := words[5]
 		valuePuntero := verificarRegistro(regPuntero, contextoEjecucion)
 
-		direcFisica := TranslateAddress(contextoEjecucion.Pid, valueAdress, GLOBALpageTam, valueLength)
+		direcFisica := c.TranslateDataAddress(contextoEjecucion.Pid, valueAdress, valueLength, "R")
+		if direcFisica == nil {
+			return fmt.Errorf("no se pudo traducir la dirección %d", valueAdress)
+		}
 
-		GLOBALrequestCPU = KernelRequest{
+		c.request = KernelRequest{
 			PcbUpdated:     *contextoEjecucion,
 			MotivoDesalojo: "INTERRUPCION POR IO",
 			IoType:         "DialFS",
@@ -842,8 +1023,12 @@ func IO(kind string, words []string, contextoEjecucion *PCB) error {
 		regPuntero := words[5]
 		valuePuntero := verificarRegistro(regPuntero, contextoEjecucion)
 
-		direcFisica := TranslateAddress(contextoEjecucion.Pid, valueAdress, GLOBALpageTam, valueLength)
-		GLOBALrequestCPU = KernelRequest{
+		direcFisica := c.TranslateDataAddress(contextoEjecucion.Pid, valueAdress, valueLength, "W")
+		if direcFisica == nil {
+			return fmt.Errorf("no se pudo traducir la dirección %d", valueAdress)
+		}
+		esperarEscrituraDispositivo(contextoEjecucion.Pid, direcFisica)
+		c.request = KernelRequest{
 			PcbUpdated:     *contextoEjecucion,
 			MotivoDesalojo: "INTERRUPCION POR IO",
 			IoType:         "DialFS",
@@ -881,13 +1066,19 @@ func verificarRegistro(registerName string, contextoEjecucion *PCB) int {
 		registerValue = int(contextoEjecucion.CpuReg.ECX)
 	case "EDX":
 		registerValue = int(contextoEjecucion.CpuReg.EDX)
+	case "ERR":
+		registerValue = int(contextoEjecucion.CpuReg.ERR)
+	case "SP":
+		registerValue = int(contextoEjecucion.CpuReg.SP)
+	case "FLAGS":
+		registerValue = int(contextoEjecucion.CpuReg.FLAGS)
 	default:
 		log.Fatalf("Register %s not found", registerName)
 	}
 	return registerValue
 }
 
-func CheckSignal(w http.ResponseWriter, r *http.Request, pid int, motivo string, recurso string) error {
+func (c *Core) CheckSignal(w http.ResponseWriter, r *http.Request, pid int, motivo string, recurso string) error {
 	waitRequest := ResponseWait{
 		Recurso: recurso,
 		Pid:     pid,
@@ -922,7 +1113,7 @@ func CheckSignal(w http.ResponseWriter, r *http.Request, pid int, motivo string,
 		return err
 	}
 	if signalResponse.Success == "exit" {
-		err := TerminarProceso(&GLOBALcontextoDeEjecucion.CpuReg, "INVALID_RESOURCE")
+		err := c.TerminarProceso(&c.contexto.CpuReg, "INVALID_RESOURCE")
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
@@ -930,7 +1121,7 @@ func CheckSignal(w http.ResponseWriter, r *http.Request, pid int, motivo string,
 	return nil
 }
 
-func CheckWait(w http.ResponseWriter, r *http.Request, registerCPU *PCB, recurso string) error {
+func (c *Core) CheckWait(w http.ResponseWriter, r *http.Request, registerCPU *PCB, recurso string) error {
 	waitRequest := ResponseWait{
 		Recurso: recurso,
 		Pid:     registerCPU.Pid,
@@ -965,13 +1156,13 @@ func CheckWait(w http.ResponseWriter, r *http.Request, registerCPU *PCB, recurso
 		return err
 	}
 	if waitResponse.Success == "false" {
-		interrupt = true
-		GLOBALrequestCPU = KernelRequest{
+		c.interrupt = true
+		c.request = KernelRequest{
 			MotivoDesalojo: "WAIT",
 			Recurso:        recurso,
 		}
 	} else if waitResponse.Success == "exit" {
-		err := TerminarProceso(&GLOBALcontextoDeEjecucion.CpuReg, "INVALID_RESOURCE")
+		err := c.TerminarProceso(&c.contexto.CpuReg, "INVALID_RESOURCE")
 		if err != nil {
 			return fmt.Errorf("error en execute: %s", err)
 		}
@@ -981,6 +1172,10 @@ func CheckWait(w http.ResponseWriter, r *http.Request, registerCPU *PCB, recurso
 }
 
 func Checkinterrupts(w http.ResponseWriter, r *http.Request) { // A chequear
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
 
 	var responseInterruptLocal ResponseInterrupt
 
@@ -990,75 +1185,24 @@ func Checkinterrupts(w http.ResponseWriter, r *http.Request) { // A chequear
 		return
 	}
 
-	if responseInterruptLocal.Motivo == "INTERRUPTED_BY_USER" {
-		// Siempre procesar INTERRUPTED_BY_USER inmediatamente
-		responseInterruptGlobal = responseInterruptLocal
-	} else if responseInterruptLocal.Motivo == "CLOCK" {
-		// Verificar si ya hay una interrupción pendiente
-		if responseInterruptGlobal.Interrupt && responseInterruptGlobal.Motivo == "INTERRUPTED_BY_USER" {
-			// Ya hay una interrupción de mayor prioridad, ignorar CLOCK
-		} else {
-			// No hay interrupción de mayor prioridad, procesar CLOCK
-			responseInterruptGlobal = responseInterruptLocal
-		}
-	}
+	// La prioridad, la mascara y el PID se resuelven al atenderla, entre instrucciones
+	c.interrupciones.recibir(responseInterruptLocal)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(interrupt)
-}
-
-func CheckTLB(pid, page int) (int, bool) { //Verifica si la etrada ya estaba en la globalTLB. Si se usa LRU, actualiza el tiempo de acceso
-	for i, entry := range globalTLB {
-		if entry.PID == pid && entry.Pagina == page {
-			if replacementAlgorithm == "LRU" {
-				globalTLB[i].UltimoAcceso = time.Now()
-			}
-			return entry.Frame, true
-		}
-	}
-	return -1, false
-}
-
-func ReplaceTLBEntry(pid, page, frame int) { //Reemplazo una entrada de globalTLB según el algoritmo de reemplazo
-	newEntry := TLBEntry{
-		PID:                pid,
-		Pagina:             page,
-		Frame:              frame,
-		UltimoAcceso:       time.Now(),
-		globalPosicionFila: globalPosicionFila,
-	}
-
-	if len(globalTLB) < globalTLBsize {
-		globalTLB = append(globalTLB, newEntry) //Si la globalTLB no está llena, agrego la entrada
-	} else {
-		if replacementAlgorithm == "FIFO" {
-			victima := 0
-			for i, entry := range globalTLB {
-				if entry.globalPosicionFila < globalTLB[victima].globalPosicionFila {
-					victima = i
-				}
-			}
-			globalTLB[victima] = newEntry
-		} else if replacementAlgorithm == "LRU" {
-			victima := 0
-			for i, entry := range globalTLB {
-				if entry.UltimoAcceso.Before(globalTLB[victima].UltimoAcceso) {
-					victima = i
-				}
-			}
-			globalTLB[victima] = newEntry
-		}
-	}
-	globalPosicionFila++
+	json.NewEncoder(w).Encode(c.interrupt)
 }
 
 func TranslateHandler(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
 	var req TranslationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 	// Realizar la traducción
-	addresses := TranslateAddress(req.PID, req.DireccionLogica, req.TamPag, req.TamData)
+	addresses := c.TranslateAddress(req.PID, req.DireccionLogica, req.TamPag, req.TamData)
 
 	// Responder con las direcciones físicas
 	res := TranslationResponse{DireccionesFisicas: addresses}
@@ -1066,40 +1210,85 @@ func TranslateHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(res)
 }
 
-func TranslateAddress(pid, DireccionLogica, TamPag, TamData int) []int {
+func (c *Core) TranslateAddress(pid, DireccionLogica, TamPag, TamData int) []int {
+	return c.TranslateAddressAcceso(pid, DireccionLogica, TamPag, TamData, "R")
+}
+
+// Igual que TranslateAddress pero indicando si el acceso es de lectura (R) o escritura (W)
+func (c *Core) TranslateAddressAcceso(pid, DireccionLogica, TamPag, TamData int, acceso string) []int {
+	if TamData <= 0 {
+		return []int{} // Nada que traducir, nil queda reservado para los errores
+	}
+	if GLOBALmodoMemoria == "SEGMENTACION" || GLOBALmodoMemoria == "SEGMENTACION_PAGINADA" {
+		return c.TranslateSegmentAddress(pid, DireccionLogica, TamData, acceso)
+	}
+
 	var DireccionesFisicas []int
-	cache := make(map[int]int)    // Mapa para no buscar 10 veces el mismo marco
-	tlbHits := make(map[int]bool) // Mapa para registrar los TLB hits por página
+	cache := make(map[int]int)            // Mapa para no buscar 10 veces el mismo marco
+	cachePermisos := make(map[int]string) // Permisos de las paginas que estan en cache
+	copiadas := make(map[int]bool)        // Paginas que ya se copiaron por copy-on-write en esta traduccion
 
 	for i := 0; i < TamData; i++ {
 		pageNumber := int(math.Floor(float64(DireccionLogica) / float64(TamPag)))
 		pageOffset := DireccionLogica - (pageNumber * TamPag)
-		frame, found := CheckTLB(pid, pageNumber)
+		frame, enCache := cache[pageNumber]
+		permisos := cachePermisos[pageNumber]
 
-		if !found {
-			if cachedFrame, ok := cache[pageNumber]; ok {
-				frame = cachedFrame
+		if !enCache { // La TLB se consulta una vez por pagina, no por cada byte
+			var found bool
+			frame, permisos, found = c.CheckTLB(pid, pageNumber)
+			if found {
+				log.Printf("PID: %d - TLB HIT - Página: %d", pid, pageNumber)
 			} else {
 				log.Printf("PID: %d - TLB MISS - Página: %d", pid, pageNumber)
-				err := FetchFrameFromMemory(pid, pageNumber)
+				inicioRecorrido := time.Now()
+				respuesta, err := c.FetchFrameFromMemory(pid, pageNumber)
 				if err != nil {
 					fmt.Println("Error al obtener el marco desde la memoria")
 					return nil
 				}
-				frame = MemoryFrame
-				cache[pageNumber] = frame
+				if GLOBALnivelesTablas > 1 {
+					log.Printf("PID: %d - Recorrido de %d niveles de tablas - Página: %d - Tiempo: %v", pid, GLOBALnivelesTablas, pageNumber, time.Since(inicioRecorrido))
+				}
+				if respuesta.SegFault {
+					log.Printf("PID: %d - SEGMENTATION FAULT - Página: %d fuera del proceso", pid, pageNumber)
+					c.generarSegFault()
+					return nil
+				}
+				if respuesta.PageFault {
+					log.Printf("PID: %d - Page Fault - Página: %d", pid, pageNumber)
+					c.generarPageFault(pageNumber)
+					return nil
+				}
+				frame = respuesta.Frame
+				permisos = respuesta.Permisos
 				log.Printf("PID: %d - OBTENER MARCO - Página: %d - Marco: %d", pid, pageNumber, frame)
 				if globals.ClientConfig.NumberFellingTLB > 0 {
-					ReplaceTLBEntry(pid, pageNumber, MemoryFrame)
+					c.ReplaceTLBEntry(pid, pageNumber, frame, permisos)
 				}
 			}
-		} else {
-			// Verificar si ya se ha registrado un hit para esta página
-			if !tlbHits[pageNumber] {
-				log.Printf("PID: %d - TLB HIT - Página: %d", pid, pageNumber)
-				tlbHits[pageNumber] = true
+			cache[pageNumber] = frame
+			cachePermisos[pageNumber] = permisos
+		}
+
+		if permisos != "" && !strings.Contains(permisos, acceso) && acceso == "W" && !copiadas[pageNumber] {
+			copiado, sinMemoria := c.copyOnWrite(pid, pageNumber)
+			if sinMemoria {
+				return nil
+			}
+			if copiado { // Se vuelve a traducir la pagina, ahora con su marco propio
+				copiadas[pageNumber] = true
+				delete(cache, pageNumber)
+				c.tlb.Invalidar(pid, pageNumber)
+				i--
+				continue
 			}
 		}
+		if permisos != "" && !strings.Contains(permisos, acceso) {
+			log.Printf("PID: %d - SEGMENTATION FAULT - Página: %d - Acceso: %s - Permisos: %s", pid, pageNumber, acceso, permisos)
+			c.generarSegFault()
+			return nil
+		}
 
 		DireccionFisica := frame*TamPag + pageOffset
 		DireccionesFisicas = append(DireccionesFisicas, DireccionFisica)
@@ -1108,6 +1297,46 @@ func TranslateAddress(pid, DireccionLogica, TamPag, TamData int) []int {
 	return DireccionesFisicas
 }
 
+// La direccion logica es [numero de segmento | offset]
+func (c *Core) TranslateSegmentAddress(pid, DireccionLogica, TamData int, acceso string) []int {
+	numSegmento := DireccionLogica / GLOBALtamMaxSegmento
+	offset := DireccionLogica % GLOBALtamMaxSegmento
+
+	memoriaURL := fmt.Sprintf("http://%s:%d/getSegmentFromCPU", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory)
+	req := BodySegmentRequest{
+		Pid:      pid,
+		Segmento: numSegmento,
+		Offset:   offset,
+		Size:     TamData,
+		Acceso:   acceso,
+	}
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		log.Printf("Error al serializar el segmento: %v", err)
+		return nil
+	}
+
+	resp, err := http.Post(memoriaURL, "application/json", bytes.NewBuffer(reqJSON))
+	if err != nil {
+		log.Printf("error al enviar la solicitud al módulo de memoria: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+
+	var response BodySegmentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		log.Printf("error al decodificar la respuesta del módulo de memoria: %v", err)
+		return nil
+	}
+	if response.SegFault {
+		log.Printf("PID: %d - SEGMENTATION FAULT - Segmento: %d - Offset: %d - Tamaño: %d - %s", pid, numSegmento, offset, TamData, response.Motivo)
+		c.generarSegFault()
+		return nil
+	}
+	log.Printf("PID: %d - OBTENER SEGMENTO - Segmento: %d - Offset: %d - Dirección Física: %d", pid, numSegmento, offset, response.Direcciones[0])
+	return response.Direcciones
+}
+
 func TamRestantePagina(dirLog, tamPag int) int {
 	// Calcular el offset dentro de la página actual
 	offsetEnPagina := dirLog % tamPag
@@ -1118,42 +1347,115 @@ func TamRestantePagina(dirLog, tamPag int) int {
 	return tamRestante
 }
 
-// simulacion de la obtención de un marco desde la memoria
-func FetchFrameFromMemory(pid, pageNumber int) error {
+// simulacion de la obtención de un marco desde la memoria, que lo manda a /core/{id}/recieveFrame antes de responder
+func (c *Core) FetchFrameFromMemory(pid, pageNumber int) (BodyFrame, error) {
 	memoryURL := fmt.Sprintf("http://%s:%d/getFramefromCPU", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory)
 	var pageTable bodyPageTable
 	pageTable.Pid = pid
 	pageTable.Page = pageNumber
+	pageTable.Core = c.ID
+	if GLOBALnivelesTablas > 1 {
+		pageTable.Indices = paginacion.IndicesDePagina(pageNumber, GLOBALnivelesTablas, GLOBALentradasPorTabla)
+	}
 
 	pageTableJSON, err := json.Marshal(pageTable)
 	if err != nil {
 		log.Fatalf("Error al serializar el Input: %v", err)
 	}
 
+	c.bus.Lock()
+	defer c.bus.Unlock()
 	resp, err := http.Post(memoryURL, "application/json", bytes.NewBuffer(pageTableJSON))
 	if err != nil {
 		log.Fatalf("error al enviar la solicitud al módulo de memoria: %v", err)
 	}
 	defer resp.Body.Close()
-	return nil
+	return c.marcoMemoria, nil
 }
 
+// POST /core/{id}/recieveFrame (o sin id para el core 0)
 func RecieveFramefromMemory(w http.ResponseWriter, r *http.Request) {
+	c := coreDelPedido(w, r)
+	if c == nil {
+		return
+	}
 	var bodyFrame BodyFrame
 	err := json.NewDecoder(r.Body).Decode(&bodyFrame)
 	if err != nil {
 		http.Error(w, "Error al decodificar los datos JSON", http.StatusInternalServerError)
 		return
 	}
-	MemoryFrame = bodyFrame.Frame
+	c.marcoMemoria = bodyFrame
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Devuelve el proceso al kernel para que memoria traiga la pagina desde swap
+func (c *Core) generarPageFault(pagina int) {
+	c.interrupt = true
+	c.request = KernelRequest{
+		MotivoDesalojo: "PAGE_FAULT",
+		Pagina:         pagina,
+	}
+}
+
+// Escritura en una pagina sin W: si memoria la tiene compartida por copy-on-write le da al proceso un marco
+// propio y la escritura se puede hacer. Si no hay marcos el kernel finaliza el proceso con OUT_OF_MEMORY
+func (c *Core) copyOnWrite(pid int, pagina int) (copiado bool, sinMemoria bool) {
+	memoriaURL := fmt.Sprintf("http://%s:%d/copyOnWrite", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory)
+	bodyJSON, err := json.Marshal(bodyPageTable{Pid: pid, Page: pagina})
+	if err != nil {
+		return false, false
+	}
+	resp, err := http.Post(memoriaURL, "application/json", bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		log.Printf("PID: %d - Error al pedir copy-on-write de la página %d: %v", pid, pagina, err)
+		return false, false
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		log.Printf("PID: %d - COPY ON WRITE - Página: %d", pid, pagina)
+		return true, false
+	case http.StatusInsufficientStorage:
+		log.Printf("PID: %d - OUT OF MEMORY - Sin marcos para copiar la página %d", pid, pagina)
+		c.interrupt = true
+		c.request = KernelRequest{
+			MotivoDesalojo: "OUT_OF_MEMORY",
+		}
+		return false, true
+	}
+	return false, false
+}
+
+// Acceso fuera del limite o sin permiso, el kernel finaliza el proceso
+func (c *Core) generarSegFault() {
+	c.interrupt = true
+	c.request = KernelRequest{
+		MotivoDesalojo: "SEGMENTATION_FAULT",
+	}
+}
+
+// Memoria avisa que desalojo una pagina, su entrada en la TLB ya no es valida
+func InvalidateTLBEntry(w http.ResponseWriter, r *http.Request) {
+	var body bodyPageTable
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, c := range cores { // Memoria no sabe en que core estuvo el proceso
+		c.tlb.Invalidar(body.Pid, body.Page)
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func sendResizeMemory(tam int) {
+func sendResizeMemory(pid int, tam int) (BodyResize, error) {
 	memoriaURL := fmt.Sprintf("http://%s:%d/resizeProcess", globals.ClientConfig.IPMemory, globals.ClientConfig.PortMemory)
 	var process bodyProcess
-	process.Pid = GLOBALcontextoDeEjecucion.Pid
+	process.Pid = pid
 	process.Pages = tam
 
 	bodyResizeJSON, err := json.Marshal(process)
@@ -1167,6 +1469,38 @@ func sendResizeMemory(tam int) {
 	}
 	defer resp.Body.Close()
 
+	var resultado BodyResize
+	if resp.StatusCode != http.StatusOK {
+		return resultado, fmt.Errorf("error en la respuesta del módulo de memoria: %v", resp.StatusCode)
+	}
+	json.NewDecoder(resp.Body).Decode(&resultado)
+	return resultado, nil
+}
+
+// Que hacer cuando memoria no pudo hacer el RESIZE segun su politica. Con KILL memoria ya
+// le pidio al kernel que finalice el proceso, asi que no hay nada que hacer
+func (c *Core) resultadoResize(resultado BodyResize, contextoDeEjecucion *PCB) {
+	c.registrarResultado(resultado.Resultado)
+	if resultado.Resultado != "OUT_OF_MEMORY" {
+		contextoDeEjecucion.CpuReg.ERR = 0
+		return
+	}
+	switch resultado.Politica {
+	case "FAIL":
+		log.Printf("PID: %d - RESIZE fallido - Out of Memory", contextoDeEjecucion.Pid)
+		contextoDeEjecucion.CpuReg.ERR = 1
+	case "BLOCK":
+		log.Printf("PID: %d - Esperando memoria para el RESIZE", contextoDeEjecucion.Pid)
+		c.interrupt = true
+		c.request = KernelRequest{
+			MotivoDesalojo: "ESPERA_MEMORIA",
+		}
+	}
+}
+
+// Motivos de desalojo en los que la instruccion no se completo y se vuelve a ejecutar (o queda para el log)
+func reintentaInstruccion(motivo string) bool {
+	return motivo == "PAGE_FAULT" || motivo == "SEGMENTATION_FAULT" || motivo == "ESPERA_MEMORIA" || motivo == "STACK_OVERFLOW" || motivo == "DIVISION_BY_ZERO" || motivo == "OUT_OF_MEMORY"
 }
 
 func sendREGtoKernel(adress []int, length int, pid int) {
@@ -1216,6 +1550,15 @@ func ReceiveTamPage(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if err := validarLineaCache(req.PageTam); err != nil {
+		log.Printf("Configuración de cache inválida: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	GLOBALpageTam = req.PageTam
+	GLOBALnivelesTablas = req.Niveles
+	GLOBALentradasPorTabla = req.EntradasPorTabla
+	GLOBALmodoMemoria = req.ModoMemoria
+	GLOBALtamMaxSegmento = req.TamMaxSegmento
 	w.WriteHeader(http.StatusOK)
 }
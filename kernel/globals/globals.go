@@ -12,6 +12,7 @@ type Config struct {
 	Recursos               []string `json:"resources"`
 	InstanciasRecursos     []int    `json:"resource_instances"`
 	Multiprogramacion      int      `json:"multiprogramming"`
+	DispatchTimeout        int      `json:"dispatch_timeout"` // Milisegundos que se espera a la CPU (confirmar un despacho o desalojar despues de una interrupcion) antes de consultar el core, 0 espera siempre
 }
 
 var ClientConfig *Config
@@ -17,12 +17,14 @@ func main() {
 	if globals.ClientConfig == nil {
 		log.Fatalf("No se pudo cargar la configuración")
 	}
+	utils.IniciarKernel()
 
 	puerto := globals.ClientConfig.Puerto
 
 	http.HandleFunc("PUT /process", utils.IniciarProceso)
 
 	http.HandleFunc("POST /syscall", utils.ProcessSyscall)
+	http.HandleFunc("POST /memoryFreed", utils.MemoriaLiberada)
 	http.HandleFunc("POST /SendPortOfInterfaceToKernel", utils.RecievePortOfInterfaceFromIO)
 	http.HandleFunc("POST /recieveREG", utils.RecieveREGFromCPU)
 
@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sisoputnfrba/tp-golang/kernel/globals"
+)
+
+// CPU (y memoria) de prueba: anota las rutas que recibe y responde /receivePCB y /core/{id}/interrupts
+// con lo que diga el test
+type cpuDePrueba struct {
+	sync.Mutex
+	pedidos        []string
+	estadoDespacho int           // Lo que responde /receivePCB
+	demora         time.Duration // Antes de responder /receivePCB
+	ack            BodyAckDispatch
+	core           BodyEstadoCore // Lo que responde /core/{id}/interrupts
+	coreCaido      bool           // /core/{id}/interrupts responde 500
+}
+
+func (c *cpuDePrueba) recibidos() []string {
+	c.Lock()
+	defer c.Unlock()
+	return append([]string{}, c.pedidos...)
+}
+
+// Deja el kernel sin procesos, con la CPU ocupada por el planificador (como executeProcessFIFO antes de
+// executeTask) y la CPU y memoria apuntando a un servidor de prueba
+func configurarKernel(t *testing.T, timeout int) *cpuDePrueba {
+	t.Helper()
+	anterior := globals.ClientConfig
+	t.Cleanup(func() { globals.ClientConfig = anterior })
+
+	cpu := &cpuDePrueba{estadoDespacho: http.StatusAccepted}
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cpu.Lock()
+		cpu.pedidos = append(cpu.pedidos, strings.TrimPrefix(r.URL.Path, "/"))
+		estado, demora, ack, core, coreCaido := cpu.estadoDespacho, cpu.demora, cpu.ack, cpu.core, cpu.coreCaido
+		cpu.Unlock()
+		switch {
+		case r.URL.Path == "/receivePCB":
+			var body BodyDispatch
+			json.NewDecoder(r.Body).Decode(&body)
+			time.Sleep(demora)
+			ack.Dispatch = body.Dispatch
+			w.WriteHeader(estado)
+			json.NewEncoder(w).Encode(ack)
+		case strings.HasSuffix(r.URL.Path, "/interrupts") && coreCaido:
+			w.WriteHeader(http.StatusInternalServerError)
+		case strings.HasSuffix(r.URL.Path, "/interrupts"):
+			json.NewEncoder(w).Encode(core)
+		}
+	}))
+	t.Cleanup(servidor.Close)
+	url, _ := neturl.Parse(servidor.URL)
+	puerto, _ := strconv.Atoi(url.Port())
+
+	globals.ClientConfig = &globals.Config{
+		IpCPU:                  url.Hostname(),
+		PuertoCPU:              puerto,
+		IpMemoria:              url.Hostname(),
+		PuertoMemoria:          puerto,
+		DispatchTimeout:        timeout,
+		AlgoritmoPlanificacion: "FIFO",
+	}
+	readyChannel = make(chan PCB, 10)
+	multiProgramacion = make(chan int, 10)
+	multiProgramacion <- 1
+	colaReady, colaExecution, colaExit = nil, nil, nil
+	despachoEnCurso, done = nil, nil
+	mutexExecutionCPU.Lock()
+	t.Cleanup(func() {
+		mutexDespacho.Lock()
+		if despachoEnCurso != nil && despachoEnCurso.timer != nil {
+			despachoEnCurso.timer.Stop()
+		}
+		despachoEnCurso = nil
+		mutexDespacho.Unlock()
+		mutexExecutionCPU.TryLock()
+		mutexExecutionCPU.Unlock()
+	})
+	return cpu
+}
+
+func cpuLibre() bool {
+	if !mutexExecutionCPU.TryLock() {
+		return false
+	}
+	mutexExecutionCPU.Unlock()
+	return true
+}
+
+// Espera hasta un segundo a que se cumpla la condicion, el desalojo y los vencimientos van en otras goroutines
+func esperar(condicion func() bool) bool {
+	for intento := 0; intento < 1000; intento++ {
+		if condicion() {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
+
+func enCola(cola *[]PCB, mutex *sync.Mutex, pid int) bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return slices.ContainsFunc(*cola, func(pcb PCB) bool { return pcb.Pid == pid })
+}
+
+func idDespacho() int {
+	mutexDespacho.Lock()
+	defer mutexDespacho.Unlock()
+	if despachoEnCurso == nil {
+		return 0
+	}
+	return despachoEnCurso.id
+}
+
+// La CPU devuelve el proceso por /syscall
+func desalojar(id int, motivo string) int {
+	body, _ := json.Marshal(KernelRequest{PcbUpdated: ExecutionContext{Pid: 5, State: "EXEC"}, MotivoDesalojo: motivo, Dispatch: id})
+	w := httptest.NewRecorder()
+	ProcessSyscall(w, httptest.NewRequest("POST", "/syscall", bytes.NewReader(body)))
+	return w.Code
+}
+
+func TestDespachoRechazado(t *testing.T) {
+	casos := []struct {
+		nombre string
+		estado int
+		caida  bool
+	}{
+		{"core ocupado", http.StatusConflict, false},
+		{"error de la CPU", http.StatusInternalServerError, false},
+		{"CPU caida", http.StatusAccepted, true},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			cpu := configurarKernel(t, 0)
+			cpu.estadoDespacho = caso.estado
+			if caso.caida {
+				caida := httptest.NewServer(http.NotFoundHandler())
+				url, _ := neturl.Parse(caida.URL)
+				caida.Close()
+				globals.ClientConfig.PuertoCPU, _ = strconv.Atoi(url.Port())
+			}
+
+			executeTask(PCB{Pid: 5, State: "READY"})
+			select {
+			case <-readyChannel:
+			case <-time.After(time.Second):
+				t.Fatal("el proceso no volvio a READY")
+			}
+			if !enCola(&colaReady, &mutexReady, 5) || enCola(&colaExecution, &mutexExecution, 5) {
+				t.Errorf("ready %v, exec %v", listarIds(colaReady), listarIds(colaExecution))
+			}
+			if idDespacho() != 0 || !cpuLibre() {
+				t.Error("el despacho rechazado no libero la CPU")
+			}
+		})
+	}
+}
+
+func TestDespachoConfirmado(t *testing.T) {
+	cpu := configurarKernel(t, 0)
+	cpu.ack.Core = 1
+
+	executeTask(PCB{Pid: 5, State: "READY"})
+	id := idDespacho()
+	if id == 0 || despachoEnCurso.core != 1 || cpuLibre() {
+		t.Fatalf("despacho en curso %+v, se esperaba el core 1 con la CPU ocupada", despachoEnCurso)
+	}
+
+	// Un desalojo de otro despacho (vencido o repetido) se descarta
+	if estado := desalojar(id+1, "FINALIZADO"); estado != http.StatusConflict {
+		t.Errorf("desalojo de otro despacho = %d, se esperaba 409", estado)
+	}
+	if idDespacho() != id || !enCola(&colaExecution, &mutexExecution, 5) {
+		t.Fatal("el desalojo descartado saco al proceso")
+	}
+
+	if estado := desalojar(id, "FINALIZADO"); estado != http.StatusOK {
+		t.Fatalf("desalojo = %d, se esperaba 200", estado)
+	}
+	if !enCola(&colaExit, &mutexExit, 5) || idDespacho() != 0 || !cpuLibre() {
+		t.Errorf("exit %v, despacho %d", listarIds(colaExit), idDespacho())
+	}
+	if estado := desalojar(id, "FINALIZADO"); estado != http.StatusConflict {
+		t.Errorf("desalojo repetido = %d, se esperaba 409", estado)
+	}
+}
+
+func TestDespachoVencido(t *testing.T) {
+	casos := []struct {
+		nombre      string
+		sinAck      bool // La CPU no confirma el despacho a tiempo
+		coreCaido   bool
+		conDespacho bool // El core informa que sigue con el despacho
+		finaliza    bool
+	}{
+		{"la CPU no confirma", true, false, false, true},
+		{"el core no tiene el despacho", false, false, false, true},
+		{"la CPU no responde la consulta", false, true, false, true},
+		{"el core sigue con el despacho", false, false, true, false},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			cpu := configurarKernel(t, 30)
+			if caso.sinAck {
+				cpu.demora = 200 * time.Millisecond
+			}
+			cpu.coreCaido = caso.coreCaido
+
+			executeTask(PCB{Pid: 5, State: "READY"})
+			id := idDespacho()
+			if caso.conDespacho {
+				cpu.Lock()
+				cpu.core = BodyEstadoCore{Despacho: id, Enmascarado: true}
+				cpu.Unlock()
+			}
+			if !caso.sinAck {
+				SendInterrupt(5, "CLOCK") // Desde la interrupcion se espera el desalojo
+			}
+
+			if !caso.finaliza {
+				time.Sleep(100 * time.Millisecond) // Mas de dos vencimientos
+				if idDespacho() != id || cpuLibre() {
+					t.Fatal("se dio por perdido un despacho que el core sigue teniendo")
+				}
+				if estado := desalojar(id, "CLOCK"); estado != http.StatusOK {
+					t.Fatalf("desalojo = %d, se esperaba 200", estado)
+				}
+				<-readyChannel
+				return
+			}
+			if !esperar(cpuLibre) {
+				t.Fatal("el despacho vencido no libero la CPU")
+			}
+			if !enCola(&colaExit, &mutexExit, 5) || idDespacho() != 0 {
+				t.Errorf("exit %v, despacho %d", listarIds(colaExit), idDespacho())
+			}
+			if !esperar(func() bool {
+				return slices.Contains(cpu.recibidos(), "interrupt") && slices.Contains(cpu.recibidos(), "terminateProcess")
+			}) {
+				t.Errorf("la CPU y memoria recibieron %v, se esperaba la interrupcion y el fin del proceso", cpu.recibidos())
+			}
+			if estado := desalojar(id, "CLOCK"); estado != http.StatusConflict {
+				t.Errorf("desalojo despues de vencer = %d, se esperaba 409", estado)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
@@ -66,17 +67,20 @@ type ExecutionContext struct {
 }
 
 type RegisterCPU struct {
-	PC  uint32
-	AX  uint8
-	BX  uint8
-	CX  uint8
-	DX  uint8
-	EAX uint32
-	EBX uint32
-	ECX uint32
-	EDX uint32
-	SI  uint32
-	DI  uint32
+	PC    uint32
+	AX    uint8
+	BX    uint8
+	CX    uint8
+	DX    uint8
+	ERR   uint8
+	FLAGS uint8
+	EAX   uint32
+	EBX   uint32
+	ECX   uint32
+	EDX   uint32
+	SI    uint32
+	DI    uint32
+	SP    uint32
 }
 
 // Estructura para la interfaz genérica
@@ -106,6 +110,15 @@ type KernelRequest struct {
 	Interface      string           `json:"interface"`
 	IoType         string           `json:"ioType"`
 	Recurso        string           `json:"recurso"`
+	Pagina         int              `json:"pagina"`
+	Core           int              `json:"core"`     // Core de la CPU que ejecuto el proceso
+	Dispatch       int              `json:"dispatch"` // Despacho al que corresponde el desalojo
+}
+
+// Lo que se manda a la CPU: el PCB y el id del despacho, que la CPU devuelve en /syscall
+type BodyDispatch struct {
+	PCB
+	Dispatch int `json:"dispatch"`
 }
 
 type RequestInterrupt struct {
@@ -170,6 +183,10 @@ var colaReady []PCB
 var colaReadyVRR []PCB
 var colaExecution []PCB
 var colaBlocked = make(map[string][]PCB) // Tiene que ser un map string[]PCB[]
+
+// Cuantas veces aviso memoria que libero marcos. Se protege con mutexBlocked
+var liberacionesMemoria int
+
 var colaExit []PCB
 
 var multiProgramacion chan int
@@ -186,7 +203,7 @@ var mutexQuantum sync.Mutex
 
 // --------------------------------------------------------
 // ----------DECLARACION MUTEX MÓDULO----------------
-var mutexExecutionCPU sync.Mutex // este mutex es para que no se envie dos procesos al mismo tiempo a la cpu
+var mutexExecutionCPU sync.Mutex // este mutex es para que no se envie dos procesos al mismo tiempo a la cpu (hay un solo EXEC)
 var mutexExecutionMEMORIA sync.Mutex
 
 var mutexes = make(map[string]*sync.Mutex)
@@ -201,6 +218,44 @@ var pidXRecursoMap = make(map[int][]string)
 var procesoEXEC Proceso // este proceso es el que se esta ejecutando
 //----------------------------------------------------------------------
 
+// ----------DESPACHO A CPU----------------
+// La CPU confirma el PCB apenas lo recibe y avisa el desalojo despues por /syscall con el id del despacho.
+// Un desalojo de un despacho que ya no esta en curso (vencido o repetido) se descarta. Con dispatch_timeout
+// se espera a la CPU mientras no confirma el despacho y despues de cada interrupcion, no mientras ejecuta.
+// Cuando vence se le pregunta al core: si todavia tiene el despacho (por ejemplo con CLI o pausado en el
+// depurador) se lo sigue esperando, el proceso solo se da por perdido si la CPU no responde o no lo tiene.
+// El kernel planifica un solo proceso en EXEC: despacha e interrumpe por las rutas sin id (/receivePCB,
+// /interrupt), que son las del core 0. Los otros cores de una CPU con cores > 1 no los usa este kernel
+type despacho struct {
+	id           int
+	pcb          PCB
+	core         int         // Core que confirmo el despacho
+	timer        *time.Timer // Corriendo mientras se espera a la CPU, nil sin dispatch_timeout
+	liberaciones int         // liberacionesMemoria al despachar
+}
+
+// Respuesta de /receivePCB
+type BodyAckDispatch struct {
+	Core     int `json:"core"`
+	Dispatch int `json:"dispatch"`
+}
+
+// Lo que responde GET /core/{id}/interrupts, solo lo que se usa para decidir si el despacho vencio
+type BodyEstadoCore struct {
+	Despacho    int  `json:"dispatch"`
+	Enmascarado bool `json:"masked"`
+	Depurando   bool `json:"debugging"`
+}
+
+// Cuanto se espera para volver a despachar un proceso que la CPU no tomo
+const esperaReintentoDespacho = 100 * time.Millisecond
+
+var nextDispatch = 1
+var despachoEnCurso *despacho
+var mutexDespacho sync.Mutex
+
+//----------------------------------------------------------------------
+
 // ---------FilaeNmae global-----------------------
 var fileName string
 var fsInstruction string
@@ -250,11 +305,6 @@ func waitIfPaused() {
 }
 
 func ProcessSyscall(w http.ResponseWriter, r *http.Request) {
-
-	if globals.ClientConfig.AlgoritmoPlanificacion != "FIFO" {
-		//log.Println("Se cierra el canal DONE ", globals.ClientConfig.AlgoritmoPlanificacion)
-		close(done)
-	}
 	var CPURequest KernelRequest
 
 	err := json.NewDecoder(r.Body).Decode(&CPURequest)
@@ -264,13 +314,19 @@ func ProcessSyscall(w http.ResponseWriter, r *http.Request) {
 	}
 	//log.Printf("Recibido Motivo de desalojo: %+v", CPURequest.MotivoDesalojo)
 
+	d, ok := terminarDespacho(CPURequest.Dispatch)
+	if !ok {
+		log.Printf("PID: %d - Desalojo descartado: el despacho %d no está en curso", CPURequest.PcbUpdated.Pid, CPURequest.Dispatch)
+		http.Error(w, "el despacho no está en curso", http.StatusConflict)
+		return
+	}
+
+	//log.Println("Se cierra el canal DONE ", globals.ClientConfig.AlgoritmoPlanificacion)
+	cerrarQuantum()
+
 	waitIfPaused()
 
-	if len(colaExecution) > 0 { // aca lo saco de la cola exec
-		mutexExecution.Lock()
-		colaExecution = append(colaExecution[:0], colaExecution[1:]...)
-		mutexExecution.Unlock()
-	} else {
+	if !sacarDeExecution() {
 		return
 	}
 
@@ -292,6 +348,12 @@ func ProcessSyscall(w http.ResponseWriter, r *http.Request) {
 	case "WAIT":
 		go waitHandler(procesoEXEC.PCB, CPURequest.Recurso)
 
+	case "PAGE_FAULT":
+		go handlePageFault(procesoEXEC.PCB, CPURequest.Pagina)
+
+	case "ESPERA_MEMORIA":
+		esperarMemoria(procesoEXEC.PCB, d.liberaciones)
+
 	case "INTERRUPTED_BY_USER":
 		//log.Printf("Finaliza el proceso %v - Motivo: INTERRUPTED_BY_USER", CPURequest.PcbUpdated.Pid)
 		enqueueExitProcess(procesoEXEC.PCB)
@@ -300,6 +362,26 @@ func ProcessSyscall(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Finaliza el proceso %v - Motivo: INVALID_RESOURCE", CPURequest.PcbUpdated.Pid)
 		enqueueExitProcess(procesoEXEC.PCB)
 
+	case "SEGMENTATION_FAULT":
+		log.Printf("Finaliza el proceso %v - Motivo: SEGMENTATION_FAULT", CPURequest.PcbUpdated.Pid)
+		enqueueExitProcess(procesoEXEC.PCB)
+
+	case "STACK_OVERFLOW":
+		log.Printf("Finaliza el proceso %v - Motivo: STACK_OVERFLOW", CPURequest.PcbUpdated.Pid)
+		enqueueExitProcess(procesoEXEC.PCB)
+
+	case "DIVISION_BY_ZERO":
+		log.Printf("Finaliza el proceso %v - Motivo: DIVISION_BY_ZERO", CPURequest.PcbUpdated.Pid)
+		enqueueExitProcess(procesoEXEC.PCB)
+
+	case "INVALID_INSTRUCTION":
+		log.Printf("Finaliza el proceso %v - Motivo: INVALID_INSTRUCTION", CPURequest.PcbUpdated.Pid)
+		enqueueExitProcess(procesoEXEC.PCB)
+
+	case "OUT_OF_MEMORY":
+		log.Printf("Finaliza el proceso %v - Motivo: OUT_OF_MEMORY", CPURequest.PcbUpdated.Pid)
+		enqueueExitProcess(procesoEXEC.PCB)
+
 	default:
 		log.Printf("PID: %v desalojado desconocido por %v", CPURequest.PcbUpdated.Pid, CPURequest.MotivoDesalojo)
 	}
@@ -349,7 +431,13 @@ func IniciarProceso(w http.ResponseWriter, r *http.Request) {
 	// Create PCB
 	pcb := createPCB()
 	createStructuresMemory(pcb.Pid, 0)
-	IniciarPlanificacionDeProcesos(request, pcb)
+	if err := IniciarPlanificacionDeProcesos(request, pcb); err != nil {
+		// Memoria rechazo el programa, el proceso no llega a NEW
+		log.Printf("PID: %d - Programa rechazado: %s", pcb.Pid, request.Path)
+		deletePagesmemory(pcb.Pid)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	quantumMapGlobal[pcb.Pid] = 0
 	newChannel <- pcb
 	// Response with the PID
@@ -358,8 +446,9 @@ func IniciarProceso(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(fmt.Sprintf(`{"pid":%d}`, pcb.Pid)))
 }
 
-func init() {
-	globals.ClientConfig = IniciarConfiguracion(os.Args[1]) // tiene que prender la confi cuando arranca
+// Arma las colas y arranca el planificador con la config ya cargada. Lo llama main, asi los tests del
+// paquete no necesitan una config en os.Args
+func IniciarKernel() {
 	readyChannel = make(chan PCB, globals.ClientConfig.Multiprogramacion)
 	newChannel = make(chan PCB, globals.ClientConfig.Multiprogramacion)
 	multiProgramacion = make(chan int, globals.ClientConfig.Multiprogramacion)
@@ -405,21 +494,25 @@ func handelMultiProg() {
 	}
 }
 
-func IniciarPlanificacionDeProcesos(request BodyRequest, pcb PCB) {
+func IniciarPlanificacionDeProcesos(request BodyRequest, pcb PCB) error {
 	proceso := Proceso{
 		Request: request,
 		PCB:     pcb,
 	}
-	mutexNew.Lock()
-	colaNew = append(colaNew, proceso.PCB)
-	mutexNew.Unlock()
 
+	// Primero se carga el programa, si memoria lo rechaza el proceso no entra a NEW
 	mutexExecutionMEMORIA.Lock()
-	if err := SendPathToMemory(proceso.Request, proceso.PCB.Pid); err != nil {
+	err := SendPathToMemory(proceso.Request, proceso.PCB.Pid)
+	mutexExecutionMEMORIA.Unlock()
+	if err != nil {
 		log.Printf("Error sending path to memory: %v", err)
-		return
+		return err
 	}
-	mutexExecutionMEMORIA.Unlock()
+
+	mutexNew.Lock()
+	colaNew = append(colaNew, proceso.PCB)
+	mutexNew.Unlock()
+	return nil
 }
 
 func executeTask(pcb PCB) {
@@ -613,6 +706,98 @@ func handleSyscallIO(pcb PCB, timeIo int, ioInterface string, ioType string) {
 
 }
 
+// Memoria respondio al page fault pero no pudo cargar la pagina (sin marcos ni victima, error de swap).
+// Reintentar daria el mismo resultado, el proceso se finaliza
+var errCargaPagina = errors.New("memoria no pudo cargar la pagina")
+
+func handlePageFault(pcb PCB, pagina int) {
+	enqueueBlockedProcess(pcb, "PAGE_FAULT")
+
+	err := solicitarCargaPagina(pcb.Pid, pagina)
+	if err != nil {
+		log.Printf("Error al cargar la pagina %d del PID %d: %v", pagina, pcb.Pid, err)
+	}
+
+	waitIfPaused()
+
+	if !sacarDeBloqueados("PAGE_FAULT", pcb.Pid) { // Si no esta es porque lo finalizaron mientras esperaba
+		return
+	}
+	if errors.Is(err, errCargaPagina) {
+		log.Printf("Finaliza el proceso %v - Motivo: OUT_OF_MEMORY", pcb.Pid)
+		enqueueExitProcess(pcb)
+		return
+	}
+	enqueueReadyProcess(pcb) // Sin error, o memoria no respondio: el proceso vuelve a ejecutar la instruccion
+}
+
+// Memoria avisa que libero marcos, los procesos bloqueados por memoria vuelven a READY a reintentar el RESIZE
+func MemoriaLiberada(w http.ResponseWriter, r *http.Request) {
+	mutexBlocked.Lock()
+	liberacionesMemoria++
+	esperando := colaBlocked["MEMORIA"]
+	colaBlocked["MEMORIA"] = nil
+	mutexBlocked.Unlock()
+
+	for _, pcb := range esperando {
+		go enqueueReadyProcess(pcb)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Bloquea el proceso hasta que memoria libere marcos. Si memoria libero despues de despacharlo, el aviso
+// pudo llegar antes que el desalojo y no va a haber otro: vuelve a READY a reintentar
+func esperarMemoria(pcb PCB, liberacionesVistas int) {
+	mutexBlocked.Lock()
+	if liberacionesMemoria != liberacionesVistas {
+		mutexBlocked.Unlock()
+		log.Printf("PID: %d - Memoria libero marcos mientras ejecutaba, reintenta", pcb.Pid)
+		go enqueueReadyProcess(pcb)
+		return
+	}
+	log.Printf("PID: %d - Estado Anterior: %s - Estado Actual: BLOCKED", pcb.Pid, pcb.State)
+	pcb.State = "BLOCKED"
+	colaBlocked["MEMORIA"] = append(colaBlocked["MEMORIA"], pcb)
+	mutexBlocked.Unlock()
+	log.Printf("PID: %d - Bloqueado por: MEMORIA", pcb.Pid)
+}
+
+func solicitarCargaPagina(pid int, pagina int) error {
+	memoriaURL := fmt.Sprintf("http://%s:%d/pageFault", globals.ClientConfig.IpMemoria, globals.ClientConfig.PuertoMemoria)
+	body := struct {
+		Pid  int `json:"pid"`
+		Page int `json:"page"`
+	}{Pid: pid, Page: pagina}
+
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error al serializar los datos JSON: %v", err)
+	}
+
+	resp, err := http.Post(memoriaURL, "application/json", bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		return fmt.Errorf("error al enviar la solicitud al módulo de memoria: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %v", errCargaPagina, resp.StatusCode)
+	}
+	return nil
+}
+
+func sacarDeBloqueados(key string, pid int) bool {
+	mutexBlocked.Lock()
+	defer mutexBlocked.Unlock()
+	for i, proceso := range colaBlocked[key] {
+		if proceso.Pid == pid {
+			colaBlocked[key] = append(colaBlocked[key][:i], colaBlocked[key][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 func InterfazExiste(nombre string, ioType string) bool {
 	for _, interfaz := range interfaces {
 		if interfaz.Name == nombre && interfaz.Type == ioType {
@@ -797,6 +982,11 @@ func SendPathToMemory(request BodyRequest, pid int) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusBadRequest {
+		// Errores del ensamblador, uno por linea
+		mensaje, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s", bytes.TrimSpace(mensaje))
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("error en la respuesta del módulo de memoria: %v", resp.StatusCode)
 	}
@@ -808,28 +998,211 @@ func SendPathToMemory(request BodyRequest, pid int) error {
 func SendContextToCPU(pcb PCB) error {
 	cpuURL := fmt.Sprintf("http://%s:%d/receivePCB", globals.ClientConfig.IpCPU, globals.ClientConfig.PuertoCPU)
 
-	context := pcb
+	context := BodyDispatch{PCB: pcb, Dispatch: nuevoDespacho(pcb)}
 	pcbResponseTest, err := json.Marshal(context)
 	if err != nil {
+		despachoRechazado(context.Dispatch)
 		return fmt.Errorf("error al serializar el PCB: %v", err)
 	}
 
 	//log.Println("Enviando solicitud con contenido:", string(pcbResponseTest))
 
-	resp, err := http.Post(cpuURL, "application/json", bytes.NewBuffer(pcbResponseTest))
+	cliente := http.Client{Timeout: time.Duration(globals.ClientConfig.DispatchTimeout) * time.Millisecond}
+	resp, err := cliente.Post(cpuURL, "application/json", bytes.NewBuffer(pcbResponseTest))
 	if err != nil {
+		var errRed net.Error
+		if !errors.As(err, &errRed) || !errRed.Timeout() {
+			despachoRechazado(context.Dispatch) // El PCB no llego a la CPU
+		}
+		// Si no respondio a tiempo el PCB puede haber llegado: el despacho queda en curso y al vencer se consulta al core
 		return fmt.Errorf("error al enviar la solicitud al módulo de cpu: %v", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		// 409: el core todavia tiene otro proceso (por ejemplo uno que se dio por perdido), se reintenta despues
+		despachoRechazado(context.Dispatch)
 		return fmt.Errorf("error en la respuesta del módulo de cpu: %v", resp.StatusCode)
 	}
+	var ack BodyAckDispatch
+	json.NewDecoder(resp.Body).Decode(&ack)
+	despachoConfirmado(context.Dispatch, ack.Core)
 
 	//log.Println("Respuesta del módulo de cpu recibida correctamente.")
 	return nil
 }
 
+func nuevoDespacho(pcb PCB) int {
+	mutexDespacho.Lock()
+	defer mutexDespacho.Unlock()
+	d := &despacho{id: nextDispatch, pcb: pcb}
+	nextDispatch++
+	mutexBlocked.Lock()
+	d.liberaciones = liberacionesMemoria
+	mutexBlocked.Unlock()
+	d.esperarCPU()
+	despachoEnCurso = d
+	return d.id
+}
+
+// Arranca (o reinicia) la espera de la respuesta de la CPU. Hay que tener mutexDespacho
+func (d *despacho) esperarCPU() {
+	if globals.ClientConfig.DispatchTimeout <= 0 {
+		return
+	}
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	id := d.id
+	d.timer = time.AfterFunc(time.Duration(globals.ClientConfig.DispatchTimeout)*time.Millisecond, func() { despachoVencido(id) })
+}
+
+// La CPU tomo el proceso: mientras ejecuta no hay timeout
+func despachoConfirmado(id int, core int) {
+	mutexDespacho.Lock()
+	defer mutexDespacho.Unlock()
+	if despachoEnCurso != nil && despachoEnCurso.id == id {
+		despachoEnCurso.core = core
+		if despachoEnCurso.timer != nil {
+			despachoEnCurso.timer.Stop()
+		}
+	}
+}
+
+// Se le mando una interrupcion al proceso: la CPU tiene que desalojarlo dentro del timeout
+func esperarDesalojo(pid int) {
+	mutexDespacho.Lock()
+	defer mutexDespacho.Unlock()
+	if despachoEnCurso != nil && despachoEnCurso.pcb.Pid == pid {
+		despachoEnCurso.esperarCPU()
+	}
+}
+
+// Si el id es el del despacho en curso lo termina (frena el timeout) y lo devuelve
+func terminarDespacho(id int) (*despacho, bool) {
+	mutexDespacho.Lock()
+	defer mutexDespacho.Unlock()
+	if despachoEnCurso == nil || despachoEnCurso.id != id {
+		return nil, false
+	}
+	d := despachoEnCurso
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	despachoEnCurso = nil
+	return d, true
+}
+
+// Le pregunta al core en que despacho esta. Error si la CPU no responde
+func consultarCore(core int) (BodyEstadoCore, error) {
+	var estado BodyEstadoCore
+	cpuURL := fmt.Sprintf("http://%s:%d/core/%d/interrupts", globals.ClientConfig.IpCPU, globals.ClientConfig.PuertoCPU, core)
+	cliente := http.Client{Timeout: time.Duration(globals.ClientConfig.DispatchTimeout) * time.Millisecond}
+	resp, err := cliente.Get(cpuURL)
+	if err != nil {
+		return estado, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return estado, fmt.Errorf("error en la respuesta del módulo de cpu: %v", resp.StatusCode)
+	}
+	err = json.NewDecoder(resp.Body).Decode(&estado)
+	return estado, err
+}
+
+// Vencio la espera: si el core sigue con el despacho se lo vuelve a esperar (con CLI o en el depurador el
+// proceso no atiende la interrupcion hasta que siga). Si la CPU no responde o ya no tiene el despacho no se
+// sabe en que quedaron los registros, asi que el proceso no puede volver a READY: se finaliza, se le pide
+// a la CPU que lo saque y se libera la CPU para el siguiente
+func despachoVencido(id int) {
+	mutexDespacho.Lock()
+	if despachoEnCurso == nil || despachoEnCurso.id != id {
+		mutexDespacho.Unlock()
+		return // El desalojo llego justo antes
+	}
+	pid, core := despachoEnCurso.pcb.Pid, despachoEnCurso.core
+	mutexDespacho.Unlock()
+
+	estado, err := consultarCore(core)
+	if err == nil && estado.Despacho == id {
+		switch {
+		case estado.Depurando:
+			log.Printf("PID: %d - Despacho %d en pausa: el core %d está detenido en el depurador", pid, id, core)
+		case estado.Enmascarado:
+			log.Printf("PID: %d - Despacho %d en pausa: el core %d tiene las interrupciones enmascaradas", pid, id, core)
+		default:
+			log.Printf("PID: %d - El core %d sigue ejecutando el despacho %d", pid, core, id)
+		}
+		mutexDespacho.Lock()
+		if despachoEnCurso != nil && despachoEnCurso.id == id {
+			despachoEnCurso.esperarCPU()
+		}
+		mutexDespacho.Unlock()
+		return
+	}
+
+	d, ok := terminarDespacho(id)
+	if !ok {
+		return
+	}
+	if err != nil {
+		log.Printf("PID: %d - La CPU no respondió el despacho %d en %d ms: %v", d.pcb.Pid, id, globals.ClientConfig.DispatchTimeout, err)
+	} else {
+		log.Printf("PID: %d - El core %d no tiene el despacho %d", d.pcb.Pid, core, id)
+	}
+
+	cerrarQuantum()
+
+	waitIfPaused()
+
+	if sacarDeExecution() {
+		go SendInterrupt(d.pcb.Pid, "DISPATCH_TIMEOUT")
+		log.Printf("Finaliza el proceso %v - Motivo: DISPATCH_TIMEOUT", d.pcb.Pid)
+		enqueueExitProcess(d.pcb)
+	}
+
+	mutexExecutionCPU.Unlock()
+}
+
+// La CPU no tomo el proceso (sin conexion o con el core ocupado): los registros son los que se mandaron,
+// asi que vuelve a READY. La CPU se libera despues de una espera para no reintentar enseguida
+func despachoRechazado(id int) {
+	d, ok := terminarDespacho(id)
+	if !ok {
+		return
+	}
+	cerrarQuantum()
+	if sacarDeExecution() {
+		log.Printf("PID: %d - La CPU no tomó el despacho %d", d.pcb.Pid, id)
+		go enqueueReadyProcess(d.pcb)
+	}
+	time.Sleep(esperaReintentoDespacho)
+	mutexExecutionCPU.Unlock()
+}
+
+// Le avisa a startQuantum que el proceso dejo la CPU. Si la CPU tardo, startQuantum puede no haber creado
+// el canal del proceso siguiente todavia y el anterior ya esta cerrado
+func cerrarQuantum() {
+	if globals.ClientConfig.AlgoritmoPlanificacion == "FIFO" || done == nil {
+		return
+	}
+	select {
+	case <-done:
+	default:
+		close(done)
+	}
+}
+
+func sacarDeExecution() bool { // aca lo saco de la cola exec
+	if len(colaExecution) == 0 {
+		return false
+	}
+	mutexExecution.Lock()
+	colaExecution = append(colaExecution[:0], colaExecution[1:]...)
+	mutexExecution.Unlock()
+	return true
+}
+
 func RecievePortOfInterfaceFromIO(w http.ResponseWriter, r *http.Request) {
 	var requestPort BodyRequestPort
 	var interfaz interfaz
@@ -1077,7 +1450,9 @@ func SendInterrupt(pid int, motivo string) error {
 		return err
 	}
 	//log.Printf("Mandando interrupción a la CPU PID: %d", pid)
-	resp, err := http.Post(cpuURL, "application/json", bytes.NewBuffer(hayQuantumBytes))
+	esperarDesalojo(pid)
+	cliente := http.Client{Timeout: time.Duration(globals.ClientConfig.DispatchTimeout) * time.Millisecond}
+	resp, err := cliente.Post(cpuURL, "application/json", bytes.NewBuffer(hayQuantumBytes))
 	if err != nil {
 		log.Printf("Error al enviar la solicitud al módulo de cpu: %v", err)
 		return err
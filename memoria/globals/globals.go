@@ -1,16 +1,37 @@
 package globals
 
 type Config struct {
-	Puerto           int    `json:"port"`
-	MemorySize       int    `json:"memory_size"`
-	IpCPU            string `json:"ip_cpu"`
-	IpKernel         string `json:"ip_kernel"`
-	IpEntradaSalida  string `json:"ip_entradasalida"`
-	PuertoCPU        int    `json:"port_cpu"`
-	PuertoKernel     int    `json:"port_kernel"`
-	PageSize         int    `json:"page_size"`
-	InstructionsPath string `json:"instructions_path"`
-	DelayResponse    int    `json:"delay_response"`
+	Puerto              int    `json:"port"`
+	MemorySize          int    `json:"memory_size"`
+	IpCPU               string `json:"ip_cpu"`
+	IpKernel            string `json:"ip_kernel"`
+	IpEntradaSalida     string `json:"ip_entradasalida"`
+	PuertoCPU           int    `json:"port_cpu"`
+	PuertoKernel        int    `json:"port_kernel"`
+	PageSize            int    `json:"page_size"`
+	InstructionsPath    string `json:"instructions_path"`
+	DelayResponse       int    `json:"delay_response"`
+	SwapPath            string `json:"swap_path"`             // Si esta vacio no hay memoria virtual: los marcos se asignan al hacer RESIZE
+	SwapSize            int    `json:"swap_size"`             // Tamaño del archivo de swap en bytes
+	SwapDelay           int    `json:"swap_delay"`            // Retardo de cada acceso a swap en milisegundos
+	Algoritmo           string `json:"replacement_algorithm"` // FIFO, LRU, CLOCK o CLOCK_M, necesita swap_path
+	Alcance             string `json:"replacement_scope"`     // GLOBAL o LOCAL, necesita swap_path
+	NivelesTablas       int    `json:"page_table_levels"`     // 1 (o 0) es la tabla plana, 2 o 3 para tablas multinivel
+	EntradasPorTabla    int    `json:"entries_per_table"`
+	RetardoTabla        int    `json:"table_access_delay"` // Retardo de cada acceso a una tabla de nivel en milisegundos
+	ModoMemoria         string `json:"memory_mode"`        // PAGINACION (por defecto), SEGMENTACION o SEGMENTACION_PAGINADA
+	TamMaxSegmento      int    `json:"max_segment_size"`   // Define el corte numero de segmento | offset de la direccion logica
+	TamPila             int    `json:"stack_size"`         // Tamaño del segmento de pila (con paginacion, paginas propias entre el codigo y los datos; sin configurar la pila son los ultimos bytes del proceso)
+	AlgoritmoSegmentos  string `json:"segment_algorithm"`  // FIRST, BEST o WORST
+	RetardoCompactacion int    `json:"compaction_delay"`
+	SnapshotPath        string `json:"snapshot_path"`         // Archivo por defecto de /memory/snapshot y /memory/restore
+	SnapshotDir         string `json:"snapshot_dir"`          // Directorio de los snapshots que se piden por nombre con ?path=
+	AsignacionFrames    string `json:"frame_allocation"`      // FIRST (por defecto), RANDOM, CONTIGUOUS o POOL
+	SemillaAsignacion   int64  `json:"frame_allocation_seed"` // Semilla de RANDOM, con la misma semilla se repiten las asignaciones
+	TamPool             int    `json:"frame_pool_size"`       // Cuota de marcos de cada proceso con POOL
+	MaxPaginasProceso   int    `json:"max_pages_per_process"` // 0 es sin limite
+	PoliticaSinMemoria  string `json:"out_of_memory_policy"`  // KILL (por defecto), BLOCK o FAIL
+	AlgoritmoHeap       string `json:"heap_algorithm"`        // FIRST (por defecto) o BEST, para MALLOC
 }
 
 var ClientConfig *Config
@@ -17,18 +17,39 @@ func main() {
 	if globals.ClientConfig == nil {
 		log.Fatalf("No se pudo cargar la configuración")
 	}
+	utils.IniciarMemoria()
 
 	puerto := globals.ClientConfig.Puerto
 
 	http.HandleFunc("POST /setInstructionFromFileToMap", utils.SetInstructionsFromFileToMap)
 
 	http.HandleFunc("GET /getInstructionFromPid", utils.GetInstruction)
+	http.HandleFunc("GET /getInstructionsFromPid", utils.GetInstructions)
 	http.HandleFunc("POST /createProcess", utils.CreateProcessHandler)
 	http.HandleFunc("POST /terminateProcess", utils.TerminateProcessHandler)
 	http.HandleFunc("POST /resizeProcess", utils.ResizeProcessHandler)
 	http.HandleFunc("POST /readMemory", utils.ReadMemoryHandler)
 	http.HandleFunc("POST /writeMemory", utils.WriteMemoryHandler)
 	http.HandleFunc("POST /getFramefromCPU", utils.GetPageFromCPU) //Recive la pagina desde "MMU" para devolver el frame
+	http.HandleFunc("POST /pageFault", utils.PageFaultHandler)
+	http.HandleFunc("POST /getSegmentFromCPU", utils.GetSegmentFromCPU)
+	http.HandleFunc("POST /protectPages", utils.ProtectPagesHandler)
+	http.HandleFunc("POST /protectSegment", utils.ProtectSegmentHandler)
+	http.HandleFunc("POST /cloneProcess", utils.CloneProcessHandler)
+	http.HandleFunc("POST /copyOnWrite", utils.CopyOnWriteHandler)
+
+	http.HandleFunc("GET /memory/frames", utils.FramesHandler)
+	http.HandleFunc("GET /memory/process/{pid}", utils.ProcessMemoryHandler)
+	http.HandleFunc("GET /memory/dump", utils.DumpHandler)
+	http.HandleFunc("POST /memory/snapshot", utils.SnapshotHandler)
+	http.HandleFunc("POST /memory/restore", utils.RestoreHandler)
+	http.HandleFunc("GET /memory/fragmentation", utils.FragmentationHandler)
+	http.HandleFunc("POST /malloc", utils.MallocHandler)
+	http.HandleFunc("POST /free", utils.FreeHandler)
+	http.HandleFunc("GET /memory/heap/{pid}", utils.HeapHandler)
+	http.HandleFunc("GET /stack", utils.StackHandler)
+	http.HandleFunc("GET /label", utils.LabelHandler)
+	http.HandleFunc("GET /program", utils.ProgramHandler)
 
 	http.ListenAndServe(":"+strconv.Itoa(puerto), nil)
 }
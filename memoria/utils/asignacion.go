@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"sort"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+type BloqueLibre struct {
+	Inicio   int `json:"inicio"`
+	Cantidad int `json:"cantidad"`
+}
+
+type UsoProceso struct {
+	Pid    int `json:"pid"`
+	Marcos int `json:"marcos"`
+	Cuota  int `json:"cuota,omitempty"` // Solo con la politica POOL
+	Pool   int `json:"pool,omitempty"`  // Marcos reservados para el proceso
+}
+
+type BodyFragmentacion struct {
+	Politica      string        `json:"politica"`
+	MarcosTotales int           `json:"marcos_totales"`
+	MarcosLibres  int           `json:"marcos_libres"`
+	MayorBloque   int           `json:"mayor_bloque_contiguo"`
+	BloquesLibres []BloqueLibre `json:"bloques_libres"`
+	Procesos      []UsoProceso  `json:"procesos"`
+}
+
+// Marcos reservados para cada proceso con la politica POOL
+var framePools = make(map[int][]int)
+
+// Ultimo marco que se le asigno a cada proceso, para la politica CONTIGUOUS
+var ultimoFrame = make(map[int]int)
+
+var generadorFrames *rand.Rand
+
+func politicaAsignacion() string {
+	if globals.ClientConfig.AsignacionFrames == "" {
+		return "FIRST"
+	}
+	return globals.ClientConfig.AsignacionFrames
+}
+
+func asignacionPorPool() bool {
+	return politicaAsignacion() == "POOL" && globals.ClientConfig.TamPool > 0
+}
+
+// Marcos libres que no estan reservados para el pool de otro proceso
+func framesLibres() []int {
+	reservados := make(map[int]bool)
+	for _, pool := range framePools {
+		for _, frame := range pool {
+			reservados[frame] = true
+		}
+	}
+	var libres []int
+	for frame, usos := range memoryMap {
+		if usos == 0 && !reservados[frame] {
+			libres = append(libres, frame)
+		}
+	}
+	return libres
+}
+
+// Agrupa marcos libres (ordenados) en bloques contiguos
+func bloquesLibres(libres []int) []BloqueLibre {
+	var bloques []BloqueLibre
+	for _, frame := range libres {
+		if n := len(bloques); n > 0 && bloques[n-1].Inicio+bloques[n-1].Cantidad == frame {
+			bloques[n-1].Cantidad++
+			continue
+		}
+		bloques = append(bloques, BloqueLibre{Inicio: frame, Cantidad: 1})
+	}
+	return bloques
+}
+
+func frameAleatorio() int {
+	libres := framesLibres()
+	if len(libres) == 0 {
+		return -1
+	}
+	if generadorFrames == nil {
+		generadorFrames = rand.New(rand.NewSource(globals.ClientConfig.SemillaAsignacion))
+	}
+	return libres[generadorFrames.Intn(len(libres))]
+}
+
+// Prefiere el marco que sigue al ultimo del proceso, si no el principio del bloque libre mas grande
+func frameContiguo(pid int) int {
+	libres := framesLibres()
+	if len(libres) == 0 {
+		return -1
+	}
+	if ultimo, exists := ultimoFrame[pid]; exists {
+		for _, frame := range libres {
+			if frame == ultimo+1 {
+				return frame
+			}
+		}
+	}
+	var mayor BloqueLibre
+	for _, bloque := range bloquesLibres(libres) {
+		if bloque.Cantidad > mayor.Cantidad {
+			mayor = bloque
+		}
+	}
+	return mayor.Inicio
+}
+
+// Reserva el pool del proceso la primera vez que pide un marco, buscando un bloque contiguo si hay
+func poolDelProceso(pid int) []int {
+	if pool, exists := framePools[pid]; exists {
+		return pool
+	}
+	cuota := globals.ClientConfig.TamPool
+	libres := framesLibres()
+	if len(libres) < cuota {
+		return nil
+	}
+	pool := libres[:cuota]
+	for _, bloque := range bloquesLibres(libres) {
+		if bloque.Cantidad >= cuota {
+			pool = nil
+			for frame := bloque.Inicio; frame < bloque.Inicio+cuota; frame++ {
+				pool = append(pool, frame)
+			}
+			break
+		}
+	}
+	framePools[pid] = append([]int{}, pool...)
+	return framePools[pid]
+}
+
+func frameDelPool(pid int) int {
+	for _, frame := range poolDelProceso(pid) {
+		if memoryMap[frame] == 0 {
+			return frame
+		}
+	}
+	return -1
+}
+
+// Marcos que el proceso todavia puede pedir
+func framesDisponibles(pid int) int {
+	if !asignacionPorPool() {
+		return counterMemoryFree()
+	}
+	var contador int
+	for _, frame := range poolDelProceso(pid) {
+		if memoryMap[frame] == 0 {
+			contador++
+		}
+	}
+	return contador
+}
+
+func liberarPool(pid int) {
+	delete(framePools, pid)
+	delete(ultimoFrame, pid)
+}
+
+// GET /memory/fragmentation
+func FragmentationHandler(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	var libres []int
+	for frame, usos := range memoryMap {
+		if usos == 0 {
+			libres = append(libres, frame)
+		}
+	}
+	respuesta := BodyFragmentacion{
+		Politica:      politicaAsignacion(),
+		MarcosTotales: len(memoryMap),
+		MarcosLibres:  len(libres),
+		BloquesLibres: bloquesLibres(libres),
+	}
+	for _, bloque := range respuesta.BloquesLibres {
+		respuesta.MayorBloque = max(respuesta.MayorBloque, bloque.Cantidad)
+	}
+
+	uso := make(map[int]int)
+	for _, pid := range pidsPaginados() {
+		uso[pid] = 0
+	}
+	for pid := range segmentTable {
+		uso[pid] = 0
+	}
+	for frame, info := range frameTable {
+		if info.Pid == -1 {
+			continue
+		}
+		uso[info.Pid]++
+		if frameCompartido(frame) {
+			for _, pid := range pidsPaginados() {
+				if pid != info.Pid && paginaDelFrame(pid, frame) != -1 {
+					uso[pid]++
+				}
+			}
+		}
+	}
+	for pid, marcos := range uso {
+		proceso := UsoProceso{Pid: pid, Marcos: marcos, Pool: len(framePools[pid])}
+		if asignacionPorPool() {
+			proceso.Cuota = globals.ClientConfig.TamPool
+		}
+		respuesta.Procesos = append(respuesta.Procesos, proceso)
+	}
+	mu.Unlock()
+	sort.Slice(respuesta.Procesos, func(i, j int) bool { return respuesta.Procesos[i].Pid < respuesta.Procesos[j].Pid })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(respuesta)
+}
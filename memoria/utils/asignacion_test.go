@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// Ocupa los marcos como si fueran del PID 99
+func ocuparMarcos(marcos ...int) {
+	for _, frame := range marcos {
+		asignarFrame(frame, 99, frame)
+	}
+}
+
+func TestProximoLugarLibre(t *testing.T) {
+	casos := []struct {
+		nombre   string
+		politica string
+		ocupados []int
+		ultimo   int // Ultimo marco del proceso, -1 si no tiene
+		frame    int
+	}{
+		{"FIRST", "FIRST", []int{0, 1, 3}, -1, 2},
+		{"FIRST por defecto", "", []int{0}, -1, 1},
+		{"sin marcos libres", "FIRST", []int{0, 1, 2, 3, 4, 5, 6, 7}, -1, -1},
+		{"CONTIGUOUS sigue al ultimo", "CONTIGUOUS", []int{0, 2}, 5, 6},
+		{"CONTIGUOUS con el siguiente ocupado", "CONTIGUOUS", []int{0, 2, 6}, 5, 3},
+		{"CONTIGUOUS sin marcos", "CONTIGUOUS", []int{1, 4}, -1, 5},
+		{"POOL sin cuota es FIRST", "POOL", []int{0}, -1, 1},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			configurarMemoria(t, globals.Config{AsignacionFrames: caso.politica}, 8)
+			ocuparMarcos(caso.ocupados...)
+			if caso.ultimo != -1 {
+				ultimoFrame[1] = caso.ultimo
+			}
+			if frame := proximoLugarLibre(1); frame != caso.frame {
+				t.Errorf("frame = %d, se esperaba %d", frame, caso.frame)
+			}
+			if caso.frame != -1 && ultimoFrame[1] != caso.frame {
+				t.Errorf("ultimo frame = %d, se esperaba %d", ultimoFrame[1], caso.frame)
+			}
+		})
+	}
+}
+
+func TestAsignacionRandom(t *testing.T) {
+	asignar := func() []int {
+		configurarMemoria(t, globals.Config{AsignacionFrames: "RANDOM", SemillaAsignacion: 7}, 8)
+		ocuparMarcos(0, 4)
+		var frames []int
+		for pagina := 0; pagina < 6; pagina++ {
+			frame := proximoLugarLibre(1)
+			if frame == -1 || memoryMap[frame] != 0 {
+				t.Fatalf("se eligio el marco %d que no esta libre", frame)
+			}
+			asignarFrame(frame, 1, pagina)
+			frames = append(frames, frame)
+		}
+		if frame := proximoLugarLibre(1); frame != -1 {
+			t.Errorf("con la memoria llena se eligio el marco %d", frame)
+		}
+		return frames
+	}
+	primera, segunda := asignar(), asignar()
+	if !reflect.DeepEqual(primera, segunda) {
+		t.Errorf("con la misma semilla se asignaron %v y %v", primera, segunda)
+	}
+}
+
+func TestAsignacionPorPool(t *testing.T) {
+	configurarMemoria(t, globals.Config{AsignacionFrames: "POOL", TamPool: 3}, 10)
+	ocuparMarcos(1, 5)
+
+	// El pool del PID 1 tiene que ser el primer bloque contiguo donde entra la cuota
+	if frame := proximoLugarLibre(1); frame != 2 {
+		t.Errorf("primer marco del PID 1 = %d, se esperaba 2", frame)
+	}
+	if pool := framePools[1]; !reflect.DeepEqual(pool, []int{2, 3, 4}) {
+		t.Errorf("pool del PID 1 = %v, se esperaba [2 3 4]", pool)
+	}
+	asignarFrame(2, 1, 0)
+	if disponibles := framesDisponibles(1); disponibles != 2 {
+		t.Errorf("disponibles del PID 1 = %d, se esperaban 2", disponibles)
+	}
+
+	// Los marcos del pool del PID 1 no son libres para el PID 2
+	if frame := proximoLugarLibre(2); frame != 6 {
+		t.Errorf("primer marco del PID 2 = %d, se esperaba 6", frame)
+	}
+	if libres := framesLibres(); slices.ContainsFunc(libres, func(frame int) bool { return frame >= 2 && frame <= 8 }) {
+		t.Errorf("libres = %v, no tendrian que estar los pools", libres)
+	}
+
+	// Sin lugar para la cuota entera no hay pool
+	if frame := proximoLugarLibre(3); frame != -1 {
+		t.Errorf("el PID 3 recibio el marco %d sin lugar para su pool", frame)
+	}
+	if framesDisponibles(3) != 0 {
+		t.Errorf("el PID 3 no tendria que tener marcos disponibles")
+	}
+
+	liberarPool(1)
+	if libres := framesLibres(); !reflect.DeepEqual(libres, []int{0, 3, 4, 9}) {
+		t.Errorf("libres despues de liberar el pool = %v, se esperaba [0 3 4 9]", libres)
+	}
+}
+
+func TestBloquesLibres(t *testing.T) {
+	casos := []struct {
+		libres  []int
+		bloques []BloqueLibre
+	}{
+		{nil, nil},
+		{[]int{3}, []BloqueLibre{{3, 1}}},
+		{[]int{0, 1, 2, 5, 7, 8}, []BloqueLibre{{0, 3}, {5, 1}, {7, 2}}},
+	}
+	for _, caso := range casos {
+		if bloques := bloquesLibres(caso.libres); !reflect.DeepEqual(bloques, caso.bloques) {
+			t.Errorf("bloquesLibres(%v) = %v, se esperaba %v", caso.libres, bloques, caso.bloques)
+		}
+	}
+}
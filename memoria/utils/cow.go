@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+type BodyClone struct {
+	Pid    int `json:"pid"`
+	NewPid int `json:"new_pid"`
+}
+
+func CloneProcessHandler(w http.ResponseWriter, r *http.Request) {
+	var body BodyClone
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ClonarProceso(body.Pid, body.NewPid); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// POST /copyOnWrite: la CPU quiso escribir en una pagina que memoria le informo sin W. Si la pagina se
+// puede escribir y su marco esta compartido se le da una copia propia, si no es un SEGMENTATION_FAULT
+func CopyOnWriteHandler(w http.ResponseWriter, r *http.Request) {
+	var body bodyCPUpage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := ResolverCopyOnWrite(body.Pid, body.Page)
+	switch {
+	case errors.Is(err, errSinMarcos):
+		http.Error(w, err.Error(), http.StatusInsufficientStorage)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// Crea el proceso nuevo compartiendo los marcos del padre. Los marcos compartidos se informan a la CPU
+// sin W hasta que alguno de los dos escribe, ahi la CPU pide la copia del marco (copy-on-write)
+func ClonarProceso(pid int, newPid int) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if modoSegmentacion() {
+		return fmt.Errorf("la clonacion de procesos solo esta disponible con paginacion")
+	}
+	for { // Una pagina que se esta llevando a swap no tiene su slot escrito todavia
+		if !tieneTablaPaginas(pid) {
+			return fmt.Errorf("Process with PID %d not found", pid)
+		}
+		espera := paginaEnTransito(pid)
+		if espera == nil {
+			break
+		}
+		mu.Unlock()
+		<-espera
+		mu.Lock()
+	}
+	paginas := paginasDelProceso(pid)
+	if tieneTablaPaginas(newPid) {
+		return fmt.Errorf("PID %d already has pages assigned", newPid)
+	}
+	if memoriaVirtual && counterSwapFree() < len(paginas) {
+		return fmt.Errorf("no hay espacio en swap para clonar el PID %d", pid)
+	}
+
+	clon := make([]int, len(paginas))
+	copy(clon, paginas)
+
+	if memoriaVirtual { // Cada pagina del clon tiene su propio slot con el contenido actual de la pagina
+		slots := make([]int, len(paginas))
+		for pagina, frame := range clon {
+			slots[pagina] = reservarSlotSwap()
+			var err error
+			if frame == -1 {
+				err = copiarSlotSwap(swapTable[pid][pagina], slots[pagina])
+			} else { // Mientras el marco este compartido no cambia, asi el slot sirve si se desaloja
+				err = escribirEnSwap(slots[pagina], frame)
+			}
+			if err != nil {
+				for _, slot := range slots[:pagina+1] { // No queda nada del clon a medias
+					swapMap[slot] = false
+				}
+				return fmt.Errorf("error al copiar la pagina %d en swap: %v", pagina, err)
+			}
+		}
+		swapTable[newPid] = slots
+	}
+
+	// Recien con los slots copiados el clon pasa a usar los marcos
+	for _, frame := range clon {
+		if frame != -1 {
+			memoryMap[frame]++
+		}
+	}
+	cargarTablaPaginas(newPid, clon)
+	pageProtection[newPid] = append([]string{}, pageProtection[pid]...)
+	mapInstructions[newPid] = mapInstructions[pid]
+	etiquetas[newPid] = etiquetas[pid]
+	if tam, exists := programasBinarios[pid]; exists {
+		programasBinarios[newPid] = tam
+	}
+
+	vaciarTLBProceso(pid) // El padre puede tener en la TLB paginas con W que ahora estan compartidas
+
+	log.Printf("PID: %d - Clonado de PID: %d - Paginas compartidas: %d", newPid, pid, len(clon))
+	return nil
+}
+
+// Canal de alguna pagina del proceso que se esta llevando o trayendo de swap, nil si no hay ninguna
+func paginaEnTransito(pid int) chan struct{} {
+	for pagina := range paginasDelProceso(pid) {
+		if espera, enTransito := paginasEnTransito[paginaProceso{pid, pagina}]; enTransito {
+			return espera
+		}
+	}
+	return nil
+}
+
+func copiarSlotSwap(origen int, destino int) error {
+	contenido := make([]byte, pageSize)
+	if _, err := swapFile.ReadAt(contenido, int64(origen*pageSize)); err != nil {
+		return err
+	}
+	_, err := swapFile.WriteAt(contenido, int64(destino*pageSize))
+	return err
+}
+
+func frameCompartido(frame int) bool {
+	return memoryMap[frame] > 1
+}
+
+func frameDelProceso(pid int, frame int) bool {
+	if frameTable[frame].Pid == pid {
+		return true
+	}
+	if frameCompartido(frame) {
+		return paginaDelFrame(pid, frame) != -1
+	}
+	return false
+}
+
+// Pagina del proceso que apunta al marco, -1 si no lo usa
+func paginaDelFrame(pid int, frame int) int {
+	for pagina, f := range paginasDelProceso(pid) {
+		if f == frame {
+			return pagina
+		}
+	}
+	return -1
+}
+
+// Saca al proceso de los que usan el marco, el marco se libera cuando no lo usa nadie
+func soltarFrame(frame int, pid int) {
+	if !frameCompartido(frame) {
+		liberarFrame(frame)
+		return
+	}
+	memoryMap[frame]--
+	if frameTable[frame].Pid != pid {
+		return
+	}
+	for _, otro := range pidsPaginados() { // El marco pasa a ser de otro proceso que lo comparte
+		if otro == pid {
+			continue
+		}
+		if pagina := paginaDelFrame(otro, frame); pagina != -1 {
+			frameTable[frame].Pid = otro
+			frameTable[frame].Pagina = pagina
+			frameTable[frame].Modificado = true // El slot del nuevo dueño puede ser viejo (el padre la modifico antes de clonar)
+			return
+		}
+	}
+}
+
+// Le da al proceso una copia propia del marco compartido. Devuelve el marco nuevo
+func copiarFrameCompartido(pid int, frame int) (int, error) {
+	pagina := paginaDelFrame(pid, frame)
+	if pagina == -1 {
+		return -1, fmt.Errorf("PID %d no usa el marco %d", pid, frame)
+	}
+
+	nuevo := proximoLugarLibre(pid)
+	if nuevo == -1 && memoriaVirtual {
+		nuevo = elegirVictima(pid, frame)
+		if nuevo != -1 {
+			if err := desalojarFrame(nuevo); err != nil {
+				return -1, fmt.Errorf("error al escribir en swap: %v", err)
+			}
+		}
+	}
+	if nuevo == -1 {
+		return -1, fmt.Errorf("%w: no hay marcos libres para copiar el marco %d", errSinMarcos, frame)
+	}
+
+	invalidarCacheCPU(frame) // La copia tiene que tener lo que la CPU todavia no escribio
+	copy(memory[nuevo*pageSize:(nuevo+1)*pageSize], memory[frame*pageSize:(frame+1)*pageSize])
+	soltarFrame(frame, pid)
+	asignarMarcoPagina(pid, pagina, nuevo)
+	asignarFrame(nuevo, pid, pagina)
+	frameTable[nuevo].Modificado = true
+	log.Printf("PID: %d - Copy-on-write - Pagina: %d - Marco: %d -> %d", pid, pagina, frame, nuevo)
+	invalidarEntradaTLB(pid, pagina)
+	return nuevo, nil
+}
+
+func ResolverCopyOnWrite(pid int, pagina int) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !tieneTablaPaginas(pid) {
+		return fmt.Errorf("Process with PID %d not found", pid)
+	}
+	if pagina < 0 || pagina >= cantidadPaginas(pid) {
+		return fmt.Errorf("PID %d no tiene la pagina %d", pid, pagina)
+	}
+	if !tienePermiso(permisosPagina(pid, pagina), "W") {
+		return fmt.Errorf("PID %d - la pagina %d no tiene permiso de escritura", pid, pagina)
+	}
+
+	frame := marcoDePagina(pid, pagina)
+	if frame != -1 && frameCompartido(frame) {
+		_, err := copiarFrameCompartido(pid, frame)
+		return err
+	}
+	// El marco ya no esta compartido (el otro proceso lo copio o termino) o la pagina se desalojo:
+	// la CPU tenia los permisos viejos en la TLB
+	invalidarEntradaTLB(pid, pagina)
+	return nil
+}
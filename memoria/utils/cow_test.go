@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// PID 1 con la pagina 0 en el marco 0 ("aaaa") y la pagina 1 en el marco 1 ("bbbb")
+func padreDePrueba() {
+	crearTablaPaginas(1)
+	for pagina, contenido := range []string{"aaaa", "bbbb"} {
+		asignarFrame(pagina, 1, pagina)
+		agregarPagina(1, pagina)
+		copy(memory[pagina*pageSize:], contenido)
+		if memoriaVirtual {
+			swapTable[1] = append(swapTable[1], reservarSlotSwap())
+		}
+	}
+}
+
+func TestClonarProceso(t *testing.T) {
+	conSwap := globals.Config{SwapSize: 64}
+	casos := []struct {
+		nombre string
+		config globals.Config
+		antes  func() // Despues de crear el padre
+		falla  bool
+		mapa   []int // memoryMap despues de clonar
+		enSwap []string
+	}{
+		{"comparte los marcos", globals.Config{}, nil, false, []int{2, 2, 0, 0}, nil},
+		{"con swap copia cada pagina a su slot", conSwap, nil, false, []int{2, 2, 0, 0}, []string{"aaaa", "bbbb"}},
+		{"pagina en swap", conSwap, func() {
+			swapFile.WriteAt([]byte("BBBB"), int64(swapTable[1][1]*pageSize))
+			liberarFrame(1)
+			asignarMarcoPagina(1, 1, -1)
+		}, false, []int{2, 0, 0, 0}, []string{"aaaa", "BBBB"}},
+		{"el PID nuevo ya existe", globals.Config{}, func() { crearTablaPaginas(2) }, true, []int{1, 1, 0, 0}, nil},
+		{"sin lugar en swap", conSwap, func() { reservarSlotSwap(); reservarSlotSwap(); reservarSlotSwap() }, true, []int{1, 1, 0, 0}, nil},
+		{"falla la escritura en swap", conSwap, func() { swapFile.Close() }, true, []int{1, 1, 0, 0}, nil},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			cpu := configurarMemoria(t, caso.config, 4)
+			padreDePrueba()
+			if caso.antes != nil {
+				caso.antes()
+			}
+			slots := slices.Clone(swapMap)
+
+			err := ClonarProceso(1, 2)
+			if (err != nil) != caso.falla {
+				t.Fatalf("error = %v, se esperaba falla = %v", err, caso.falla)
+			}
+			if !slices.Equal(memoryMap, caso.mapa) {
+				t.Errorf("memoryMap = %v, se esperaba %v", memoryMap, caso.mapa)
+			}
+			if caso.falla {
+				if !slices.Equal(swapMap, slots) || len(swapTable[2]) != 0 {
+					t.Errorf("el clon fallido dejo slots tomados: %v, tabla %v", swapMap, swapTable[2])
+				}
+				return
+			}
+			if !slices.Equal(paginasDelProceso(2), paginasDelProceso(1)) {
+				t.Errorf("tabla del clon %v, la del padre es %v", paginasDelProceso(2), paginasDelProceso(1))
+			}
+			if permisos := permisosEfectivos(2, 0); permisos != "R" {
+				t.Errorf("permisos de la pagina compartida = %q, se esperaba %q", permisos, "R")
+			}
+			for pagina, contenido := range caso.enSwap {
+				if slot := leerSlot(swapTable[2][pagina]); slot != contenido || swapTable[2][pagina] == swapTable[1][pagina] {
+					t.Errorf("slot %d del clon = %q, se esperaba %q", swapTable[2][pagina], slot, contenido)
+				}
+			}
+			if !slices.Contains(cpu.recibidos(), "tlb?pid=1") {
+				t.Errorf("no se vacio la TLB del padre: %v", cpu.recibidos())
+			}
+		})
+	}
+}
+
+func TestResolverCopyOnWrite(t *testing.T) {
+	casos := []struct {
+		nombre string
+		marcos int
+		pid    int
+		antes  func()
+		err    error // nil si no falla, errSinMarcos si se espera ese error
+		falla  bool
+		marco  int // Marco de la pagina 0 del pid despues de resolver
+		dueño  int // Dueño del marco 0 despues de resolver
+	}{
+		{"el clon escribe", 4, 2, nil, nil, false, 2, 1},
+		{"el padre escribe", 4, 1, nil, nil, false, 2, 2},
+		{"ya no esta compartido", 4, 2, func() { ResolverCopyOnWrite(1, 0) }, nil, false, 0, 2},
+		{"sin permiso de escritura", 4, 2, func() { pageProtection[2] = []string{"R", "R"} }, nil, true, 0, 1},
+		{"sin marcos libres", 2, 2, nil, errSinMarcos, true, 0, 1},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			cpu := configurarMemoria(t, globals.Config{}, caso.marcos)
+			padreDePrueba()
+			if err := ClonarProceso(1, 2); err != nil {
+				t.Fatal(err)
+			}
+			if caso.antes != nil {
+				caso.antes()
+			}
+
+			err := ResolverCopyOnWrite(caso.pid, 0)
+			if (err != nil) != caso.falla || (caso.err != nil && !errors.Is(err, caso.err)) {
+				t.Fatalf("error = %v, se esperaba falla = %v (%v)", err, caso.falla, caso.err)
+			}
+			marco := marcoDePagina(caso.pid, 0)
+			if marco != caso.marco || frameTable[0].Pid != caso.dueño {
+				t.Errorf("marco = %d y dueño del marco 0 = %d, se esperaba %d y %d", marco, frameTable[0].Pid, caso.marco, caso.dueño)
+			}
+			if string(memory[marco*pageSize:marco*pageSize+4]) != "aaaa" {
+				t.Errorf("contenido del marco %d = %q", marco, memory[marco*pageSize:marco*pageSize+4])
+			}
+			if caso.falla {
+				return
+			}
+			if memoryMap[marco] != 1 || permisosEfectivos(caso.pid, 0) != "RW" {
+				t.Errorf("la pagina quedo compartida: memoryMap %v, permisos %q", memoryMap, permisosEfectivos(caso.pid, 0))
+			}
+			if !slices.Contains(cpu.recibidos(), "invalidateTLB?") {
+				t.Errorf("la CPU no recibio invalidateTLB: %v", cpu.recibidos())
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// Resultados de RESIZE
+const (
+	ResizeOK          = "OK"
+	ResizeOutOfMemory = "OUT_OF_MEMORY"
+)
+
+type BodyResize struct {
+	Resultado string `json:"result"`
+	Politica  string `json:"policy"` // KILL, BLOCK o FAIL
+}
+
+// Hay algun proceso bloqueado en el kernel esperando que se libere memoria
+var esperandoMemoria bool
+
+func politicaSinMemoria() string {
+	if globals.ClientConfig.PoliticaSinMemoria == "" {
+		return "KILL"
+	}
+	return globals.ClientConfig.PoliticaSinMemoria
+}
+
+func superaLimite(paginas int) bool {
+	return globals.ClientConfig.MaxPaginasProceso > 0 && paginas > globals.ClientConfig.MaxPaginasProceso
+}
+
+// Aplica la politica cuando no se puede hacer el RESIZE. Si el pedido no se va a poder cumplir
+// nunca (por ejemplo supera el limite por proceso) no tiene sentido bloquear y se finaliza el proceso
+func sinMemoria(pid int, motivo string, puedeEsperar bool) string {
+	politica := politicaSinMemoria()
+	if politica == "BLOCK" && !puedeEsperar {
+		politica = "KILL"
+	}
+	log.Printf("PID: %d - Out of Memory: %s - Politica: %s", pid, motivo, politica)
+
+	switch politica {
+	case "BLOCK":
+		esperandoMemoria = true
+	case "FAIL":
+	default:
+		FinalizarProceso(pid)
+	}
+	return ResizeOutOfMemory
+}
+
+// Avisa al kernel para que los procesos bloqueados por memoria reintenten el RESIZE
+func notificarMemoriaLiberada() {
+	if !esperandoMemoria {
+		return
+	}
+	esperandoMemoria = false
+	go func() {
+		kernelURL := fmt.Sprintf("http://%s:%d/memoryFreed", globals.ClientConfig.IpKernel, globals.ClientConfig.PuertoKernel)
+		resp, err := http.Post(kernelURL, "application/json", nil)
+		if err != nil {
+			log.Printf("Error al avisar al kernel que se libero memoria: %v", err)
+			return
+		}
+		defer resp.Body.Close()
+	}()
+}
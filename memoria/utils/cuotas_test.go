@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// Espera a que la CPU (o el kernel) de prueba reciba el pedido, los avisos de memoria liberada van en otra goroutine
+func esperarPedido(cpu *cpuDePrueba, pedido string) bool {
+	for intento := 0; intento < 100; intento++ {
+		if slices.Contains(cpu.recibidos(), pedido) {
+			return true
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return false
+}
+
+func TestPoliticaSinMemoria(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		config    globals.Config
+		paginas   int // Paginas que pide el RESIZE, el proceso tiene 1 y hay 2 marcos
+		resultado string
+		finaliza  bool
+		espera    bool
+	}{
+		{"entra", globals.Config{}, 2, ResizeOK, false, false},
+		{"KILL por defecto", globals.Config{}, 3, ResizeOutOfMemory, true, false},
+		{"KILL", globals.Config{PoliticaSinMemoria: "KILL"}, 3, ResizeOutOfMemory, true, false},
+		{"BLOCK", globals.Config{PoliticaSinMemoria: "BLOCK"}, 3, ResizeOutOfMemory, false, true},
+		{"FAIL", globals.Config{PoliticaSinMemoria: "FAIL"}, 3, ResizeOutOfMemory, false, false},
+		{"limite por proceso", globals.Config{MaxPaginasProceso: 1, PoliticaSinMemoria: "FAIL"}, 2, ResizeOutOfMemory, false, false},
+		{"BLOCK sobre el limite finaliza", globals.Config{MaxPaginasProceso: 1, PoliticaSinMemoria: "BLOCK"}, 2, ResizeOutOfMemory, true, false},
+		{"BLOCK sin lugar en swap", globals.Config{SwapSize: 32, PoliticaSinMemoria: "BLOCK"}, 3, ResizeOutOfMemory, false, true},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			kernel := configurarMemoria(t, caso.config, 2)
+			procesoDePrueba("hola")
+
+			resultado, err := redimensionarProceso(1, caso.paginas*pageSize)
+			if err != nil || resultado != caso.resultado {
+				t.Fatalf("resultado = %q (%v), se esperaba %q", resultado, err, caso.resultado)
+			}
+			if finaliza := slices.Contains(kernel.recibidos(), "process?pid=1&motivo=OUT_OF_MEMORY"); finaliza != caso.finaliza {
+				t.Errorf("se finalizo el proceso = %v, se esperaba %v: %v", finaliza, caso.finaliza, kernel.recibidos())
+			}
+			if esperandoMemoria != caso.espera {
+				t.Errorf("esperandoMemoria = %v, se esperaba %v", esperandoMemoria, caso.espera)
+			}
+			if caso.resultado != ResizeOK && cantidadPaginas(1) != 1 {
+				t.Errorf("el RESIZE fallido cambio el proceso: %d paginas", cantidadPaginas(1))
+			}
+		})
+	}
+}
+
+func TestAvisoMemoriaLiberada(t *testing.T) {
+	kernel := configurarMemoria(t, globals.Config{PoliticaSinMemoria: "BLOCK"}, 2)
+	procesoDePrueba("hola")
+	redimensionarProceso(1, 2*pageSize)
+
+	// Achicarse sin nadie esperando no le avisa al kernel
+	redimensionarProceso(1, pageSize)
+	if esperarPedido(kernel, "memoryFreed?") {
+		t.Fatal("se aviso memoria liberada sin procesos bloqueados")
+	}
+
+	redimensionarProceso(1, 2*pageSize)
+	if resultado, _ := redimensionarProceso(1, 3*pageSize); resultado != ResizeOutOfMemory || !esperandoMemoria {
+		t.Fatalf("resultado = %q, se esperaba que el proceso quede esperando memoria", resultado)
+	}
+	redimensionarProceso(1, pageSize)
+	if !esperarPedido(kernel, "memoryFreed?") {
+		t.Fatalf("el kernel no recibio memoryFreed: %v", kernel.recibidos())
+	}
+	if esperandoMemoria {
+		t.Error("despues de avisar no tendria que quedar nadie esperando")
+	}
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/sisoputnfrba/tp-golang/utils/instrucciones"
+)
+
+// Etiquetas de cada proceso (nombre -> numero de instruccion), las usa /label para CALL y los saltos.
+// Igual que mapInstructions, se protege con el mutex de memoria
+var etiquetas = make(map[int]map[string]int)
+
+// Lee el archivo y lo ensambla: primera pasada saca comentarios, lineas vacias y etiquetas,
+// la segunda valida cada instruccion contra la tabla de la CPU y reemplaza las etiquetas por numeros
+func ensamblarArchivo(path string) (instrucciones.Programa, error) {
+	readFile, err := os.Open(path)
+	if err != nil {
+		return instrucciones.Programa{}, fmt.Errorf("no se pudo abrir el archivo %s: %v", path, err)
+	}
+	defer readFile.Close()
+
+	var lineas []string
+	fileScanner := bufio.NewScanner(readFile)
+	fileScanner.Split(bufio.ScanLines)
+	for fileScanner.Scan() {
+		lineas = append(lineas, fileScanner.Text())
+	}
+	if err := fileScanner.Err(); err != nil {
+		return instrucciones.Programa{}, fmt.Errorf("error al leer el archivo %s: %v", path, err)
+	}
+
+	programa, err := instrucciones.Ensamblar(lineas)
+	if err != nil {
+		return programa, err
+	}
+	if len(programa.Lineas) == 0 {
+		return programa, fmt.Errorf("el archivo %s no tiene instrucciones", path)
+	}
+	return programa, nil
+}
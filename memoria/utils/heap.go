@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+type BloqueHeap struct {
+	Inicio int  `json:"inicio"` // Direccion logica del bloque
+	Tamaño int  `json:"tamanio"`
+	Libre  bool `json:"libre"`
+}
+
+// El heap arranca donde terminaba el proceso en el primer MALLOC, asi no pisa lo que se reservo con RESIZE
+type Heap struct {
+	Base    int          `json:"base"`
+	Bloques []BloqueHeap `json:"bloques"` // Ordenados por direccion, cubren desde Base hasta el final del proceso
+}
+
+type BodyMalloc struct {
+	Pid       int    `json:"pid"`
+	Size      int    `json:"size,omitempty"`
+	Direccion int    `json:"address"`
+	Resultado string `json:"result,omitempty"` // OK u OUT_OF_MEMORY, igual que RESIZE
+	Politica  string `json:"policy,omitempty"`
+}
+
+var heaps = make(map[int]*Heap)
+
+// Tamaño del espacio logico que puede usar el heap y donde empieza
+func espacioHeap(pid int) (inicio int, fin int) {
+	if modoSegmentacion() {
+		inicio = SegmentoDatos * globals.ClientConfig.TamMaxSegmento
+		return inicio, inicio + segmentTable[pid][SegmentoDatos].Limite
+	}
+	return baseDatos(pid), cantidadPaginas(pid) * pageSize
+}
+
+// Deja los bloques alineados con el tamaño actual del proceso, que puede haber cambiado con RESIZE
+func ajustarHeap(pid int) *Heap {
+	inicio, fin := espacioHeap(pid)
+	heap, exists := heaps[pid]
+	if !exists {
+		heap = &Heap{Base: fin}
+		heaps[pid] = heap
+	}
+	if heap.Base < inicio {
+		heap.Base = inicio
+	}
+
+	var bloques []BloqueHeap
+	final := heap.Base
+	for _, bloque := range heap.Bloques {
+		if bloque.Inicio >= fin {
+			break
+		}
+		bloque.Tamaño = min(bloque.Tamaño, fin-bloque.Inicio)
+		bloques = append(bloques, bloque)
+		final = bloque.Inicio + bloque.Tamaño
+	}
+	if final < fin {
+		bloques = append(bloques, BloqueHeap{Inicio: final, Tamaño: fin - final, Libre: true})
+	}
+	heap.Bloques = unirBloquesLibres(bloques)
+	return heap
+}
+
+func unirBloquesLibres(bloques []BloqueHeap) []BloqueHeap {
+	var unidos []BloqueHeap
+	for _, bloque := range bloques {
+		if n := len(unidos); n > 0 && unidos[n-1].Libre && bloque.Libre {
+			unidos[n-1].Tamaño += bloque.Tamaño
+			continue
+		}
+		unidos = append(unidos, bloque)
+	}
+	return unidos
+}
+
+// Indice del bloque libre donde entra el pedido segun el algoritmo, -1 si no hay
+func buscarBloqueLibre(heap *Heap, tam int) int {
+	elegido := -1
+	for i, bloque := range heap.Bloques {
+		if !bloque.Libre || bloque.Tamaño < tam {
+			continue
+		}
+		if globals.ClientConfig.AlgoritmoHeap != "BEST" {
+			return i
+		}
+		if elegido == -1 || bloque.Tamaño < heap.Bloques[elegido].Tamaño {
+			elegido = i
+		}
+	}
+	return elegido
+}
+
+// Reserva el bloque partiendo el hueco elegido, devuelve la direccion o -1
+func reservarBloque(pid int, tam int) int {
+	heap := ajustarHeap(pid)
+	i := buscarBloqueLibre(heap, tam)
+	if i == -1 {
+		return -1
+	}
+	bloque := heap.Bloques[i]
+	heap.Bloques[i] = BloqueHeap{Inicio: bloque.Inicio, Tamaño: tam}
+	if bloque.Tamaño > tam {
+		resto := BloqueHeap{Inicio: bloque.Inicio + tam, Tamaño: bloque.Tamaño - tam, Libre: true}
+		heap.Bloques = append(heap.Bloques[:i+1], append([]BloqueHeap{resto}, heap.Bloques[i+1:]...)...)
+	}
+	return bloque.Inicio
+}
+
+// La reserva y el agrandado del proceso se hacen con el mutex tomado, asi otro MALLOC o RESIZE
+// no se queda con el hueco ni cambia el tamaño en el medio
+func Malloc(pid int, tam int) (int, string, error) {
+	if tam <= 0 {
+		return -1, "", fmt.Errorf("tamaño invalido: %d", tam)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !procesoExiste(pid) {
+		return -1, "", fmt.Errorf("Process with PID %d not found", pid)
+	}
+	direccion := reservarBloque(pid, tam)
+	if direccion == -1 { // No hay hueco, el proceso se agranda por el mismo camino que RESIZE
+		heap := heaps[pid]
+		faltante := tam
+		if n := len(heap.Bloques); n > 0 && heap.Bloques[n-1].Libre {
+			faltante -= heap.Bloques[n-1].Tamaño // El hueco del final se aprovecha al agrandar
+		}
+		inicio, fin := espacioHeap(pid)
+		resultado, err := redimensionarProceso(pid, fin-inicio+faltante)
+		if err != nil || resultado != ResizeOK {
+			return -1, resultado, err
+		}
+		direccion = reservarBloque(pid, tam)
+		if direccion == -1 {
+			return -1, "", fmt.Errorf("no se pudo reservar %d bytes despues de agrandar el proceso", tam)
+		}
+	}
+
+	direccion -= baseDatos(pid) // El programa ve las direcciones de sus datos, como en MOV_IN y MOV_OUT
+	log.Printf("PID: %d - MALLOC - Direccion: %d - Tamaño: %d", pid, direccion, tam)
+	return direccion, ResizeOK, nil
+}
+
+func Free(pid int, direccion int) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !procesoExiste(pid) {
+		return fmt.Errorf("Process with PID %d not found", pid)
+	}
+	heap := ajustarHeap(pid)
+	logica := direccion + baseDatos(pid)
+	for i, bloque := range heap.Bloques {
+		if bloque.Inicio == logica && !bloque.Libre {
+			heap.Bloques[i].Libre = true
+			heap.Bloques = unirBloquesLibres(heap.Bloques)
+			log.Printf("PID: %d - FREE - Direccion: %d - Tamaño: %d", pid, direccion, bloque.Tamaño)
+			return nil
+		}
+	}
+	return fmt.Errorf("la direccion %d no es un bloque reservado del PID %d", direccion, pid)
+}
+
+func MallocHandler(w http.ResponseWriter, r *http.Request) {
+	var body BodyMalloc
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	direccion, resultado, err := Malloc(body.Pid, body.Size)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BodyMalloc{Pid: body.Pid, Size: body.Size, Direccion: direccion, Resultado: resultado, Politica: politicaSinMemoria()})
+}
+
+// Un FREE invalido devuelve 400 y la CPU lo toma como SEGMENTATION_FAULT
+func FreeHandler(w http.ResponseWriter, r *http.Request) {
+	var body BodyMalloc
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := Free(body.Pid, body.Direccion); err != nil {
+		log.Printf("PID: %d - FREE invalido: %v", body.Pid, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// GET /memory/heap/{pid}
+func HeapHandler(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.Atoi(r.PathValue("pid"))
+	if err != nil {
+		http.Error(w, "PID inválido", http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	if !procesoExiste(pid) {
+		mu.Unlock()
+		http.Error(w, fmt.Sprintf("Process with PID %d not found", pid), http.StatusNotFound)
+		return
+	}
+	var heap Heap
+	if _, exists := heaps[pid]; exists {
+		heap = *ajustarHeap(pid)
+	}
+	mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(heap)
+}
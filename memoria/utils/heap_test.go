@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// Un MALLOC de tam bytes que devuelve la direccion, o un FREE de la direccion
+type pedidoHeap struct {
+	free      bool
+	tam       int
+	direccion int
+	falla     bool
+}
+
+func malloc(tam int, direccion int) pedidoHeap { return pedidoHeap{tam: tam, direccion: direccion} }
+func free(direccion int) pedidoHeap            { return pedidoHeap{free: true, direccion: direccion} }
+func falla(pedido pedidoHeap) pedidoHeap       { pedido.falla = true; return pedido }
+
+func TestMallocFree(t *testing.T) {
+	// El PID 1 tiene una pagina de 16 bytes, el heap arranca al final: en 16
+	casos := []struct {
+		nombre    string
+		algoritmo string
+		pedidos   []pedidoHeap
+		libre     int // Direccion que tiene que quedar libre al final, -1 si no importa
+	}{
+		{"agranda el proceso", "", []pedidoHeap{malloc(8, 16), malloc(4, 24), malloc(20, 28)}, -1},
+		{"FIRST usa el primer hueco", "FIRST", []pedidoHeap{malloc(8, 16), malloc(4, 24), malloc(4, 28), free(16), free(28), malloc(4, 16)}, 20},
+		{"BEST usa el hueco mas chico", "BEST", []pedidoHeap{malloc(8, 16), malloc(4, 24), malloc(4, 28), free(16), free(28), malloc(4, 28)}, 16},
+		{"une huecos vecinos", "", []pedidoHeap{malloc(8, 16), malloc(4, 24), malloc(4, 28), free(24), free(16), malloc(12, 16)}, -1},
+		{"FREE repetido", "", []pedidoHeap{malloc(8, 16), free(16), falla(free(16))}, 16},
+		{"FREE en el medio de un bloque", "", []pedidoHeap{malloc(8, 16), falla(free(20))}, -1},
+		{"MALLOC de 0", "", []pedidoHeap{falla(malloc(0, 0))}, -1},
+		{"sin memoria", "", []pedidoHeap{malloc(40, 16), falla(malloc(16, 0))}, -1},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			configurarMemoria(t, globals.Config{AlgoritmoHeap: caso.algoritmo, PoliticaSinMemoria: "FAIL"}, 4)
+			procesoDePrueba("hola")
+			for _, pedido := range caso.pedidos {
+				if pedido.free {
+					if err := Free(1, pedido.direccion); (err != nil) != pedido.falla {
+						t.Fatalf("FREE %d: error = %v", pedido.direccion, err)
+					}
+					continue
+				}
+				direccion, resultado, err := Malloc(1, pedido.tam)
+				if pedido.falla {
+					if err == nil && resultado == ResizeOK {
+						t.Fatalf("MALLOC %d: se esperaba que falle y devolvio %d", pedido.tam, direccion)
+					}
+					continue
+				}
+				if err != nil || resultado != ResizeOK || direccion != pedido.direccion {
+					t.Fatalf("MALLOC %d = %d, %q, %v, se esperaba %d", pedido.tam, direccion, resultado, err, pedido.direccion)
+				}
+			}
+			if caso.libre == -1 {
+				return
+			}
+			for _, bloque := range heaps[1].Bloques {
+				if bloque.Inicio == caso.libre && !bloque.Libre {
+					t.Errorf("el bloque en %d no quedo libre: %+v", caso.libre, heaps[1].Bloques)
+				}
+			}
+		})
+	}
+}
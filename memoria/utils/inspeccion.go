@@ -0,0 +1,282 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+type EstadoFrame struct {
+	Frame        int   `json:"frame"`
+	Ocupado      bool  `json:"ocupado"`
+	Referencias  int   `json:"referencias"` // Mas de 1 si el marco es copy-on-write
+	Pid          int   `json:"pid"`
+	Pagina       int   `json:"pagina"`
+	Compartido   []int `json:"compartido_con,omitempty"`
+	Referenciado bool  `json:"referenciado"`
+	Modificado   bool  `json:"modificado"`
+}
+
+type EstadoPagina struct {
+	Pagina     int    `json:"pagina"`
+	Frame      int    `json:"frame"` // -1 si no esta cargada
+	Presente   bool   `json:"presente"`
+	SlotSwap   int    `json:"slot_swap"` // -1 si no hay memoria virtual
+	Permisos   string `json:"permisos"`
+	Compartida bool   `json:"compartida"`
+}
+
+type EstadoProceso struct {
+	Pid            int            `json:"pid"`
+	Tamaño         int            `json:"tamanio"`
+	Paginas        []EstadoPagina `json:"paginas,omitempty"`
+	MarcosCargados []int          `json:"marcos_cargados,omitempty"`
+	Segmentos      []Segmento     `json:"segmentos,omitempty"`
+}
+
+// GET /memory/frames
+func FramesHandler(w http.ResponseWriter, r *http.Request) {
+	mu.Lock()
+	frames := make([]EstadoFrame, len(frameTable))
+	for frame, info := range frameTable {
+		frames[frame] = EstadoFrame{
+			Frame:        frame,
+			Ocupado:      memoryMap[frame] > 0,
+			Referencias:  memoryMap[frame],
+			Pid:          info.Pid,
+			Pagina:       info.Pagina,
+			Referenciado: info.Referenciado,
+			Modificado:   info.Modificado,
+		}
+		if frameCompartido(frame) {
+			for _, pid := range pidsPaginados() {
+				if pid != info.Pid && paginaDelFrame(pid, frame) != -1 {
+					frames[frame].Compartido = append(frames[frame].Compartido, pid)
+				}
+			}
+		}
+	}
+	mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(frames)
+}
+
+// GET /memory/process/{pid}
+func ProcessMemoryHandler(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.Atoi(r.PathValue("pid"))
+	if err != nil {
+		http.Error(w, "PID inválido", http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	estado, err := estadoProceso(pid)
+	mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(estado)
+}
+
+func estadoProceso(pid int) (EstadoProceso, error) {
+	if !procesoExiste(pid) {
+		return EstadoProceso{}, fmt.Errorf("Process with PID %d not found", pid)
+	}
+	estado := EstadoProceso{Pid: pid}
+
+	if modoSegmentacion() {
+		estado.Segmentos = segmentTable[pid]
+		for _, segmento := range segmentTable[pid] {
+			estado.Tamaño += segmento.Limite
+			estado.MarcosCargados = append(estado.MarcosCargados, segmento.Paginas...)
+		}
+		return estado, nil
+	}
+
+	for pagina, frame := range paginasDelProceso(pid) {
+		slot := -1
+		if memoriaVirtual {
+			slot = swapTable[pid][pagina]
+		}
+		estado.Paginas = append(estado.Paginas, EstadoPagina{
+			Pagina:     pagina,
+			Frame:      frame,
+			Presente:   frame != -1,
+			SlotSwap:   slot,
+			Permisos:   permisosPagina(pid, pagina),
+			Compartida: frame != -1 && frameCompartido(frame),
+		})
+		if frame != -1 {
+			estado.MarcosCargados = append(estado.MarcosCargados, frame)
+		}
+	}
+	estado.Tamaño = cantidadPaginas(pid) * pageSize
+	return estado, nil
+}
+
+// GET /memory/dump?pid=&from=&len=&format=hex|ascii
+// Con pid, from es una direccion logica del proceso. Sin pid se muestra la memoria fisica
+func DumpHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	desde, err := parametroEntero(query.Get("from"), 0)
+	if err != nil {
+		http.Error(w, "from inválido", http.StatusBadRequest)
+		return
+	}
+	largo, err := parametroEntero(query.Get("len"), pageSize)
+	if err != nil || largo < 0 {
+		http.Error(w, "len inválido", http.StatusBadRequest)
+		return
+	}
+	formato := query.Get("format")
+	if formato == "" {
+		formato = "hex"
+	}
+	if formato != "hex" && formato != "ascii" {
+		http.Error(w, "format debe ser hex o ascii", http.StatusBadRequest)
+		return
+	}
+
+	contenido, err := leerVolcado(query.Get("pid"), desde, largo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if formato == "ascii" {
+		w.Write([]byte(caracteresVisibles(contenido)))
+		return
+	}
+	w.Write([]byte(volcadoHex(desde, contenido)))
+}
+
+func parametroEntero(valor string, porDefecto int) (int, error) {
+	if valor == "" {
+		return porDefecto, nil
+	}
+	return strconv.Atoi(valor)
+}
+
+// Lee con el mutex de memoria tomado. Sin pid lee la memoria fisica
+func leerVolcado(pidTexto string, desde int, largo int) ([]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if pidTexto == "" {
+		return leerFisico(desde, largo)
+	}
+	pid, err := strconv.Atoi(pidTexto)
+	if err != nil {
+		return nil, err
+	}
+	return leerLogico(pid, desde, largo)
+}
+
+func leerFisico(desde int, largo int) ([]byte, error) {
+	if desde < 0 || largo > len(memory)-desde {
+		return nil, fmt.Errorf("el rango %d-%d esta fuera de la memoria", desde, desde+largo)
+	}
+	return append([]byte{}, memory[desde:desde+largo]...), nil
+}
+
+// Lee el espacio de usuario del proceso, las paginas que estan en swap se leen del archivo
+func leerLogico(pid int, desde int, largo int) ([]byte, error) {
+	if !procesoExiste(pid) {
+		return nil, fmt.Errorf("Process with PID %d not found", pid)
+	}
+	// El rango se controla antes de reservar, un len enorme no tiene que llegar al make
+	tamaño := cantidadPaginas(pid) * pageSize
+	if modoSegmentacion() {
+		tamaño = len(segmentTable[pid]) * globals.ClientConfig.TamMaxSegmento
+	}
+	if desde < 0 || largo > tamaño-desde {
+		return nil, fmt.Errorf("el rango %d-%d esta fuera del PID %d", desde, desde+largo, pid)
+	}
+
+	contenido := make([]byte, largo)
+	enSwap := make(map[int][]byte) // Paginas en swap ya leidas, se lee la pagina entera una sola vez
+	for i := range contenido {
+		direccion := desde + i
+		if modoSegmentacion() {
+			fisica, err := direccionSegmento(pid, direccion)
+			if err != nil {
+				return nil, err
+			}
+			contenido[i] = memory[fisica]
+			continue
+		}
+
+		pagina := direccion / pageSize
+		if direccion < 0 || pagina >= cantidadPaginas(pid) {
+			return nil, fmt.Errorf("la direccion %d esta fuera del PID %d", direccion, pid)
+		}
+		if frame := marcoDePagina(pid, pagina); frame != -1 {
+			contenido[i] = memory[frame*pageSize+direccion%pageSize]
+			continue
+		}
+		if enSwap[pagina] == nil {
+			datos := make([]byte, pageSize)
+			if _, err := swapFile.ReadAt(datos, int64(swapTable[pid][pagina]*pageSize)); err != nil {
+				return nil, fmt.Errorf("error al leer de swap: %v", err)
+			}
+			enSwap[pagina] = datos
+		}
+		contenido[i] = enSwap[pagina][direccion%pageSize]
+	}
+	return contenido, nil
+}
+
+// Traduce numero de segmento | offset igual que la MMU de la CPU
+func direccionSegmento(pid int, direccion int) (int, error) {
+	tamMax := globals.ClientConfig.TamMaxSegmento
+	numSegmento, offset := direccion/tamMax, direccion%tamMax
+	if direccion < 0 || numSegmento >= len(segmentTable[pid]) || offset >= segmentTable[pid][numSegmento].Limite {
+		return -1, fmt.Errorf("la direccion %d esta fuera de los segmentos del PID %d", direccion, pid)
+	}
+	segmento := segmentTable[pid][numSegmento]
+	if segmentacionPaginada() {
+		return segmento.Paginas[offset/pageSize]*pageSize + offset%pageSize, nil
+	}
+	return segmento.Base + offset, nil
+}
+
+func caracteresVisibles(contenido []byte) string {
+	var sb strings.Builder
+	for _, b := range contenido {
+		if b >= 32 && b < 127 {
+			sb.WriteByte(b)
+		} else {
+			sb.WriteByte('.')
+		}
+	}
+	return sb.String()
+}
+
+// Formato de hexdump -C, con la direccion de cada fila
+func volcadoHex(desde int, contenido []byte) string {
+	var sb strings.Builder
+	for fila := 0; fila < len(contenido); fila += 16 {
+		fin := min(fila+16, len(contenido))
+		fmt.Fprintf(&sb, "%08x  ", desde+fila)
+		for i := fila; i < fila+16; i++ {
+			if i < fin {
+				fmt.Fprintf(&sb, "%02x ", contenido[i])
+			} else {
+				sb.WriteString("   ")
+			}
+			if i == fila+7 {
+				sb.WriteString(" ")
+			}
+		}
+		fmt.Fprintf(&sb, " |%s|\n", caracteresVisibles(contenido[fila:fin]))
+	}
+	return sb.String()
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+func TestDumpRangos(t *testing.T) {
+	configurarMemoria(t, globals.Config{}, 4)
+	asignarFrame(2, 1, 0)
+	crearTablaPaginas(1)
+	agregarPagina(1, 2)
+	copy(memory[2*pageSize:], "hola")
+
+	casos := []struct {
+		nombre    string
+		consulta  string
+		estado    int
+		contenido string
+	}{
+		{"logica", "pid=1&from=0&len=4&format=ascii", http.StatusOK, "hola"},
+		{"fisica", "from=32&len=4&format=ascii", http.StatusOK, "hola"},
+		{"logica hasta el final", "pid=1&from=12&len=4&format=ascii", http.StatusOK, "...."},
+		{"logica fuera del proceso", "pid=1&from=12&len=5", http.StatusBadRequest, ""},
+		{"len enorme", "pid=1&len=9223372036854775807", http.StatusBadRequest, ""},
+		{"len enorme en fisica", "from=1&len=9223372036854775807", http.StatusBadRequest, ""},
+		{"from negativo", "pid=1&from=-1&len=1", http.StatusBadRequest, ""},
+		{"proceso que no existe", "pid=7&len=1", http.StatusBadRequest, ""},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			DumpHandler(w, httptest.NewRequest("GET", "/memory/dump?"+caso.consulta, nil))
+			if w.Code != caso.estado {
+				t.Fatalf("estado = %d, se esperaba %d: %s", w.Code, caso.estado, w.Body.String())
+			}
+			if caso.contenido != "" && !strings.HasPrefix(w.Body.String(), caso.contenido) {
+				t.Errorf("contenido = %q, se esperaba %q", w.Body.String(), caso.contenido)
+			}
+			if !mu.TryLock() {
+				t.Fatal("el volcado dejo tomado el mutex de memoria")
+			}
+			mu.Unlock()
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+	"github.com/sisoputnfrba/tp-golang/utils/paginacion"
+)
+
+// Entrada del ultimo nivel que no corresponde a ninguna pagina del proceso
+const entradaSinUso = -2
+
+// Tabla de un nivel. Las tablas intermedias apuntan a tablas del nivel siguiente
+// y las del ultimo nivel tienen el marco de cada pagina (-1 si la pagina esta en swap)
+type TablaNivel struct {
+	Entradas []*TablaNivel
+	Marcos   []int // Solo en el ultimo nivel
+	enUso    int   // Entradas ocupadas, cuando llega a 0 la tabla se libera
+}
+
+// Tablas de un proceso: la de primer nivel y cuantas paginas tiene
+type TablasProceso struct {
+	Raiz    *TablaNivel
+	Paginas int
+}
+
+// Con mas de un nivel las tablas de cada proceso estan aca y pageTable no se usa
+var tablasMultinivel = make(map[int]*TablasProceso)
+
+func tablasMultinivelActivas() bool {
+	return globals.ClientConfig.NivelesTablas > 1 && globals.ClientConfig.EntradasPorTabla > 0
+}
+
+// Cantidad maxima de paginas que se pueden direccionar con la estructura configurada
+func maxPaginasMultinivel() int {
+	return paginacion.MaxPaginas(globals.ClientConfig.NivelesTablas, globals.ClientConfig.EntradasPorTabla)
+}
+
+func indicesDePagina(pagina int) []int {
+	return paginacion.IndicesDePagina(pagina, globals.ClientConfig.NivelesTablas, globals.ClientConfig.EntradasPorTabla)
+}
+
+func nuevaTablaNivel(ultimoNivel bool) *TablaNivel {
+	entradas := globals.ClientConfig.EntradasPorTabla
+	if ultimoNivel {
+		marcos := make([]int, entradas)
+		for i := range marcos {
+			marcos[i] = entradaSinUso
+		}
+		return &TablaNivel{Marcos: marcos}
+	}
+	return &TablaNivel{Entradas: make([]*TablaNivel, entradas)}
+}
+
+// Tabla del ultimo nivel que tiene la pagina. Con crear arma las tablas intermedias que falten
+func tablaDePagina(tablas *TablasProceso, pagina int, crear bool) (*TablaNivel, int) {
+	niveles := globals.ClientConfig.NivelesTablas
+	indices := indicesDePagina(pagina)
+	tabla := tablas.Raiz
+	for nivel := 0; nivel < niveles-1; nivel++ {
+		if tabla.Entradas[indices[nivel]] == nil {
+			if !crear {
+				return nil, -1
+			}
+			tabla.Entradas[indices[nivel]] = nuevaTablaNivel(nivel == niveles-2)
+			tabla.enUso++
+		}
+		tabla = tabla.Entradas[indices[nivel]]
+	}
+	return tabla, indices[niveles-1]
+}
+
+// Saca la pagina de su tabla y libera las tablas que quedan vacias, desde el ultimo nivel hacia arriba
+func liberarEntrada(tabla *TablaNivel, indices []int) {
+	if len(indices) == 1 {
+		tabla.Marcos[indices[0]] = entradaSinUso
+		tabla.enUso--
+		return
+	}
+	siguiente := tabla.Entradas[indices[0]]
+	liberarEntrada(siguiente, indices[1:])
+	if siguiente.enUso == 0 {
+		tabla.Entradas[indices[0]] = nil
+		tabla.enUso--
+	}
+}
+
+/////////////////////////////////// ACCESO A LA TABLA DE PAGINAS (PLANA O MULTINIVEL) ///////////////////////////////////
+
+func tieneTablaPaginas(pid int) bool {
+	if tablasMultinivelActivas() {
+		_, exists := tablasMultinivel[pid]
+		return exists
+	}
+	_, exists := pageTable[pid]
+	return exists
+}
+
+func crearTablaPaginas(pid int) {
+	if tablasMultinivelActivas() {
+		tablasMultinivel[pid] = &TablasProceso{Raiz: nuevaTablaNivel(false)}
+		return
+	}
+	pageTable[pid] = []int{}
+}
+
+func borrarTablaPaginas(pid int) {
+	delete(tablasMultinivel, pid)
+	delete(pageTable, pid)
+}
+
+func cantidadPaginas(pid int) int {
+	if tablasMultinivelActivas() {
+		if tablas, exists := tablasMultinivel[pid]; exists {
+			return tablas.Paginas
+		}
+		return 0
+	}
+	return len(pageTable[pid])
+}
+
+// Marco de la pagina, -1 si esta en swap. La pagina tiene que ser del proceso
+func marcoDePagina(pid int, pagina int) int {
+	if tablasMultinivelActivas() {
+		tabla, indice := tablaDePagina(tablasMultinivel[pid], pagina, false)
+		return tabla.Marcos[indice]
+	}
+	return pageTable[pid][pagina]
+}
+
+func asignarMarcoPagina(pid int, pagina int, frame int) {
+	if tablasMultinivelActivas() {
+		tabla, indice := tablaDePagina(tablasMultinivel[pid], pagina, false)
+		tabla.Marcos[indice] = frame
+		return
+	}
+	pageTable[pid][pagina] = frame
+}
+
+// Agrega una pagina al final del proceso
+func agregarPagina(pid int, frame int) {
+	if tablasMultinivelActivas() {
+		tablas := tablasMultinivel[pid]
+		tabla, indice := tablaDePagina(tablas, tablas.Paginas, true)
+		tabla.Marcos[indice] = frame
+		tabla.enUso++
+		tablas.Paginas++
+		return
+	}
+	pageTable[pid] = append(pageTable[pid], frame)
+}
+
+// Deja el proceso con las primeras paginas, los marcos los tiene que soltar el que llama
+func recortarPaginas(pid int, paginas int) {
+	if tablasMultinivelActivas() {
+		tablas := tablasMultinivel[pid]
+		for ; tablas.Paginas > paginas; tablas.Paginas-- {
+			liberarEntrada(tablas.Raiz, indicesDePagina(tablas.Paginas-1))
+		}
+		return
+	}
+	pageTable[pid] = pageTable[pid][:paginas]
+}
+
+// Copia del marco de cada pagina, en orden
+func paginasDelProceso(pid int) []int {
+	if !tablasMultinivelActivas() {
+		return append([]int{}, pageTable[pid]...)
+	}
+	marcos := make([]int, cantidadPaginas(pid))
+	for pagina := range marcos {
+		marcos[pagina] = marcoDePagina(pid, pagina)
+	}
+	return marcos
+}
+
+// Arma la tabla del proceso con el marco de cada pagina (clonacion y restore)
+func cargarTablaPaginas(pid int, marcos []int) {
+	crearTablaPaginas(pid)
+	for _, frame := range marcos {
+		agregarPagina(pid, frame)
+	}
+}
+
+func pidsPaginados() []int {
+	var pids []int
+	if tablasMultinivelActivas() {
+		for pid := range tablasMultinivel {
+			pids = append(pids, pid)
+		}
+		return pids
+	}
+	for pid := range pageTable {
+		pids = append(pids, pid)
+	}
+	return pids
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// Retardo de acceder a cada nivel de tablas. Se espera sin tener el mutex de memoria
+func esperarAccesoTablas() {
+	time.Sleep(time.Duration(globals.ClientConfig.NivelesTablas*globals.ClientConfig.RetardoTabla) * time.Millisecond)
+}
+
+// Recorre las tablas del proceso con los indices que calculo la MMU. Devuelve la pagina y su marco
+func recorrerTablas(pid int, indices []int) (int, int, error) {
+	tablas, exists := tablasMultinivel[pid]
+	if !exists {
+		return -1, -1, fmt.Errorf("PID %d no tiene tablas de paginas", pid)
+	}
+	if len(indices) != globals.ClientConfig.NivelesTablas {
+		return -1, -1, fmt.Errorf("se esperaban %d indices y llegaron %d", globals.ClientConfig.NivelesTablas, len(indices))
+	}
+
+	tabla := tablas.Raiz
+	for nivel, indice := range indices {
+		log.Printf("PID: %d - Acceso a Tabla de Nivel %d - Entrada: %d", pid, nivel+1, indice)
+		if indice < 0 || indice >= globals.ClientConfig.EntradasPorTabla {
+			return -1, -1, fmt.Errorf("entrada %d fuera de la tabla de nivel %d", indice, nivel+1)
+		}
+		if nivel == len(indices)-1 {
+			if tabla.Marcos[indice] == entradaSinUso {
+				return -1, -1, fmt.Errorf("entrada %d de la tabla de nivel %d sin uso", indice, nivel+1)
+			}
+			return paginacion.PaginaDeIndices(indices, globals.ClientConfig.EntradasPorTabla), tabla.Marcos[indice], nil
+		}
+		if tabla.Entradas[indice] == nil {
+			return -1, -1, fmt.Errorf("entrada %d de la tabla de nivel %d sin uso", indice, nivel+1)
+		}
+		tabla = tabla.Entradas[indice]
+	}
+	return -1, -1, fmt.Errorf("no hay indices para recorrer")
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+type BodyPila struct {
+	Base int `json:"base"` // Direccion logica mas baja que puede usar la pila
+	Tope int `json:"top"`  // Valor inicial del SP, la pila crece hacia abajo
+}
+
+type BodyEtiqueta struct {
+	PC int `json:"pc"`
+}
+
+// Con paginacion la pila son las paginas entre el codigo y los datos (ver baseDatos). Sin stack_size
+// la pila comparte los datos del proceso y arranca al final. Con segmentacion es el segmento de pila
+func limitesPila(pid int) (BodyPila, error) {
+	if !procesoExiste(pid) {
+		return BodyPila{}, fmt.Errorf("Process with PID %d not found", pid)
+	}
+	if modoSegmentacion() {
+		base := SegmentoPila * globals.ClientConfig.TamMaxSegmento
+		return BodyPila{Base: base, Tope: base + segmentTable[pid][SegmentoPila].Limite}, nil
+	}
+	if globals.ClientConfig.TamPila <= 0 {
+		return BodyPila{Base: baseDatos(pid), Tope: cantidadPaginas(pid) * pageSize}, nil
+	}
+	return BodyPila{Base: redondearAPagina(programasBinarios[pid]), Tope: baseDatos(pid)}, nil
+}
+
+// GET /stack?pid=
+func StackHandler(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.Atoi(r.URL.Query().Get("pid"))
+	if err != nil {
+		http.Error(w, "PID inválido", http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	pila, err := limitesPila(pid)
+	mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pila)
+}
+
+// GET /label?pid=&name= devuelve el numero de instruccion de la linea "name:"
+func LabelHandler(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.Atoi(r.URL.Query().Get("pid"))
+	if err != nil {
+		http.Error(w, "PID inválido", http.StatusBadRequest)
+		return
+	}
+	nombre := r.URL.Query().Get("name")
+
+	// Las etiquetas las resolvio el ensamblador al cargar el programa
+	mu.Lock()
+	pc, exists := etiquetas[pid][nombre]
+	mu.Unlock()
+	if exists {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(BodyEtiqueta{PC: pc})
+		return
+	}
+	http.Error(w, fmt.Sprintf("la etiqueta %s no existe en el PID %d", nombre, pid), http.StatusNotFound)
+}
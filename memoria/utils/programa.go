@@ -0,0 +1,217 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+	"github.com/sisoputnfrba/tp-golang/utils/instrucciones"
+)
+
+const (
+	FormatoTexto   = "TEXT"
+	FormatoBinario = "BINARY"
+)
+
+type BodyPrograma struct {
+	Pid     int    `json:"pid"`
+	Formato string `json:"format"`
+	Tamaño  int    `json:"size"` // Cantidad de instrucciones (TEXT) o bytes de codigo (BINARY)
+	Datos   int    `json:"data"` // Direccion logica donde empiezan los datos del programa (ver baseDatos)
+}
+
+// Tamaño del codigo de los procesos con programa binario. El codigo esta en memoria de usuario desde la
+// direccion 0 (en segmentacion es el segmento de codigo), asi que el PC de estos procesos es en bytes
+var programasBinarios = make(map[int]int)
+
+// Si el archivo es un programa binario lo carga y devuelve true, si es texto devuelve false sin hacer nada
+func cargarArchivoBinario(pid int, path string) (bool, error) {
+	archivo, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("no se pudo abrir el archivo %s: %v", path, err)
+	}
+	if !instrucciones.EsBinario(archivo) {
+		return false, nil
+	}
+	codigo, err := instrucciones.CodigoBinario(archivo)
+	if err != nil {
+		return true, err
+	}
+	if len(codigo) == 0 {
+		return true, fmt.Errorf("el archivo %s no tiene instrucciones", path)
+	}
+	return true, cargarBinario(pid, codigo)
+}
+
+func cargarBinario(pid int, codigo []byte) error {
+	if modoSegmentacion() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !procesoExiste(pid) {
+			return fmt.Errorf("Process with PID %d not found", pid)
+		}
+		if err := redimensionarSegmento(pid, SegmentoCodigo, len(codigo)); err != nil {
+			return fmt.Errorf("no hay lugar para el codigo: %v", err)
+		}
+		programasBinarios[pid] = len(codigo)
+		return escribirLogico(pid, 0, codigo)
+	}
+
+	// El codigo ocupa las primeras paginas y despues va la pila. Se revisa antes que haya lugar para no
+	// aplicar la politica de OUT_OF_MEMORY a un proceso que el kernel todavia no creo
+	paginas := (len(codigo) + pageSize - 1) / pageSize
+	mu.Lock()
+	anterior, habia := programasBinarios[pid]
+	programasBinarios[pid] = len(codigo)
+	err := lugarParaPaginas(pid, baseDatos(pid)/pageSize)
+	if err == nil {
+		var resultado string
+		resultado, err = redimensionarProceso(pid, 0) // Sin datos: quedan el codigo y la pila
+		if err == nil && resultado != ResizeOK {
+			err = fmt.Errorf("%s", resultado)
+		}
+	}
+	if err != nil {
+		if habia {
+			programasBinarios[pid] = anterior
+		} else {
+			delete(programasBinarios, pid)
+		}
+		mu.Unlock()
+		return fmt.Errorf("no hay lugar para el codigo: %v", err)
+	}
+	err = escribirLogico(pid, 0, codigo)
+	mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ProtegerPaginas(pid, 0, paginas, "RX")
+}
+
+func lugarParaPaginas(pid int, paginas int) error {
+	if !tieneTablaPaginas(pid) {
+		return fmt.Errorf("Process with PID %d not found", pid)
+	}
+	if superaLimite(paginas) {
+		return fmt.Errorf("supera el limite de paginas por proceso")
+	}
+	if tablasMultinivelActivas() && paginas > maxPaginasMultinivel() {
+		return fmt.Errorf("las tablas de paginas no alcanzan")
+	}
+	if memoriaVirtual && counterSwapFree() < paginas {
+		return fmt.Errorf("no hay espacio en swap")
+	}
+	if !memoriaVirtual && framesDisponibles(pid) < paginas {
+		return fmt.Errorf("no hay marcos libres")
+	}
+	return nil
+}
+
+// Primera direccion logica de los datos del programa. Con paginacion el proceso es [codigo][pila][datos]:
+// los datos van despues de las paginas del codigo y de la pila, asi un MOV_OUT a la direccion 0 no pisa
+// instrucciones y RESIZE y MALLOC no pueden liberar ni usar el codigo ni la pila.
+// La CPU le suma la base a las direcciones de MOV_IN, MOV_OUT, COPY_STRING y las de IO.
+// Con segmentacion cada region es un segmento y las direcciones ya dicen a cual van
+func baseDatos(pid int) int {
+	if modoSegmentacion() {
+		return 0
+	}
+	return redondearAPagina(programasBinarios[pid]) + redondearAPagina(globals.ClientConfig.TamPila)
+}
+
+func redondearAPagina(tam int) int {
+	return (max(tam, 0) + pageSize - 1) / pageSize * pageSize
+}
+
+// Con stack_size la pila tiene sus propias paginas (ver baseDatos), se reservan al cargar el programa.
+// Hay que tener el mutex de memoria
+func reservarPila(pid int) error {
+	if modoSegmentacion() || globals.ClientConfig.TamPila <= 0 || cantidadPaginas(pid)*pageSize >= baseDatos(pid) {
+		return nil
+	}
+	if err := lugarParaPaginas(pid, baseDatos(pid)/pageSize-cantidadPaginas(pid)); err != nil {
+		return fmt.Errorf("no hay lugar para la pila: %v", err)
+	}
+	if resultado, err := redimensionarProceso(pid, 0); err != nil || resultado != ResizeOK {
+		return fmt.Errorf("no hay lugar para la pila: %v %s", err, resultado)
+	}
+	return nil
+}
+
+// Escribe en el espacio logico del proceso, igual que leerLogico (las paginas en swap se escriben en swap)
+func escribirLogico(pid int, desde int, datos []byte) error {
+	for i, dato := range datos {
+		direccion := desde + i
+		if modoSegmentacion() {
+			fisica, err := direccionSegmento(pid, direccion)
+			if err != nil {
+				return err
+			}
+			memory[fisica] = dato
+			continue
+		}
+
+		pagina := direccion / pageSize
+		if direccion < 0 || pagina >= cantidadPaginas(pid) {
+			return fmt.Errorf("la direccion %d esta fuera del PID %d", direccion, pid)
+		}
+		if frame := marcoDePagina(pid, pagina); frame != -1 {
+			memory[frame*pageSize+direccion%pageSize] = dato
+			frameTable[frame].Modificado = true
+			continue
+		}
+		offset := int64(swapTable[pid][pagina]*pageSize + direccion%pageSize)
+		if _, err := swapFile.WriteAt([]byte{dato}, offset); err != nil {
+			return fmt.Errorf("error al escribir en swap: %v", err)
+		}
+	}
+	return nil
+}
+
+func programaCargado(pid int) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	_, texto := mapInstructions[pid]
+	_, binario := programasBinarios[pid]
+	return texto || binario
+}
+
+// Si se vuelve a cargar el programa de un proceso, la CPU no puede seguir usando lo que tenga guardado
+func avisarRecargaPrograma(pid int) {
+	CPUurl := fmt.Sprintf("http://%s:%d/invalidateProgram?pid=%d", globals.ClientConfig.IpCPU, globals.ClientConfig.PuertoCPU, pid)
+	resp, err := http.Post(CPUurl, "application/json", nil)
+	if err != nil {
+		log.Printf("Error al avisar a la CPU que se recargo el programa: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+}
+
+// GET /program?pid= le dice a la CPU como hacer el fetch de las instrucciones
+func ProgramHandler(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.Atoi(r.URL.Query().Get("pid"))
+	if err != nil {
+		http.Error(w, "PID inválido", http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	programa := BodyPrograma{Pid: pid, Formato: FormatoTexto, Tamaño: len(mapInstructions[pid]), Datos: baseDatos(pid)}
+	if tam, exists := programasBinarios[pid]; exists {
+		programa.Formato = FormatoBinario
+		programa.Tamaño = tam
+	}
+	_, cargado := mapInstructions[pid]
+	mu.Unlock()
+	if programa.Formato == FormatoTexto && !cargado {
+		http.Error(w, fmt.Sprintf("el PID %d no tiene un programa cargado", pid), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(programa)
+}
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Permisos por defecto de las paginas nuevas
+const permisosPorDefecto = "RW"
+
+type BodyProtection struct {
+	Pid      int    `json:"pid"`
+	Page     int    `json:"page"`
+	Count    int    `json:"count"`
+	Permisos string `json:"permisos"` // Combinacion de R, W y X
+}
+
+// Permisos de cada pagina de cada proceso (misma posicion que en la tabla de paginas)
+var pageProtection = make(map[int][]string)
+
+// Deja una entrada de permisos por pagina, las paginas nuevas arrancan con los permisos por defecto
+func ajustarProteccion(pid int) {
+	if !tieneTablaPaginas(pid) {
+		delete(pageProtection, pid)
+		return
+	}
+	paginas := cantidadPaginas(pid)
+	permisos := pageProtection[pid]
+	for len(permisos) < paginas {
+		permisos = append(permisos, permisosPorDefecto)
+	}
+	pageProtection[pid] = permisos[:paginas]
+}
+
+func permisosPagina(pid int, pagina int) string {
+	if permisos, exists := pageProtection[pid]; exists && pagina < len(permisos) {
+		return permisos[pagina]
+	}
+	return permisosPorDefecto
+}
+
+// Permisos que ve la CPU: una pagina con el marco compartido por copy-on-write no tiene W hasta que se copia
+func permisosEfectivos(pid int, pagina int) string {
+	permisos := permisosPagina(pid, pagina)
+	if frame := marcoDePagina(pid, pagina); frame != -1 && frameCompartido(frame) {
+		return strings.ReplaceAll(permisos, "W", "")
+	}
+	return permisos
+}
+
+func ProtectPagesHandler(w http.ResponseWriter, r *http.Request) {
+	var body BodyProtection
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ProtegerPaginas(body.Pid, body.Page, body.Count, body.Permisos); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func ProtegerPaginas(pid int, desde int, cantidad int, permisos string) error {
+	permisos = strings.ToUpper(permisos)
+	if strings.Trim(permisos, "RWX") != "" {
+		return fmt.Errorf("permisos invalidos: %s", permisos)
+	}
+
+	mu.Lock()
+	paginas, exists := pageProtection[pid]
+	if !exists {
+		mu.Unlock()
+		return fmt.Errorf("Process with PID %d not found", pid)
+	}
+	if desde < 0 || cantidad < 0 || desde+cantidad > len(paginas) {
+		mu.Unlock()
+		return fmt.Errorf("PID %d no tiene las paginas %d a %d", pid, desde, desde+cantidad-1)
+	}
+	for pagina := desde; pagina < desde+cantidad; pagina++ {
+		paginas[pagina] = permisos
+	}
+	mu.Unlock()
+
+	for pagina := desde; pagina < desde+cantidad; pagina++ {
+		log.Printf("PID: %d - Pagina: %d - Permisos: %s", pid, pagina, permisos)
+		invalidarEntradaTLB(pid, pagina) // La CPU guarda los permisos junto con el marco
+	}
+	return nil
+}
+
+// Permisos del proceso sobre la direccion fisica: los de su pagina o los del segmento que la contiene.
+// Devuelve false si la direccion no es de un marco o segmento del proceso
+func permisosDireccion(pid int, address int) (string, bool) {
+	if address < 0 || address >= len(memory) {
+		return "", false
+	}
+	frame := address / pageSize
+	if modoSegmentacion() {
+		for _, segmento := range segmentTable[pid] {
+			if segmentacionPaginada() && slices.Contains(segmento.Paginas, frame) {
+				return segmento.Permisos, true
+			}
+			if !segmentacionPaginada() && segmento.Limite > 0 && address >= segmento.Base && address < segmento.Base+segmento.Limite {
+				return segmento.Permisos, true
+			}
+		}
+		return "", false
+	}
+	if !frameDelProceso(pid, frame) {
+		return "", false
+	}
+	pagina := frameTable[frame].Pagina
+	if frameTable[frame].Pid != pid { // Marco compartido que figura a nombre de otro proceso
+		pagina = paginaDelFrame(pid, frame)
+	}
+	return permisosEfectivos(pid, pagina), true
+}
+
+// Verifica que la direccion sea del proceso y que tenga permiso para el acceso (R, W o X)
+func verificarAcceso(pid int, address int, acceso string) error {
+	permisos, ok := permisosDireccion(pid, address)
+	if !ok {
+		return fmt.Errorf("PID %d cannot access address %d", pid, address)
+	}
+	if !tienePermiso(permisos, acceso) {
+		return fmt.Errorf("PID %d - acceso %s denegado en la direccion %d (permisos %s)", pid, acceso, address, permisos)
+	}
+	return nil
+}
+
+// Los programas de texto no estan en la memoria del proceso, el permiso de ejecucion es el del segmento
+// de codigo (con segmentacion). Los binarios se leen de sus paginas con acceso X, no por este camino
+func verificarFetch(pid int) error {
+	if _, binario := programasBinarios[pid]; binario {
+		return fmt.Errorf("el PID %d tiene un programa binario, el fetch se hace leyendo su codigo", pid)
+	}
+	if segmentos, exists := segmentTable[pid]; exists && !tienePermiso(segmentos[SegmentoCodigo].Permisos, "X") {
+		return fmt.Errorf("el segmento de codigo del PID %d no tiene permiso de ejecucion (%s)", pid, segmentos[SegmentoCodigo].Permisos)
+	}
+	return nil
+}
+
+type BodyProteccionSegmento struct {
+	Pid      int    `json:"pid"`
+	Segmento int    `json:"segment"`
+	Permisos string `json:"permisos"`
+}
+
+// POST /protectSegment: con segmentacion los permisos son de cada segmento
+func ProtectSegmentHandler(w http.ResponseWriter, r *http.Request) {
+	var body BodyProteccionSegmento
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ProtegerSegmento(body.Pid, body.Segmento, body.Permisos); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func ProtegerSegmento(pid int, numSegmento int, permisos string) error {
+	permisos = strings.ToUpper(permisos)
+	if strings.Trim(permisos, "RWX") != "" {
+		return fmt.Errorf("permisos invalidos: %s", permisos)
+	}
+	if !modoSegmentacion() {
+		return fmt.Errorf("los permisos por segmento solo existen con segmentacion")
+	}
+
+	mu.Lock()
+	segmentos, exists := segmentTable[pid]
+	if !exists {
+		mu.Unlock()
+		return fmt.Errorf("Process with PID %d not found", pid)
+	}
+	if numSegmento < 0 || numSegmento >= len(segmentos) {
+		mu.Unlock()
+		return fmt.Errorf("PID %d no tiene el segmento %d", pid, numSegmento)
+	}
+	segmentos[numSegmento].Permisos = permisos
+	nombre := segmentos[numSegmento].Nombre
+	mu.Unlock()
+
+	log.Printf("PID: %d - Segmento: %s - Permisos: %s", pid, nombre, permisos)
+	if numSegmento == SegmentoCodigo {
+		avisarRecargaPrograma(pid) // La CPU puede tener instrucciones del segmento en el cache de prefetch
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"log"
+	"slices"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// Puntero de los algoritmos CLOCK y CLOCK_M, recorre la tabla de marcos de forma circular
+var punteroClock int
+
+// Actualiza los bits del marco en cada lectura/escritura del espacio de usuario
+func marcarAcceso(frame int, escritura bool) {
+	if frame < 0 || frame >= len(frameTable) || frameTable[frame].Pid == -1 {
+		return
+	}
+	contadorAccesos++
+	frameTable[frame].UltimoUso = contadorAccesos
+	frameTable[frame].Referenciado = true
+	if escritura {
+		frameTable[frame].Modificado = true
+	}
+}
+
+// Marcos que pueden ser victima segun el alcance configurado, sin los excluidos (el marco que se esta
+// copiando por copy-on-write) ni los que esta usando una carga desde swap. Un marco compartido es candidato de todos los procesos que lo usan.
+// Con pools de marcos el reemplazo siempre es local, el marco tiene que quedar en el pool del proceso
+func marcosCandidatos(pid int, excluidos []int) []int {
+	var candidatos []int
+	if globals.ClientConfig.Alcance == "LOCAL" || asignacionPorPool() {
+		for frame, info := range frameTable {
+			if info.Pid != -1 && !marcosEnTransito[frame] && frameDelProceso(pid, frame) && !slices.Contains(excluidos, frame) {
+				candidatos = append(candidatos, frame)
+			}
+		}
+		if len(candidatos) > 0 || asignacionPorPool() {
+			return candidatos
+		}
+		log.Printf("PID: %d - Sin marcos propios para reemplazo local, se usa alcance global", pid)
+	}
+	for frame, info := range frameTable {
+		if info.Pid != -1 && !marcosEnTransito[frame] && !slices.Contains(excluidos, frame) {
+			candidatos = append(candidatos, frame)
+		}
+	}
+	return candidatos
+}
+
+func elegirVictima(pid int, excluidos ...int) int {
+	candidatos := marcosCandidatos(pid, excluidos)
+	if len(candidatos) == 0 {
+		return -1
+	}
+
+	switch globals.ClientConfig.Algoritmo {
+	case "LRU":
+		return victimaLRU(candidatos)
+	case "CLOCK":
+		return victimaClock(candidatos)
+	case "CLOCK_M":
+		return victimaClockMejorado(candidatos)
+	default:
+		return victimaFIFO(candidatos)
+	}
+}
+
+func victimaFIFO(candidatos []int) int {
+	victima := candidatos[0]
+	for _, frame := range candidatos {
+		if frameTable[frame].Cargado < frameTable[victima].Cargado {
+			victima = frame
+		}
+	}
+	return victima
+}
+
+func victimaLRU(candidatos []int) int {
+	victima := candidatos[0]
+	for _, frame := range candidatos {
+		if frameTable[frame].UltimoUso < frameTable[victima].UltimoUso {
+			victima = frame
+		}
+	}
+	return victima
+}
+
+// Ordena los candidatos empezando desde el puntero del clock
+func recorridoClock(candidatos []int) []int {
+	inicio := 0
+	for i, frame := range candidatos {
+		if frame >= punteroClock {
+			inicio = i
+			break
+		}
+	}
+	return append(append([]int{}, candidatos[inicio:]...), candidatos[:inicio]...)
+}
+
+func avanzarPuntero(frame int) {
+	punteroClock = (frame + 1) % len(frameTable)
+}
+
+func victimaClock(candidatos []int) int {
+	recorrido := recorridoClock(candidatos)
+	for { // En la segunda vuelta ya estan todos los bits de uso en false
+		for _, frame := range recorrido {
+			if !frameTable[frame].Referenciado {
+				avanzarPuntero(frame)
+				return frame
+			}
+			frameTable[frame].Referenciado = false
+		}
+	}
+}
+
+func victimaClockMejorado(candidatos []int) int {
+	recorrido := recorridoClock(candidatos)
+	for {
+		// Primera pasada: (u=0, m=0) sin tocar los bits
+		for _, frame := range recorrido {
+			if !frameTable[frame].Referenciado && !frameTable[frame].Modificado {
+				avanzarPuntero(frame)
+				return frame
+			}
+		}
+		// Segunda pasada: (u=0, m=1) poniendo en 0 los bits de uso
+		for _, frame := range recorrido {
+			if !frameTable[frame].Referenciado && frameTable[frame].Modificado {
+				avanzarPuntero(frame)
+				return frame
+			}
+			frameTable[frame].Referenciado = false
+		}
+	}
+}
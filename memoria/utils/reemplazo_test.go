@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// Pedidos que recibio la CPU de prueba, "ruta?consulta"
+type cpuDePrueba struct {
+	sync.Mutex
+	pedidos []string
+	cuerpos [][]byte         // Cuerpo de cada /invalidateFrames
+	sucias  []BodyLineaSucia // Lo que responde /invalidateFrames
+}
+
+func (c *cpuDePrueba) recibidos() []string {
+	c.Lock()
+	defer c.Unlock()
+	return append([]string{}, c.pedidos...)
+}
+
+// Deja la memoria vacia con la configuracion pedida y la restaura al terminar el test. Los avisos a la
+// CPU (TLB, cache de datos, programas) y al kernel van a un servidor de prueba que los anota
+func configurarMemoria(t *testing.T, config globals.Config, marcos int) *cpuDePrueba {
+	t.Helper()
+	anterior := globals.ClientConfig
+	t.Cleanup(func() { globals.ClientConfig = anterior })
+
+	cpu := &cpuDePrueba{}
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cpu.Lock()
+		defer cpu.Unlock()
+		cpu.pedidos = append(cpu.pedidos, strings.TrimPrefix(r.URL.Path, "/")+"?"+r.URL.RawQuery)
+		if r.URL.Path == "/invalidateFrames" {
+			cuerpo, _ := io.ReadAll(r.Body)
+			cpu.cuerpos = append(cpu.cuerpos, cuerpo)
+			json.NewEncoder(w).Encode(append([]BodyLineaSucia{}, cpu.sucias...))
+			cpu.sucias = nil
+		}
+	}))
+	t.Cleanup(servidor.Close)
+	url, _ := neturl.Parse(servidor.URL)
+	config.IpCPU = url.Hostname()
+	config.PuertoCPU, _ = strconv.Atoi(url.Port())
+	config.IpKernel, config.PuertoKernel = config.IpCPU, config.PuertoCPU
+
+	if config.PageSize == 0 {
+		config.PageSize = 16
+	}
+	config.MemorySize = config.PageSize * marcos
+	if config.SwapSize > 0 {
+		config.SwapPath = filepath.Join(t.TempDir(), "swap.bin")
+	}
+	globals.ClientConfig = &config
+	pageSize, memorySize = config.PageSize, config.MemorySize
+	memory = make([]byte, memorySize)
+	memoryMap = make([]int, marcos)
+	iniciarFrameTable(marcos)
+	pageTable = make(map[int][]int)
+	tablasMultinivel = make(map[int]*TablasProceso)
+	segmentTable = make(map[int][]Segmento)
+	pageProtection = make(map[int][]string)
+	mapInstructions = make(map[int][][]string)
+	etiquetas = make(map[int]map[string]int)
+	programasBinarios = make(map[int]int)
+	heaps = make(map[int]*Heap)
+	framePools = make(map[int][]int)
+	ultimoFrame = make(map[int]int)
+	generadorFrames = nil
+	esperandoMemoria = false
+	punteroClock, contadorCargas, contadorAccesos = 0, 0, 0
+	memoriaVirtual, swapFile, swapMap, swapTable = false, nil, nil, make(map[int][]int)
+	paginasEnTransito = make(map[paginaProceso]chan struct{})
+	marcosEnTransito, slotsEnTransito = make(map[int]bool), make(map[int]bool)
+	if config.SwapSize > 0 {
+		IniciarSwap()
+		t.Cleanup(func() { swapFile.Close() })
+	}
+	return cpu
+}
+
+// Bits de uso y modificado de un marco para CLOCK y CLOCK_M
+type bits struct{ u, m bool }
+
+func TestElegirVictima(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		algoritmo string
+		cargas    []int  // Orden en que se cargan los marcos 0 a 3 del PID 1
+		accesos   []int  // Marcos que se leen despues de cargar
+		bits      []bits // Si esta, pisa los bits de uso y modificado
+		puntero   int
+		victima   int
+		siguiente int // Donde queda el puntero del clock
+	}{
+		{"FIFO", "FIFO", []int{2, 0, 3, 1}, []int{2}, nil, 0, 2, 0},
+		{"FIFO por defecto", "", []int{1, 3, 0, 2}, nil, nil, 0, 1, 0},
+		{"LRU", "LRU", []int{0, 1, 2, 3}, []int{0, 1, 3}, nil, 0, 2, 0},
+		{"LRU con todos accedidos", "LRU", []int{0, 1, 2, 3}, []int{3, 2, 1, 0}, nil, 0, 3, 0},
+		{"CLOCK todos usados", "CLOCK", []int{0, 1, 2, 3}, nil, nil, 0, 0, 1},
+		{"CLOCK desde el puntero", "CLOCK", []int{0, 1, 2, 3}, nil, []bits{{}, {u: true}, {u: true}, {}}, 1, 3, 0},
+		{"CLOCK da la vuelta", "CLOCK", []int{0, 1, 2, 3}, nil, []bits{{}, {u: true}, {u: true}, {u: true}}, 2, 0, 1},
+		{"CLOCK_M prefiere no modificado", "CLOCK_M", []int{0, 1, 2, 3}, nil, []bits{{u: true, m: true}, {m: true}, {}, {u: true}}, 0, 2, 3},
+		{"CLOCK_M segunda pasada", "CLOCK_M", []int{0, 1, 2, 3}, nil, []bits{{u: true}, {m: true}, {u: true, m: true}, {m: true}}, 0, 1, 2},
+		{"CLOCK_M todos usados y modificados", "CLOCK_M", []int{0, 1, 2, 3}, nil, []bits{{true, true}, {true, true}, {true, true}, {true, true}}, 1, 1, 2},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			configurarMemoria(t, globals.Config{Algoritmo: caso.algoritmo}, 4)
+			for _, frame := range caso.cargas {
+				asignarFrame(frame, 1, frame)
+			}
+			for _, frame := range caso.accesos {
+				marcarAcceso(frame, false)
+			}
+			for frame, b := range caso.bits {
+				frameTable[frame].Referenciado, frameTable[frame].Modificado = b.u, b.m
+			}
+			punteroClock = caso.puntero
+
+			if victima := elegirVictima(1); victima != caso.victima {
+				t.Errorf("victima = %d, se esperaba %d", victima, caso.victima)
+			}
+			if punteroClock != caso.siguiente {
+				t.Errorf("puntero = %d, se esperaba %d", punteroClock, caso.siguiente)
+			}
+		})
+	}
+}
+
+func TestVictimaSegunAlcance(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		alcance   string
+		pid       int
+		excluidos []int
+		victima   int
+	}{
+		{"global elige la mas vieja", "GLOBAL", 1, nil, 2},
+		{"sin alcance es global", "", 1, nil, 2},
+		{"local elige entre las del proceso", "LOCAL", 1, nil, 0},
+		{"local de otro proceso", "LOCAL", 2, nil, 2},
+		{"local sin marcos propios usa global", "LOCAL", 3, nil, 2},
+		{"excluye el marco que se copia", "LOCAL", 1, []int{0}, 1},
+		{"global con excluidos", "GLOBAL", 2, []int{2, 3}, 0},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			configurarMemoria(t, globals.Config{Algoritmo: "FIFO", Alcance: caso.alcance}, 4)
+			// El PID 2 carga primero los marcos 2 y 3, despues el PID 1 los marcos 0 y 1
+			asignarFrame(2, 2, 0)
+			asignarFrame(3, 2, 1)
+			asignarFrame(0, 1, 0)
+			asignarFrame(1, 1, 1)
+			if victima := elegirVictima(caso.pid, caso.excluidos...); victima != caso.victima {
+				t.Errorf("victima = %d, se esperaba %d", victima, caso.victima)
+			}
+		})
+	}
+}
+
+func TestSinCandidatos(t *testing.T) {
+	configurarMemoria(t, globals.Config{Algoritmo: "LRU"}, 2)
+	if victima := elegirVictima(1); victima != -1 {
+		t.Errorf("con la memoria vacia la victima tendria que ser -1 y es %d", victima)
+	}
+	asignarFrame(0, 1, 0)
+	if victima := elegirVictima(1, 0); victima != -1 {
+		t.Errorf("con el unico marco excluido la victima tendria que ser -1 y es %d", victima)
+	}
+}
+
+func TestMarcarAcceso(t *testing.T) {
+	configurarMemoria(t, globals.Config{}, 2)
+	asignarFrame(0, 1, 0)
+	frameTable[0].Referenciado = false
+	marcarAcceso(0, true)
+	marcarAcceso(1, true) // Libre: no se marca
+	marcarAcceso(5, true) // Fuera de la tabla
+	if !frameTable[0].Referenciado || !frameTable[0].Modificado || frameTable[0].UltimoUso != 2 {
+		t.Errorf("marco 0 = %+v", frameTable[0])
+	}
+	if frameTable[1].Referenciado || frameTable[1].Modificado {
+		t.Errorf("el marco libre se marco: %+v", frameTable[1])
+	}
+}
@@ -0,0 +1,311 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// Segmentos que tiene cada proceso, el numero de segmento es la posicion en la tabla
+const (
+	SegmentoCodigo = iota
+	SegmentoDatos
+	SegmentoPila
+)
+
+type Segmento struct {
+	Nombre   string `json:"nombre"`
+	Base     int    `json:"base"`     // Direccion fisica de inicio (solo segmentacion pura)
+	Limite   int    `json:"limite"`   // Tamaño del segmento en bytes
+	Permisos string `json:"permisos"` // R, W y X
+	Paginas  []int  `json:"paginas"`  // Marcos del segmento (solo segmentacion paginada)
+}
+
+type Hueco struct {
+	Base   int
+	Tamaño int
+}
+
+type BodySegmentRequest struct {
+	Pid      int    `json:"pid"`
+	Segmento int    `json:"segment"`
+	Offset   int    `json:"offset"`
+	Size     int    `json:"size"`
+	Acceso   string `json:"access"` // R, W o X (fetch de programas binarios)
+}
+
+type BodySegmentResponse struct {
+	Direcciones []int  `json:"physical_addresses"`
+	SegFault    bool   `json:"segfault"`
+	Motivo      string `json:"motivo,omitempty"`
+}
+
+// Tabla de segmentos de cada proceso
+var segmentTable = make(map[int][]Segmento)
+
+func modoSegmentacion() bool {
+	return globals.ClientConfig.ModoMemoria == "SEGMENTACION" || globals.ClientConfig.ModoMemoria == "SEGMENTACION_PAGINADA"
+}
+
+func segmentacionPaginada() bool {
+	return globals.ClientConfig.ModoMemoria == "SEGMENTACION_PAGINADA"
+}
+
+func procesoExiste(pid int) bool {
+	if modoSegmentacion() {
+		_, exists := segmentTable[pid]
+		return exists
+	}
+	return tieneTablaPaginas(pid)
+}
+
+func crearSegmentos(pid int) error {
+	if _, exists := segmentTable[pid]; exists {
+		return fmt.Errorf("PID %d already has segments assigned", pid)
+	}
+	segmentTable[pid] = []Segmento{
+		SegmentoCodigo: {Nombre: "CODIGO", Permisos: "RX", Base: -1},
+		SegmentoDatos:  {Nombre: "DATOS", Permisos: "RW", Base: -1},
+		SegmentoPila:   {Nombre: "PILA", Permisos: "RW", Base: -1},
+	}
+	return redimensionarSegmento(pid, SegmentoPila, globals.ClientConfig.TamPila)
+}
+
+func liberarSegmentos(pid int) {
+	for _, segmento := range segmentTable[pid] {
+		invalidarSegmentoCPU(segmento, 0)
+		for _, frame := range segmento.Paginas {
+			liberarFrame(frame)
+		}
+	}
+	delete(segmentTable, pid)
+	liberarPool(pid)
+}
+
+// Cambia el tamaño del segmento. Si no hay espacio suficiente devuelve error sin modificar nada
+func redimensionarSegmento(pid int, numSegmento int, nuevoTam int) error {
+	segmento := &segmentTable[pid][numSegmento]
+	if nuevoTam > globals.ClientConfig.TamMaxSegmento {
+		return fmt.Errorf("el segmento %s no puede superar %d bytes", segmento.Nombre, globals.ClientConfig.TamMaxSegmento)
+	}
+
+	if segmentacionPaginada() {
+		paginas := (nuevoTam + pageSize - 1) / pageSize
+		if paginas-len(segmento.Paginas) > framesDisponibles(pid) {
+			return fmt.Errorf("no hay marcos libres suficientes")
+		}
+		for len(segmento.Paginas) < paginas {
+			frame := proximoLugarLibre(pid)
+			asignarFrame(frame, pid, paginaLogica(numSegmento, len(segmento.Paginas)))
+			segmento.Paginas = append(segmento.Paginas, frame)
+		}
+		invalidarSegmentoCPU(*segmento, paginas*pageSize)
+		for _, frame := range segmento.Paginas[paginas:] {
+			liberarFrame(frame)
+		}
+		segmento.Paginas = segmento.Paginas[:paginas]
+		segmento.Limite = nuevoTam
+		log.Printf("PID: %d - Segmento: %s - Paginas: %d - Tamaño: %d", pid, segmento.Nombre, paginas, nuevoTam)
+		return nil
+	}
+
+	if nuevoTam <= segmento.Limite { // Achicar no mueve el segmento
+		invalidarSegmentoCPU(*segmento, nuevoTam)
+		segmento.Limite = nuevoTam
+		log.Printf("PID: %d - Segmento: %s - Base: %d - Tamaño: %d", pid, segmento.Nombre, segmento.Base, nuevoTam)
+		return nil
+	}
+	if nuevoTam-segmento.Limite > espacioLibreSegmentacion() {
+		return fmt.Errorf("no hay espacio libre suficiente")
+	}
+
+	// Se saca el segmento de memoria para buscarle un hueco nuevo, conservando su contenido
+	invalidarSegmentoCPU(*segmento, 0)
+	contenido := make([]byte, segmento.Limite)
+	if segmento.Limite > 0 {
+		copy(contenido, memory[segmento.Base:segmento.Base+segmento.Limite])
+	}
+	segmento.Limite = 0
+
+	base := buscarHueco(nuevoTam)
+	if base == -1 {
+		compactar()
+		base = buscarHueco(nuevoTam)
+	}
+	segmento.Base = base
+	segmento.Limite = nuevoTam
+	copy(memory[base:], contenido)
+	log.Printf("PID: %d - Segmento: %s - Base: %d - Tamaño: %d", pid, segmento.Nombre, base, nuevoTam)
+	return nil
+}
+
+// La cache de datos de la CPU esta indexada por direccion fisica: antes de mover o liberar los bytes del
+// segmento desde el offset, sus lineas sucias tienen que llegar a memoria y las demas no pueden quedar
+func invalidarSegmentoCPU(segmento Segmento, desde int) {
+	if segmentacionPaginada() {
+		invalidarCacheCPU(segmento.Paginas[min(desde/pageSize, len(segmento.Paginas)):]...)
+		return
+	}
+	if segmento.Limite <= desde || segmento.Base < 0 {
+		return
+	}
+	var marcos []int
+	for frame := (segmento.Base + desde) / pageSize; frame*pageSize < segmento.Base+segmento.Limite; frame++ {
+		marcos = append(marcos, frame)
+	}
+	invalidarCacheCPU(marcos...)
+}
+
+// Numero de pagina dentro del espacio logico del proceso, para la tabla de marcos
+func paginaLogica(numSegmento int, pagina int) int {
+	return (numSegmento*globals.ClientConfig.TamMaxSegmento)/pageSize + pagina
+}
+
+// Segmentos de todos los procesos que ocupan memoria, ordenados por base
+func segmentosOcupados() []*Segmento {
+	var ocupados []*Segmento
+	for pid := range segmentTable {
+		for i := range segmentTable[pid] {
+			if segmentTable[pid][i].Limite > 0 {
+				ocupados = append(ocupados, &segmentTable[pid][i])
+			}
+		}
+	}
+	sort.Slice(ocupados, func(i, j int) bool { return ocupados[i].Base < ocupados[j].Base })
+	return ocupados
+}
+
+func huecosLibres() []Hueco {
+	var huecos []Hueco
+	siguiente := 0
+	for _, segmento := range segmentosOcupados() {
+		if segmento.Base > siguiente {
+			huecos = append(huecos, Hueco{Base: siguiente, Tamaño: segmento.Base - siguiente})
+		}
+		siguiente = segmento.Base + segmento.Limite
+	}
+	if siguiente < memorySize {
+		huecos = append(huecos, Hueco{Base: siguiente, Tamaño: memorySize - siguiente})
+	}
+	return huecos
+}
+
+func espacioLibreSegmentacion() int {
+	var libre int
+	for _, hueco := range huecosLibres() {
+		libre += hueco.Tamaño
+	}
+	return libre
+}
+
+// Busca un hueco segun el algoritmo configurado, devuelve -1 si ninguno alcanza
+func buscarHueco(tam int) int {
+	var elegido *Hueco
+	for _, hueco := range huecosLibres() {
+		if hueco.Tamaño < tam {
+			continue
+		}
+		switch globals.ClientConfig.AlgoritmoSegmentos {
+		case "BEST":
+			if elegido == nil || hueco.Tamaño < elegido.Tamaño {
+				elegido = &hueco
+			}
+		case "WORST":
+			if elegido == nil || hueco.Tamaño > elegido.Tamaño {
+				elegido = &hueco
+			}
+		default: // FIRST
+			return hueco.Base
+		}
+	}
+	if elegido == nil {
+		return -1
+	}
+	return elegido.Base
+}
+
+// Mueve todos los segmentos al principio de la memoria para dejar un unico hueco al final
+func compactar() {
+	log.Printf("Inicio de compactación")
+	time.Sleep(time.Duration(globals.ClientConfig.RetardoCompactacion) * time.Millisecond)
+	invalidarCacheCPU(todosLosMarcos()...) // Los bytes de los segmentos cambian de direccion fisica
+	siguiente := 0
+	for _, segmento := range segmentosOcupados() {
+		if segmento.Base != siguiente {
+			copy(memory[siguiente:], memory[segmento.Base:segmento.Base+segmento.Limite])
+			segmento.Base = siguiente
+		}
+		siguiente += segmento.Limite
+	}
+	for pid, segmentos := range segmentTable {
+		for _, segmento := range segmentos {
+			if segmento.Limite == 0 {
+				continue
+			}
+			log.Printf("PID: %d - Segmento: %s - Base: %d - Tamaño: %d", pid, segmento.Nombre, segmento.Base, segmento.Limite)
+		}
+	}
+}
+
+func GetSegmentFromCPU(w http.ResponseWriter, r *http.Request) {
+	var req BodySegmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mu.Lock()
+	response := traducirSegmento(req)
+	mu.Unlock()
+
+	if response.SegFault {
+		log.Printf("PID: %d - Segmento: %d - Offset: %d - Tamaño: %d - Segmentation Fault: %s", req.Pid, req.Segmento, req.Offset, req.Size, response.Motivo)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func traducirSegmento(req BodySegmentRequest) BodySegmentResponse {
+	segmentos, exists := segmentTable[req.Pid]
+	if !exists {
+		return BodySegmentResponse{SegFault: true, Motivo: "proceso inexistente"}
+	}
+	if req.Segmento < 0 || req.Segmento >= len(segmentos) {
+		return BodySegmentResponse{SegFault: true, Motivo: "segmento inexistente"}
+	}
+	segmento := segmentos[req.Segmento]
+	if req.Offset < 0 || req.Offset+req.Size > segmento.Limite {
+		return BodySegmentResponse{SegFault: true, Motivo: fmt.Sprintf("limite %d excedido", segmento.Limite)}
+	}
+	if !tienePermiso(segmento.Permisos, req.Acceso) {
+		return BodySegmentResponse{SegFault: true, Motivo: fmt.Sprintf("acceso %s en segmento %s", req.Acceso, segmento.Permisos)}
+	}
+
+	var direcciones []int
+	for offset := req.Offset; offset < req.Offset+req.Size; offset++ {
+		if segmentacionPaginada() {
+			frame := segmento.Paginas[offset/pageSize]
+			direcciones = append(direcciones, frame*pageSize+offset%pageSize)
+		} else {
+			direcciones = append(direcciones, segmento.Base+offset)
+		}
+	}
+	return BodySegmentResponse{Direcciones: direcciones}
+}
+
+func tienePermiso(permisos string, acceso string) bool {
+	if acceso == "" {
+		acceso = "R"
+	}
+	for _, permiso := range permisos {
+		if string(permiso) == acceso {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+func TestCompactarTraeLineasSucias(t *testing.T) {
+	cpu := configurarMemoria(t, globals.Config{ModoMemoria: "SEGMENTACION", TamMaxSegmento: 64}, 4)
+	segmentTable[1] = []Segmento{{Nombre: "DATOS", Permisos: "RW", Base: 40, Limite: 8}}
+	copy(memory[40:], "datosvie")
+	// La CPU tiene los dos primeros bytes del segmento sin escribir en memoria
+	cpu.sucias = []BodyLineaSucia{{Direccion: 40, Datos: []byte("DA")}}
+
+	compactar()
+	segmento := segmentTable[1][0]
+	if segmento.Base != 0 || string(memory[:8]) != "DAtosvie" {
+		t.Errorf("segmento en %d con %q, se esperaba en 0 con %q", segmento.Base, memory[:8], "DAtosvie")
+	}
+}
+
+// Marcos que memoria le pidio invalidar a la CPU, en orden
+func marcosInvalidados(t *testing.T, cpu *cpuDePrueba) []int {
+	t.Helper()
+	cpu.Lock()
+	defer cpu.Unlock()
+	var marcos []int
+	for _, cuerpo := range cpu.cuerpos {
+		var body BodyMarcos
+		json.Unmarshal(cuerpo, &body)
+		marcos = append(marcos, body.Marcos...)
+	}
+	return marcos
+}
+
+func TestSegmentosInvalidanCache(t *testing.T) {
+	casos := []struct {
+		nombre string
+		modo   string
+		accion func()
+		marcos []int
+	}{
+		{"achicar sin mover", "SEGMENTACION", func() { redimensionarSegmento(1, 0, 14) }, []int{2}},
+		{"agrandar mueve el segmento", "SEGMENTACION", func() { redimensionarSegmento(1, 0, 20) }, []int{1, 2}},
+		{"liberar", "SEGMENTACION", func() { liberarSegmentos(1) }, []int{1, 2}},
+		{"achicar paginada", "SEGMENTACION_PAGINADA", func() { redimensionarSegmento(1, 0, 10) }, []int{3}},
+		{"liberar paginada", "SEGMENTACION_PAGINADA", func() { liberarSegmentos(1) }, []int{2, 3}},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			cpu := configurarMemoria(t, globals.Config{ModoMemoria: caso.modo, TamMaxSegmento: 64}, 4)
+			if caso.modo == "SEGMENTACION" {
+				// Bytes 20 a 35: marcos 1 y 2
+				segmentTable[1] = []Segmento{{Nombre: "DATOS", Permisos: "RW", Base: 20, Limite: 16}}
+				segmentTable[2] = []Segmento{{Nombre: "DATOS", Permisos: "RW", Base: 36, Limite: 28}}
+			} else {
+				asignarFrame(2, 1, 0)
+				asignarFrame(3, 1, 1)
+				segmentTable[1] = []Segmento{{Nombre: "DATOS", Permisos: "RW", Base: -1, Limite: 20, Paginas: []int{2, 3}}}
+			}
+			caso.accion()
+			marcos := marcosInvalidados(t, cpu)
+			slices.Sort(marcos)
+			if !slices.Equal(slices.Compact(marcos), caso.marcos) {
+				t.Errorf("marcos invalidados %v, se esperaba %v", marcos, caso.marcos)
+			}
+		})
+	}
+}
@@ -0,0 +1,326 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// Cabecera del archivo de snapshot: magic + version (uint16 big endian) y despues el contenido en gob
+const magicSnapshot = "TPSOMEM\x00"
+
+// Version 2: MemoryMap con las referencias de cada marco (copy-on-write) y los heaps de los procesos
+// Version 3: configuracion de la memoria que lo guardo (swap, modo y tablas) para rechazar restores incompatibles
+const versionSnapshot uint16 = 3
+
+// Estado completo de memoria. Las tablas de paginas se guardan planas (el marco de cada pagina),
+// con tablas multinivel se arman de nuevo al restaurar
+type Snapshot struct {
+	PageSize        int
+	MemorySize      int
+	Memory          []byte
+	MemoryMap       []int
+	PageTable       map[int][]int
+	MapInstructions map[int][][]string
+	Etiquetas       map[int]map[string]int
+	Binarios        map[int]int // Tamaño del codigo de los programas binarios
+	FrameTable      []FrameInfo
+	SwapMap         []bool
+	SwapTable       map[int][]int
+	Swap            []byte // Contenido del archivo de swap
+	PageProtection  map[int][]string
+	SegmentTable    map[int][]Segmento
+	ContadorCargas  int
+	ContadorAccesos int
+	PunteroClock    int
+	FramePools      map[int][]int
+	Heaps           map[int]*Heap
+	MemoriaVirtual  bool
+	ModoMemoria     string
+	NivelesTablas   int
+	EntradasTabla   int
+	TamMaxSegmento  int
+}
+
+// Con ?path= solo se puede elegir el nombre del archivo dentro de snapshot_dir, sin directorios
+func rutaSnapshot(r *http.Request) (string, error) {
+	nombre := r.URL.Query().Get("path")
+	if nombre == "" {
+		if globals.ClientConfig.SnapshotPath != "" {
+			return globals.ClientConfig.SnapshotPath, nil
+		}
+		return "memoria.snapshot", nil
+	}
+	if globals.ClientConfig.SnapshotDir == "" {
+		return "", fmt.Errorf("no hay snapshot_dir configurado, solo se puede usar snapshot_path")
+	}
+	if strings.ContainsAny(nombre, `/\`) || nombre == "." || nombre == ".." {
+		return "", fmt.Errorf("nombre de snapshot invalido: %s", nombre)
+	}
+	return filepath.Join(globals.ClientConfig.SnapshotDir, nombre), nil
+}
+
+// POST /memory/snapshot?path=
+func SnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	path, err := rutaSnapshot(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := GuardarSnapshot(path); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(path))
+}
+
+// POST /memory/restore?path=
+func RestoreHandler(w http.ResponseWriter, r *http.Request) {
+	path, err := rutaSnapshot(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := CargarSnapshot(path); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func GuardarSnapshot(path string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Las lineas sucias de la cache de datos de la CPU tienen que estar en memory antes de copiarla
+	invalidarCacheCPU(todosLosMarcos()...)
+	config := globals.ClientConfig
+	snapshot := Snapshot{
+		PageSize:        pageSize,
+		MemorySize:      memorySize,
+		Memory:          memory,
+		MemoryMap:       memoryMap,
+		PageTable:       tablasPlanas(),
+		MapInstructions: mapInstructions,
+		Etiquetas:       etiquetas,
+		Binarios:        programasBinarios,
+		FrameTable:      frameTable,
+		SwapMap:         swapMap,
+		SwapTable:       swapTable,
+		PageProtection:  pageProtection,
+		SegmentTable:    segmentTable,
+		ContadorCargas:  contadorCargas,
+		ContadorAccesos: contadorAccesos,
+		PunteroClock:    punteroClock,
+		FramePools:      framePools,
+		Heaps:           heaps,
+		MemoriaVirtual:  memoriaVirtual,
+		ModoMemoria:     config.ModoMemoria,
+		NivelesTablas:   config.NivelesTablas,
+		EntradasTabla:   config.EntradasPorTabla,
+		TamMaxSegmento:  config.TamMaxSegmento,
+	}
+	if memoriaVirtual {
+		snapshot.Swap = make([]byte, len(swapMap)*pageSize)
+		if _, err := swapFile.ReadAt(snapshot.Swap, 0); err != nil && err != io.EOF {
+			return fmt.Errorf("error al leer el archivo de swap: %v", err)
+		}
+	}
+
+	// Se escribe en un temporal para no dejar un snapshot a medias si algo falla
+	temporal := path + ".tmp"
+	file, err := os.Create(temporal)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	writer.WriteString(magicSnapshot)
+	binary.Write(writer, binary.BigEndian, versionSnapshot)
+	if err := gob.NewEncoder(writer).Encode(snapshot); err != nil {
+		file.Close()
+		os.Remove(temporal)
+		return fmt.Errorf("error al serializar el snapshot: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(temporal)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temporal, path); err != nil {
+		return err
+	}
+
+	log.Printf("Snapshot de memoria guardado en %s - Procesos: %d", path, len(snapshot.PageTable)+len(segmentTable))
+	return nil
+}
+
+func CargarSnapshot(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+
+	magic := make([]byte, len(magicSnapshot))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != magicSnapshot {
+		return fmt.Errorf("%s no es un snapshot de memoria", path)
+	}
+	var version uint16
+	if err := binary.Read(reader, binary.BigEndian, &version); err != nil {
+		return err
+	}
+	if version != versionSnapshot {
+		return fmt.Errorf("version de snapshot %d no soportada (se esperaba %d)", version, versionSnapshot)
+	}
+	var snapshot Snapshot
+	if err := gob.NewDecoder(reader).Decode(&snapshot); err != nil {
+		return fmt.Errorf("error al leer el snapshot: %v", err)
+	}
+
+	pids, err := restaurarSnapshot(snapshot)
+	if err != nil {
+		return err
+	}
+	// La TLB y los caches de programas e instrucciones de la CPU pueden tener cosas del estado anterior
+	vaciarTLBCompleta()
+	for _, pid := range pids {
+		avisarRecargaPrograma(pid)
+	}
+
+	log.Printf("Snapshot de memoria restaurado desde %s - Procesos: %d", path, len(snapshot.PageTable)+len(snapshot.SegmentTable))
+	return nil
+}
+
+// Tiene que coincidir con la memoria que esta corriendo, se controla antes de tocar cualquier global
+func validarSnapshot(snapshot Snapshot) error {
+	config := globals.ClientConfig
+	switch {
+	case snapshot.PageSize != pageSize || snapshot.MemorySize != memorySize || len(snapshot.Memory) != memorySize:
+		return fmt.Errorf("el snapshot es de una memoria de %d bytes con paginas de %d", snapshot.MemorySize, snapshot.PageSize)
+	case len(snapshot.MemoryMap) != len(memoryMap) || len(snapshot.FrameTable) != len(frameTable):
+		return fmt.Errorf("el snapshot tiene %d marcos y la memoria %d", len(snapshot.MemoryMap), len(memoryMap))
+	case snapshot.MemoriaVirtual != memoriaVirtual:
+		return fmt.Errorf("el snapshot es de una memoria con swap %v y esta tiene swap %v", snapshot.MemoriaVirtual, memoriaVirtual)
+	case len(snapshot.SwapMap) != len(swapMap) || len(snapshot.Swap) != len(swapMap)*pageSize:
+		return fmt.Errorf("el snapshot tiene %d slots de swap y la memoria %d", len(snapshot.SwapMap), len(swapMap))
+	case snapshot.ModoMemoria != config.ModoMemoria:
+		return fmt.Errorf("el snapshot es del modo %q y la memoria esta en %q", snapshot.ModoMemoria, config.ModoMemoria)
+	case snapshot.NivelesTablas != config.NivelesTablas || snapshot.EntradasTabla != config.EntradasPorTabla:
+		return fmt.Errorf("el snapshot tiene %d niveles de %d entradas y la memoria %d de %d", snapshot.NivelesTablas, snapshot.EntradasTabla, config.NivelesTablas, config.EntradasPorTabla)
+	case snapshot.TamMaxSegmento != config.TamMaxSegmento:
+		return fmt.Errorf("el snapshot tiene segmentos de hasta %d bytes y la memoria de %d", snapshot.TamMaxSegmento, config.TamMaxSegmento)
+	}
+	return nil
+}
+
+func todosLosMarcos() []int {
+	todos := make([]int, len(memoryMap))
+	for frame := range todos {
+		todos[frame] = frame
+	}
+	return todos
+}
+
+// Devuelve los procesos que habia antes y los del snapshot, la CPU tiene que olvidar sus programas
+func restaurarSnapshot(snapshot Snapshot) ([]int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := validarSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+	if memoriaVirtual {
+		if _, err := swapFile.WriteAt(snapshot.Swap, 0); err != nil {
+			return nil, fmt.Errorf("error al escribir el archivo de swap: %v", err)
+		}
+	}
+
+	pids := pidsPaginados()
+	for pid := range segmentTable {
+		pids = append(pids, pid)
+	}
+	for pid := range snapshot.PageTable {
+		pids = append(pids, pid)
+	}
+	for pid := range snapshot.SegmentTable {
+		pids = append(pids, pid)
+	}
+	slices.Sort(pids)
+	pids = slices.Compact(pids)
+
+	invalidarCacheCPU(todosLosMarcos()...) // Las lineas de la cache de datos de la CPU son del estado anterior
+	memory = snapshot.Memory
+	memoryMap = snapshot.MemoryMap
+	pageTable = make(map[int][]int)
+	tablasMultinivel = make(map[int]*TablasProceso)
+	for pid, marcos := range snapshot.PageTable {
+		cargarTablaPaginas(pid, marcos)
+	}
+	mapInstructions = snapshot.MapInstructions
+	if mapInstructions == nil {
+		mapInstructions = make(map[int][][]string)
+	}
+	etiquetas = snapshot.Etiquetas
+	if etiquetas == nil {
+		etiquetas = make(map[int]map[string]int)
+	}
+	programasBinarios = snapshot.Binarios
+	if programasBinarios == nil {
+		programasBinarios = make(map[int]int)
+	}
+	frameTable = snapshot.FrameTable
+	if memoriaVirtual {
+		swapMap = snapshot.SwapMap
+		swapTable = mapNoNulo(snapshot.SwapTable)
+	}
+	pageProtection = snapshot.PageProtection
+	if pageProtection == nil {
+		pageProtection = make(map[int][]string)
+	}
+	segmentTable = snapshot.SegmentTable
+	if segmentTable == nil {
+		segmentTable = make(map[int][]Segmento)
+	}
+	contadorCargas = snapshot.ContadorCargas
+	contadorAccesos = snapshot.ContadorAccesos
+	punteroClock = snapshot.PunteroClock
+	framePools = mapNoNulo(snapshot.FramePools)
+	ultimoFrame = make(map[int]int)
+	heaps = snapshot.Heaps
+	if heaps == nil {
+		heaps = make(map[int]*Heap)
+	}
+	return pids, nil
+}
+
+// Marco de cada pagina de cada proceso, sin importar cuantos niveles de tablas haya
+func tablasPlanas() map[int][]int {
+	tablas := make(map[int][]int)
+	for _, pid := range pidsPaginados() {
+		tablas[pid] = paginasDelProceso(pid)
+	}
+	return tablas
+}
+
+// gob no distingue un map vacio de uno nil
+func mapNoNulo(m map[int][]int) map[int][]int {
+	if m == nil {
+		return make(map[int][]int)
+	}
+	return m
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// PID 1 con la pagina 0 en el marco 0. Con swap la pagina tiene su slot
+func procesoDePrueba(contenido string) {
+	crearTablaPaginas(1)
+	asignarFrame(0, 1, 0)
+	agregarPagina(1, 0)
+	copy(memory, contenido)
+	if memoriaVirtual {
+		swapTable[1] = []int{reservarSlotSwap()}
+	}
+}
+
+func TestSnapshotIdaYVuelta(t *testing.T) {
+	cpu := configurarMemoria(t, globals.Config{SwapSize: 64}, 4)
+	procesoDePrueba("hola")
+	swapFile.WriteAt([]byte("swap"), 0)
+	// La CPU tiene una linea sucia que todavia no llego a memoria
+	cpu.sucias = []BodyLineaSucia{{Direccion: 2, Datos: []byte("LA")}}
+
+	path := filepath.Join(t.TempDir(), "memoria.snapshot")
+	if err := GuardarSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Se pisa todo y se restaura
+	copy(memory, "chau")
+	swapFile.WriteAt([]byte("xxxx"), 0)
+	borrarTablaPaginas(1)
+	crearTablaPaginas(2)
+	if err := CargarSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(memory[:4]) != "hoLA" {
+		t.Errorf("memoria = %q, se esperaba %q con la linea sucia de la CPU", memory[:4], "hoLA")
+	}
+	datos := make([]byte, 4)
+	swapFile.ReadAt(datos, 0)
+	if string(datos) != "swap" {
+		t.Errorf("swap = %q, se esperaba %q", datos, "swap")
+	}
+	if !tieneTablaPaginas(1) || tieneTablaPaginas(2) || marcoDePagina(1, 0) != 0 {
+		t.Errorf("tablas restauradas: %v", tablasPlanas())
+	}
+	if !slices.Equal(swapTable[1], []int{0}) || !swapMap[0] {
+		t.Errorf("swap restaurado: tabla %v, slots %v", swapTable, swapMap)
+	}
+	for _, pedido := range []string{"invalidateProgram?pid=1", "invalidateProgram?pid=2", "tlb?"} {
+		if !slices.Contains(cpu.recibidos(), pedido) {
+			t.Errorf("la CPU no recibio %s: %v", pedido, cpu.recibidos())
+		}
+	}
+}
+
+func TestSnapshotIncompatible(t *testing.T) {
+	conSwap := globals.Config{SwapSize: 64}
+	casos := []struct {
+		nombre   string
+		guardado globals.Config
+		marcos   int
+		actual   globals.Config
+	}{
+		{"sin swap en memoria con swap", globals.Config{}, 4, conSwap},
+		{"con swap en memoria sin swap", conSwap, 4, globals.Config{}},
+		{"otro tamaño de swap", conSwap, 4, globals.Config{SwapSize: 128}},
+		{"otro tamaño de memoria", globals.Config{}, 8, globals.Config{}},
+		{"otro modo", globals.Config{}, 4, globals.Config{ModoMemoria: "SEGMENTACION", TamMaxSegmento: 64}},
+		{"otras tablas", globals.Config{}, 4, globals.Config{NivelesTablas: 2, EntradasPorTabla: 4}},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			configurarMemoria(t, caso.guardado, caso.marcos)
+			procesoDePrueba("viejo")
+			path := filepath.Join(t.TempDir(), "memoria.snapshot")
+			if err := GuardarSnapshot(path); err != nil {
+				t.Fatal(err)
+			}
+
+			cpu := configurarMemoria(t, caso.actual, 4)
+			crearTablaPaginas(3)
+			copy(memory, "nuevo")
+			slots := slices.Clone(swapMap)
+			if err := CargarSnapshot(path); err == nil {
+				t.Fatal("se esperaba error")
+			}
+			if string(memory[:5]) != "nuevo" || !tieneTablaPaginas(3) || tieneTablaPaginas(1) || !slices.Equal(swapMap, slots) {
+				t.Errorf("el restore rechazado cambio la memoria: %q, tablas %v, swap %v", memory[:5], tablasPlanas(), swapMap)
+			}
+			if len(cpu.recibidos()) != 0 {
+				t.Errorf("el restore rechazado le aviso a la CPU: %v", cpu.recibidos())
+			}
+		})
+	}
+}
+
+func TestSnapshotArchivoInvalido(t *testing.T) {
+	configurarMemoria(t, globals.Config{}, 4)
+	dir := t.TempDir()
+	archivos := map[string][]byte{
+		"texto":        []byte("no es un snapshot"),
+		"otra version": append([]byte(magicSnapshot), 0, 1),
+		"cortado":      append([]byte(magicSnapshot), 0, byte(versionSnapshot), 1, 2),
+	}
+	for nombre, contenido := range archivos {
+		path := filepath.Join(dir, nombre)
+		os.WriteFile(path, contenido, 0666)
+		if err := CargarSnapshot(path); err == nil {
+			t.Errorf("%s: se esperaba error", nombre)
+		}
+	}
+}
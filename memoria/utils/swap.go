@@ -0,0 +1,457 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// Estructura de cada marco: que pagina de que proceso esta cargada
+type FrameInfo struct {
+	Pid          int // -1 si el marco esta libre
+	Pagina       int
+	Cargado      int  // Orden en el que se cargo la pagina (para FIFO)
+	UltimoUso    int  // Orden del ultimo acceso (para LRU)
+	Referenciado bool // Bit de uso (para CLOCK y CLOCK_M)
+	Modificado   bool // Bit de modificado, si esta en false no hace falta escribir en swap
+}
+
+/////////////////////////////////////////////////// VARS DE MEMORIA VIRTUAL ///////////////////////////////////////////////////////////////
+
+// Si es false, los marcos se asignan al hacer RESIZE como siempre
+var memoriaVirtual bool
+
+var swapFile *os.File
+
+// Mapa de slots de swap ocupados/libres
+var swapMap []bool
+
+// Slot de swap de cada pagina de cada proceso
+var swapTable = make(map[int][]int)
+
+// Tabla de marcos (inversa de la tabla de paginas)
+var frameTable []FrameInfo
+
+var contadorCargas int
+var contadorAccesos int
+
+// La pagina no se puede cargar nunca: no hay marco libre ni victima. El kernel finaliza el proceso
+var errSinMarcos = errors.New("OUT_OF_MEMORY")
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func iniciarFrameTable(cantidadFrames int) {
+	frameTable = make([]FrameInfo, cantidadFrames)
+	for i := range frameTable {
+		liberarFrame(i)
+	}
+}
+
+func asignarFrame(frame int, pid int, pagina int) {
+	memoryMap[frame] = 1
+	contadorCargas++
+	contadorAccesos++
+	frameTable[frame] = FrameInfo{
+		Pid:          pid,
+		Pagina:       pagina,
+		Cargado:      contadorCargas,
+		UltimoUso:    contadorAccesos,
+		Referenciado: true,
+	}
+}
+
+func liberarFrame(frame int) {
+	memoryMap[frame] = 0
+	frameTable[frame] = FrameInfo{Pid: -1, Pagina: -1}
+}
+
+func IniciarSwap() {
+	if globals.ClientConfig.SwapPath == "" {
+		// Sin swap no hay a donde desalojar: una config con reemplazo y sin swap terminaria igual en OUT_OF_MEMORY
+		if globals.ClientConfig.Algoritmo != "" || globals.ClientConfig.Alcance != "" {
+			log.Fatalf("replacement_algorithm y replacement_scope necesitan swap_path")
+		}
+		return
+	}
+	if globals.ClientConfig.SwapSize < pageSize {
+		log.Fatalf("swap_size tiene que alcanzar al menos para una pagina (%d bytes)", pageSize)
+	}
+	file, err := os.OpenFile(globals.ClientConfig.SwapPath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0666)
+	if err != nil {
+		log.Fatalf("Error al crear el archivo de swap: %v", err)
+	}
+	if err := file.Truncate(int64(globals.ClientConfig.SwapSize)); err != nil {
+		log.Fatalf("Error al dimensionar el archivo de swap: %v", err)
+	}
+	swapFile = file
+	swapMap = make([]bool, globals.ClientConfig.SwapSize/pageSize)
+	memoriaVirtual = true
+	log.Printf("Memoria virtual activada - Swap: %s - Slots: %d", globals.ClientConfig.SwapPath, len(swapMap))
+}
+
+func counterSwapFree() int {
+	var contador int
+	for _, ocupado := range swapMap {
+		if !ocupado {
+			contador++
+		}
+	}
+	return contador
+}
+
+// Reserva un slot de swap y lo deja en cero, asi la pagina arranca vacia
+func reservarSlotSwap() int {
+	for i, ocupado := range swapMap {
+		if !ocupado {
+			swapMap[i] = true
+			swapFile.WriteAt(make([]byte, pageSize), int64(i*pageSize))
+			return i
+		}
+	}
+	return -1
+}
+
+func liberarPaginaVirtual(pid int, pagina int) {
+	if frame := marcoDePagina(pid, pagina); frame != -1 {
+		soltarFrame(frame, pid)
+	}
+	if slots, exists := swapTable[pid]; exists && pagina < len(slots) {
+		liberarSlotSwap(slots[pagina])
+	}
+}
+
+func escribirEnSwap(slot int, frame int) error {
+	time.Sleep(time.Duration(globals.ClientConfig.SwapDelay) * time.Millisecond)
+	_, err := swapFile.WriteAt(memory[frame*pageSize:(frame+1)*pageSize], int64(slot*pageSize))
+	return err
+}
+
+func leerDeSwap(slot int, frame int) error {
+	time.Sleep(time.Duration(globals.ClientConfig.SwapDelay) * time.Millisecond)
+	_, err := swapFile.ReadAt(memory[frame*pageSize:(frame+1)*pageSize], int64(slot*pageSize))
+	return err
+}
+
+// Si el marco esta compartido por copy-on-write se saca de la tabla de todos los procesos que lo usan.
+// Los slots de los otros procesos ya tienen el contenido: se copio al clonar y el marco no cambia mientras
+// esta compartido. El slot del dueño se escribe solo si la pagina se modifico. Lo hace todo con el mutex
+// tomado (copy-on-write), CargarPagina usa las mismas partes soltando el mutex para la E/S
+func desalojarFrame(frame int) error {
+	d := prepararDesalojo(frame)
+	err := d.completar()
+	d.terminar(err)
+	if err == nil {
+		liberarFrame(frame)
+	}
+	return err
+}
+
+func PageFaultHandler(w http.ResponseWriter, r *http.Request) {
+	var body bodyCPUpage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := CargarPagina(body.Pid, body.Page); err != nil {
+		log.Printf("PID: %d - Pagina: %d - No se pudo cargar: %v", body.Pid, body.Page, err)
+		if errors.Is(err, errSinMarcos) {
+			http.Error(w, err.Error(), http.StatusInsufficientStorage)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Una pagina de un proceso
+type paginaProceso struct {
+	pid    int
+	pagina int
+}
+
+// Paginas que se estan llevando o trayendo de swap sin el mutex. Un page fault de una de estas paginas
+// espera a que se cierre el canal
+var paginasEnTransito = make(map[paginaProceso]chan struct{})
+
+// Marcos que tiene reservados una carga en curso, no pueden ser victima de otro reemplazo
+var marcosEnTransito = make(map[int]bool)
+
+// Slots de swap que se estan leyendo o escribiendo. Si el proceso los libera mientras tanto (termino o
+// se achico) el valor pasa a true y se liberan cuando termina la E/S, asi nadie los reserva antes
+var slotsEnTransito = make(map[int]bool)
+
+// Hay que tener el mutex
+func liberarSlotSwap(slot int) {
+	if _, enTransito := slotsEnTransito[slot]; enTransito {
+		slotsEnTransito[slot] = true
+		return
+	}
+	swapMap[slot] = false
+}
+
+// Hay que tener el mutex
+func terminarTransitoSlot(slot int) {
+	if liberar := slotsEnTransito[slot]; liberar {
+		swapMap[slot] = false
+	}
+	delete(slotsEnTransito, slot)
+}
+
+// Lo que se hace sin el mutex para desalojar una pagina: escribirla en swap y avisarle a la CPU
+type desalojo struct {
+	marco     int
+	victima   FrameInfo
+	slot      int
+	usuarios  []paginaProceso // Todos los procesos que usaban el marco (mas de uno si es copy-on-write)
+	memoryMap int
+}
+
+// Hay que tener el mutex. Saca el marco de la tabla de todos los procesos que lo usan, sin tocar su contenido
+func prepararDesalojo(frame int) desalojo {
+	d := desalojo{marco: frame, victima: frameTable[frame], memoryMap: memoryMap[frame]}
+	d.slot = swapTable[d.victima.Pid][d.victima.Pagina]
+	for _, pid := range pidsPaginados() {
+		if pagina := paginaDelFrame(pid, frame); pagina != -1 {
+			d.usuarios = append(d.usuarios, paginaProceso{pid, pagina})
+			asignarMarcoPagina(pid, pagina, -1)
+			paginasEnTransito[paginaProceso{pid, pagina}] = make(chan struct{})
+		}
+	}
+	slotsEnTransito[d.slot] = false
+	return d
+}
+
+// Sin el mutex: el marco ya no esta en ninguna tabla. Primero se saca de la TLB, despues se traen las
+// lineas sucias de la cache de datos de la CPU y recien ahi se escribe en swap
+func (d desalojo) completar() error {
+	for _, usuario := range d.usuarios {
+		invalidarEntradaTLB(usuario.pid, usuario.pagina)
+	}
+	modificado := d.victima.Modificado
+	for _, linea := range pedirLineasSucias(d.marco) {
+		inicio := d.marco * pageSize
+		if linea.Direccion >= inicio && linea.Direccion+len(linea.Datos) <= inicio+pageSize {
+			copy(memory[linea.Direccion:], linea.Datos)
+			modificado = true
+		}
+	}
+	escritura := "NO"
+	if modificado { // Si la pagina no se modifico, la copia en swap sigue siendo valida
+		if err := escribirEnSwap(d.slot, d.marco); err != nil {
+			return err
+		}
+		escritura = "SI"
+	}
+	for _, usuario := range d.usuarios {
+		if usuario.pid != d.victima.Pid {
+			log.Printf("Reemplazo - PID Victima: %d - Pagina: %d - Marco compartido: %d", usuario.pid, usuario.pagina, d.marco)
+		}
+	}
+	log.Printf("Reemplazo - PID Victima: %d - Pagina: %d - Marco: %d - Escritura en Swap: %s", d.victima.Pid, d.victima.Pagina, d.marco, escritura)
+	return nil
+}
+
+// Hay que tener el mutex. Si no se pudo escribir en swap el marco vuelve a las tablas de los procesos que sigan
+func (d desalojo) terminar(err error) {
+	for _, usuario := range d.usuarios {
+		if err != nil && tieneTablaPaginas(usuario.pid) && usuario.pagina < cantidadPaginas(usuario.pid) && marcoDePagina(usuario.pid, usuario.pagina) == -1 {
+			asignarMarcoPagina(usuario.pid, usuario.pagina, d.marco)
+		}
+		close(paginasEnTransito[usuario])
+		delete(paginasEnTransito, usuario)
+	}
+	terminarTransitoSlot(d.slot)
+	if err != nil {
+		frameTable[d.marco] = d.victima
+		memoryMap[d.marco] = d.memoryMap
+	}
+}
+
+// Trae la pagina desde swap a un marco libre, desalojando otra pagina si no hay marcos libres. El mutex se
+// tiene solo para elegir y reservar el marco y para actualizar las tablas: los accesos a swap (con su
+// retardo) y los avisos a la CPU se hacen sin el, asi los otros procesos siguen usando memoria
+func CargarPagina(pid int, pagina int) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for {
+		if !memoriaVirtual {
+			return fmt.Errorf("la memoria virtual no esta activada")
+		}
+		if !tieneTablaPaginas(pid) {
+			return fmt.Errorf("Process with PID %d not found", pid)
+		}
+		if pagina < 0 || pagina >= cantidadPaginas(pid) {
+			return fmt.Errorf("PID %d no tiene la pagina %d", pid, pagina)
+		}
+		if marcoDePagina(pid, pagina) != -1 { // Ya la cargo otro page fault
+			return nil
+		}
+		espera, enTransito := paginasEnTransito[paginaProceso{pid, pagina}]
+		if !enTransito {
+			break
+		}
+		mu.Unlock() // Otro la esta cargando o desalojando
+		<-espera
+		mu.Lock()
+	}
+
+	var reemplazo *desalojo
+	frame := proximoLugarLibre(pid)
+	if frame == -1 {
+		frame = elegirVictima(pid)
+		if frame == -1 {
+			return fmt.Errorf("%w: no hay marcos para desalojar", errSinMarcos)
+		}
+		d := prepararDesalojo(frame)
+		reemplazo = &d
+	}
+	cargando := paginaProceso{pid, pagina}
+	slot := swapTable[pid][pagina]
+	paginasEnTransito[cargando] = make(chan struct{})
+	marcosEnTransito[frame] = true
+	slotsEnTransito[slot] = false
+	asignarFrame(frame, pid, pagina) // Reservado, la pagina todavia no apunta al marco
+
+	mu.Unlock()
+	var errDesalojo, err error
+	if reemplazo != nil {
+		if errDesalojo = reemplazo.completar(); errDesalojo != nil {
+			err = fmt.Errorf("error al escribir en swap: %v", errDesalojo)
+		}
+	}
+	if err == nil {
+		if err = leerDeSwap(slot, frame); err != nil {
+			err = fmt.Errorf("error al leer de swap: %v", err)
+		}
+	}
+	mu.Lock()
+
+	delete(marcosEnTransito, frame)
+	if reemplazo != nil {
+		reemplazo.terminar(errDesalojo) // Si fallo, el marco vuelve a ser de la victima
+	}
+	close(paginasEnTransito[cargando])
+	delete(paginasEnTransito, cargando)
+	terminarTransitoSlot(slot)
+	if errDesalojo != nil {
+		return err
+	}
+
+	// Mientras tanto el proceso pudo terminar o achicarse (y volver a crecer con otro slot)
+	sigue := tieneTablaPaginas(pid) && pagina < cantidadPaginas(pid) && marcoDePagina(pid, pagina) == -1 && swapTable[pid][pagina] == slot
+	if err != nil || !sigue {
+		liberarFrame(frame)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("PID %d ya no tiene la pagina %d", pid, pagina)
+	}
+	asignarMarcoPagina(pid, pagina, frame)
+	log.Printf("PID: %d - Pagina: %d - Cargada en Marco: %d", pid, pagina, frame)
+	return nil
+}
+
+func invalidarEntradaTLB(pid int, pagina int) {
+	CPUurl := fmt.Sprintf("http://%s:%d/invalidateTLB", globals.ClientConfig.IpCPU, globals.ClientConfig.PuertoCPU)
+	body := bodyCPUpage{Pid: pid, Page: pagina}
+	bodyJSON, err := json.Marshal(body)
+	if err != nil {
+		log.Printf("Error al serializar la pagina: %v", err)
+		return
+	}
+
+	resp, err := http.Post(CPUurl, "application/json", bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		log.Printf("Error al invalidar la TLB de la CPU: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+}
+
+type BodyMarcos struct {
+	Marcos []int `json:"frames"`
+}
+
+type BodyLineaSucia struct {
+	Direccion int    `json:"address"`
+	Datos     []byte `json:"data"`
+}
+
+// Hay que tener el mutex. La cache de datos de la CPU esta indexada por direccion fisica: antes de desalojar,
+// copiar o liberar un marco se le pide que saque sus lineas, y las sucias que devuelve se escriben aca
+// (la CPU no puede mandarlas a /writeMemory mientras se tiene el mutex)
+func invalidarCacheCPU(marcos ...int) {
+	for _, linea := range pedirLineasSucias(marcos...) {
+		for i, dato := range linea.Datos {
+			if direccion := linea.Direccion + i; direccion >= 0 && direccion < len(memory) {
+				memory[direccion] = dato
+				marcarAcceso(direccion/pageSize, true)
+			}
+		}
+		log.Printf("Cache de la CPU - Linea sucia escrita - Direccion fisica: %d - Tamaño: %d", linea.Direccion, len(linea.Datos))
+	}
+}
+
+// Le pide a la CPU que saque de su cache de datos las lineas de los marcos y devuelve las sucias
+func pedirLineasSucias(marcos ...int) []BodyLineaSucia {
+	if len(marcos) == 0 {
+		return nil
+	}
+	CPUurl := fmt.Sprintf("http://%s:%d/invalidateFrames", globals.ClientConfig.IpCPU, globals.ClientConfig.PuertoCPU)
+	bodyJSON, err := json.Marshal(BodyMarcos{Marcos: marcos})
+	if err != nil {
+		log.Printf("Error al serializar los marcos: %v", err)
+		return nil
+	}
+	resp, err := http.Post(CPUurl, "application/json", bytes.NewBuffer(bodyJSON))
+	if err != nil {
+		log.Printf("Error al invalidar la cache de datos de la CPU: %v", err)
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil // Una CPU sin la ruta no tiene cache de datos
+	}
+
+	var sucias []BodyLineaSucia
+	if err := json.NewDecoder(resp.Body).Decode(&sucias); err != nil {
+		log.Printf("Error al leer las lineas sucias de la CPU: %v", err)
+		return nil
+	}
+	return sucias
+}
+
+// Borra todas las entradas del proceso en la TLB de la CPU
+func vaciarTLBProceso(pid int) {
+	vaciarTLB(fmt.Sprintf("http://%s:%d/tlb?pid=%d", globals.ClientConfig.IpCPU, globals.ClientConfig.PuertoCPU, pid))
+}
+
+// Borra todas las entradas de la TLB de la CPU, de todos los procesos
+func vaciarTLBCompleta() {
+	vaciarTLB(fmt.Sprintf("http://%s:%d/tlb", globals.ClientConfig.IpCPU, globals.ClientConfig.PuertoCPU))
+}
+
+func vaciarTLB(CPUurl string) {
+	req, err := http.NewRequest(http.MethodDelete, CPUurl, nil)
+	if err != nil {
+		log.Printf("Error al crear la solicitud: %v", err)
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("Error al vaciar la TLB de la CPU: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sisoputnfrba/tp-golang/memoria/globals"
+)
+
+// PID 1 con las paginas en swap, cada una con su contenido en su slot
+func procesoEnSwap(contenidos ...string) {
+	crearTablaPaginas(1)
+	for _, contenido := range contenidos {
+		agregarPagina(1, -1)
+		slot := reservarSlotSwap()
+		swapTable[1] = append(swapTable[1], slot)
+		swapFile.WriteAt([]byte(contenido), int64(slot*pageSize))
+	}
+}
+
+func leerSlot(slot int) string {
+	datos := make([]byte, 4)
+	swapFile.ReadAt(datos, int64(slot*pageSize))
+	return string(datos)
+}
+
+func TestCargarYDesalojar(t *testing.T) {
+	cpu := configurarMemoria(t, globals.Config{SwapSize: 64, Algoritmo: "FIFO"}, 2)
+	procesoEnSwap("aaaa", "bbbb", "cccc")
+	for _, pagina := range []int{0, 1} {
+		if err := CargarPagina(1, pagina); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// La pagina 0 se modifica en memoria y ademas la CPU tiene una linea sucia del marco
+	copy(memory, "AA")
+	marcarAcceso(0, true)
+	cpu.sucias = []BodyLineaSucia{{Direccion: 2, Datos: []byte("ZZ")}}
+
+	if err := CargarPagina(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if marcoDePagina(1, 0) != -1 || marcoDePagina(1, 2) != 0 || string(memory[:4]) != "cccc" {
+		t.Errorf("despues del reemplazo: tabla %v, marco 0 %q", pageTable[1], memory[:4])
+	}
+	if slot := leerSlot(swapTable[1][0]); slot != "AAZZ" {
+		t.Errorf("swap de la pagina 0 = %q, se esperaba %q con la linea sucia de la CPU", slot, "AAZZ")
+	}
+	if !slices.Contains(cpu.recibidos(), "invalidateTLB?") {
+		t.Errorf("la CPU no recibio invalidateTLB: %v", cpu.recibidos())
+	}
+
+	// La pagina 1 no se modifico: se desaloja sin escribir y la pagina 0 vuelve con lo que se escribio
+	swapFile.WriteAt([]byte("xxxx"), int64(swapTable[1][1]*pageSize))
+	if err := CargarPagina(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if marcoDePagina(1, 0) != 1 || string(memory[pageSize:pageSize+4]) != "AAZZ" {
+		t.Errorf("pagina 0 recargada: tabla %v, marco 1 %q", pageTable[1], memory[pageSize:pageSize+4])
+	}
+	if slot := leerSlot(swapTable[1][1]); slot != "xxxx" {
+		t.Errorf("la pagina 1 sin modificar se escribio en swap: %q", slot)
+	}
+	if len(paginasEnTransito) != 0 || len(marcosEnTransito) != 0 || len(slotsEnTransito) != 0 {
+		t.Errorf("quedaron cargas en curso: %v %v %v", paginasEnTransito, marcosEnTransito, slotsEnTransito)
+	}
+}
+
+// Empieza a cargar la pagina 2 del PID 1 (desalojando la 0) con un retardo de swap y espera a que este en curso
+func cargaEnCurso(t *testing.T) chan error {
+	t.Helper()
+	configurarMemoria(t, globals.Config{SwapSize: 64, Algoritmo: "FIFO"}, 2)
+	procesoEnSwap("aaaa", "bbbb", "cccc")
+	CargarPagina(1, 0)
+	CargarPagina(1, 1)
+	globals.ClientConfig.SwapDelay = 100
+
+	termino := make(chan error, 1)
+	go func() { termino <- CargarPagina(1, 2) }()
+	time.Sleep(30 * time.Millisecond)
+	return termino
+}
+
+func TestCargaSinElMutex(t *testing.T) {
+	termino := cargaEnCurso(t)
+	if !mu.TryLock() {
+		t.Fatal("el mutex de memoria esta tomado durante el retardo de swap")
+	}
+	if !marcosEnTransito[0] || marcoDePagina(1, 0) != -1 || marcoDePagina(1, 2) != -1 {
+		t.Errorf("durante la carga: marcos %v, tabla %v", marcosEnTransito, pageTable[1])
+	}
+	if victima := elegirVictima(1); victima != 1 {
+		t.Errorf("victima = %d, el marco reservado no puede ser victima", victima)
+	}
+	mu.Unlock()
+
+	// Otro page fault de la misma pagina espera a la carga en curso
+	if err := CargarPagina(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if marcoDePagina(1, 2) != 0 || string(memory[:4]) != "cccc" {
+		t.Errorf("el segundo page fault no vio la carga: tabla %v, marco 0 %q", pageTable[1], memory[:4])
+	}
+	if err := <-termino; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcesoTerminaDuranteLaCarga(t *testing.T) {
+	termino := cargaEnCurso(t)
+	mu.Lock()
+	slot := swapTable[1][2]
+	for pagina := 2; pagina >= 0; pagina-- {
+		liberarPaginaVirtual(1, pagina)
+	}
+	borrarTablaPaginas(1)
+	delete(swapTable, 1)
+	if !swapMap[slot] {
+		t.Error("el slot que se esta leyendo se libero antes de terminar la lectura")
+	}
+	mu.Unlock()
+
+	if err := <-termino; err == nil {
+		t.Fatal("se esperaba error: el proceso ya no existe")
+	}
+	if memoryMap[0] != 0 || frameTable[0].Pid != -1 || swapMap[slot] {
+		t.Errorf("la carga no devolvio el marco ni el slot: marco %+v, slots %v", frameTable[0], swapMap)
+	}
+}
@@ -1,7 +1,6 @@
 package utils
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -10,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,6 +33,10 @@ type InstructionResposne struct {
 	Instruction string `json:"instruction"`
 }
 
+type InstructionsResponse struct {
+	Instructions []string `json:"instructions"`
+}
+
 type PCB struct {
 	// programCounter, Quantum int
 	Pid    int
@@ -41,7 +45,9 @@ type PCB struct {
 
 type RegisterCPU struct {
 	PC, EAX, EBX, ECX, EDX, SI, DI uint32
-	AX, BX, CX, DX                 uint8
+	SP                             uint32
+	FLAGS                          uint8
+	AX, BX, CX, DX, ERR            uint8
 }
 
 /////////////////////////////////////////////////// VARS GLOBALES DE MEMORIA //////////////////////////////////////////////////////////////
@@ -53,13 +59,13 @@ var pageSize int
 var memorySize int
 
 // Mapa de memoria ocupada/libre
-var memoryMap []bool // True si el FRAME esta ocupado, False si esta libre
+var memoryMap []int // Cantidad de procesos que usan el FRAME, 0 si esta libre (mas de 1 si es copy-on-write)
 
 // Espacio de memoria
 var memory []byte
 
 // Tabla de páginas
-var pageTable = make(map[int][]int) // Map de pids con pagina asociada, cuya pagina tiene un marco asociado
+var pageTable = make(map[int][]int) // Map de pids con pagina asociada, cuya pagina tiene un marco asociado (tabla de un nivel)
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
@@ -80,10 +86,15 @@ type MemoryRequest struct {
 	Data    []byte `json:"data,omitempty"` //Si es 0, se omite Util para creacion y terminacion de procesos)
 	Type    string `json:"type"`
 	Port    int    `json:"port,omitempty"`
+	Acceso  string `json:"access,omitempty"` // R (por defecto) o X para el fetch de programas binarios
+	Core    int    `json:"core"`             // Core de la CPU al que se le mandan los datos
 }
 
 type BodyFrame struct {
-	Frame int `json:"frame"`
+	Frame     int    `json:"frame"`
+	PageFault bool   `json:"page_fault,omitempty"` // La pagina no esta en memoria principal
+	SegFault  bool   `json:"segfault,omitempty"`   // La pagina no pertenece al proceso
+	Permisos  string `json:"permisos,omitempty"`
 }
 
 type BodyRequestPort struct {
@@ -98,12 +109,18 @@ type BodyRequestInput struct {
 }
 
 type bodyCPUpage struct {
-	Pid  int `json:"pid"`
-	Page int `json:"page"`
+	Pid     int   `json:"pid"`
+	Page    int   `json:"page"`
+	Indices []int `json:"indices,omitempty"` // Indice de cada nivel de tablas calculado por la MMU
+	Core    int   `json:"core"`              // Core de la CPU al que se le manda el marco
 }
 
 type BodyPageTam struct {
-	PageTam int `json:"pageTam"`
+	PageTam          int    `json:"pageTam"`
+	Niveles          int    `json:"levels,omitempty"`
+	EntradasPorTabla int    `json:"entries_per_table,omitempty"`
+	ModoMemoria      string `json:"memory_mode,omitempty"`
+	TamMaxSegmento   int    `json:"max_segment_size,omitempty"`
 }
 
 /////////////////////////////////////////////////// VARS GLOBALES ///////////////////////////////////////////////////////////////////////
@@ -113,19 +130,16 @@ var CPUpage int
 
 /////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
-func init() {
-	globals.ClientConfig = IniciarConfiguracion(os.Args[1]) // tiene que prender la confi cuando arranca
-
-	if globals.ClientConfig != nil {
-		pageSize = globals.ClientConfig.PageSize
-		memorySize = globals.ClientConfig.MemorySize
-		memory = make([]byte, memorySize) //intocable
-		memoryMap = make([]bool, memorySize/globals.ClientConfig.PageSize)
-		SendPageTamToCPU(globals.ClientConfig.PageSize)
-		//cantidadFrames := globals.ClientConfig.MemorySize / globals.ClientConfig.PageSize
-	} else {
-		log.Fatal("ClientConfig is not initialized")
-	}
+// Arma la memoria, la tabla de marcos y el swap con la config ya cargada y le avisa a la CPU el tamaño
+// de pagina. Lo llama main, asi los tests del paquete no necesitan una config en os.Args
+func IniciarMemoria() {
+	pageSize = globals.ClientConfig.PageSize
+	memorySize = globals.ClientConfig.MemorySize
+	memory = make([]byte, memorySize) //intocable
+	memoryMap = make([]int, memorySize/globals.ClientConfig.PageSize)
+	iniciarFrameTable(len(memoryMap))
+	IniciarSwap()
+	SendPageTamToCPU(globals.ClientConfig.PageSize)
 }
 
 func IniciarConfiguracion(filePath string) *globals.Config {
@@ -157,22 +171,52 @@ func SetInstructionsFromFileToMap(w http.ResponseWriter, r *http.Request) {
 	pid, _ := strconv.Atoi(queryParams1.Get("pid"))
 	queryParams2 := r.URL.Query()
 	path := queryParams2.Get("path")
+	recarga := programaCargado(pid)
+
+	binario, err := cargarArchivoBinario(pid, path)
+	if binario || err != nil {
+		if err != nil {
+			log.Printf("PID: %d - Programa rechazado: %s - %v", pid, path, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("PID: %d - Programa binario cargado: %s - Tamaño: %d", pid, path, programasBinarios[pid])
+		if recarga {
+			avisarRecargaPrograma(pid)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Instructions loaded successfully"))
+		return
+	}
 
-	readFile, _ := os.Open(path)
-	// Ensure file is closed even on errors
-
-	// Create a new scanner for line-by-line reading
-	fileScanner := bufio.NewScanner(readFile)
-	fileScanner.Split(bufio.ScanLines)
+	programa, err := ensamblarArchivo(path)
+	if err != nil {
+		// El kernel le devuelve este mensaje al usuario y no crea el proceso
+		log.Printf("PID: %d - Programa rechazado: %s\n%v", pid, path, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	var arrInstructions [][]string
-	for fileScanner.Scan() {
-		//Esta linea lee los codigos
-		arrInstructions = append(arrInstructions, []string{fileScanner.Text()})
+	for _, linea := range programa.Lineas {
+		arrInstructions = append(arrInstructions, []string{linea.Texto})
+	}
+	mu.Lock()
+	delete(programasBinarios, pid)
+	err = reservarPila(pid)
+	if err == nil {
+		mapInstructions[pid] = arrInstructions
+		etiquetas[pid] = programa.Etiquetas
+	}
+	mu.Unlock()
+	if err != nil {
+		log.Printf("PID: %d - Programa rechazado: %s - %v", pid, path, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if recarga {
+		avisarRecargaPrograma(pid)
 	}
-	mapInstructions[pid] = arrInstructions
-
-	defer readFile.Close()
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Instructions loaded successfully"))
@@ -182,7 +226,15 @@ func GetInstruction(w http.ResponseWriter, r *http.Request) {
 	queryParams := r.URL.Query()
 	pid, _ := strconv.Atoi(queryParams.Get("pid"))
 	programCounter, _ := strconv.Atoi(queryParams.Get("programCounter"))
-	instruction := mapInstructions[pid][programCounter][0]
+	instrucciones, ok := instruccionesParaFetch(w, pid)
+	if !ok {
+		return
+	}
+	if programCounter < 0 || programCounter >= len(instrucciones) {
+		http.Error(w, fmt.Sprintf("el PID %d no tiene la instrucción %d", pid, programCounter), http.StatusBadRequest)
+		return
+	}
+	instruction := instrucciones[programCounter][0]
 
 	time.Sleep(time.Duration(globals.ClientConfig.DelayResponse) * time.Millisecond)
 
@@ -195,6 +247,51 @@ func GetInstruction(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(instruction))
 }
 
+// GET /getInstructionsFromPid?pid=&programCounter=&count= devuelve hasta count instrucciones desde el PC
+// con una sola espera de delay_response, para el cache de instrucciones de la CPU
+func GetInstructions(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	pid, _ := strconv.Atoi(queryParams.Get("pid"))
+	programCounter, _ := strconv.Atoi(queryParams.Get("programCounter"))
+	count, err := strconv.Atoi(queryParams.Get("count"))
+	if err != nil || count <= 0 {
+		http.Error(w, "Cantidad inválida", http.StatusBadRequest)
+		return
+	}
+	instrucciones, ok := instruccionesParaFetch(w, pid)
+	if !ok {
+		return
+	}
+	if programCounter < 0 || programCounter >= len(instrucciones) {
+		http.Error(w, fmt.Sprintf("el PID %d no tiene la instrucción %d", pid, programCounter), http.StatusBadRequest)
+		return
+	}
+	var response InstructionsResponse
+	for _, instruction := range instrucciones[programCounter:min(programCounter+count, len(instrucciones))] {
+		response.Instructions = append(response.Instructions, instruction[0])
+	}
+
+	time.Sleep(time.Duration(globals.ClientConfig.DelayResponse) * time.Millisecond)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Instrucciones del proceso para el fetch. Sin permiso de ejecucion se responde 403, la CPU lo toma como
+// SEGMENTATION_FAULT. Al recargar el programa se reemplaza el slice entero, asi que se puede leer sin el mutex
+func instruccionesParaFetch(w http.ResponseWriter, pid int) ([][]string, bool) {
+	mu.Lock()
+	err := verificarFetch(pid)
+	instrucciones := mapInstructions[pid]
+	mu.Unlock()
+	if err != nil {
+		log.Printf("PID: %d - Fetch denegado: %v", pid, err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return nil, false
+	}
+	return instrucciones, true
+}
+
 // COMUNICACION
 // Creacion de procesos
 func CreateProcessHandler(w http.ResponseWriter, r *http.Request) {
@@ -217,14 +314,23 @@ func CreateProcess(pid int, pages int) error {
 	mu.Lock()
 	defer mu.Unlock()
 
+	if modoSegmentacion() {
+		if err := crearSegmentos(pid); err != nil {
+			log.Printf("Error: %v", err)
+		}
+		log.Printf("PID: %d - Tamaño: %d", pid, pages)
+		return nil
+	}
+
 	if len(memory)/pageSize < pages { // Verifico si hay suficiente espacio en memoria en base a las paginas solicitadas
 		FinalizarProceso(pid)
 	}
 
-	if _, exists := pageTable[pid]; exists { //Verifico si ya existe un proceso con ese pid
+	if tieneTablaPaginas(pid) { //Verifico si ya existe un proceso con ese pid
 		log.Printf("Error: PID %d already has pages assigned", pid)
 	} else {
-		pageTable[pid] = make([]int, pages) // Creo un slice de paginas para el proceso
+		cargarTablaPaginas(pid, make([]int, pages)) // Creo la tabla de paginas del proceso
+		ajustarProteccion(pid)
 	}
 
 	log.Printf("PID: %d - Tamaño: %d", pid, pages)
@@ -256,17 +362,40 @@ func TerminateProcess(pid int) error {
 	/*mu.Lock()
 	defer mu.Unlock()*/
 
-	if _, exists := pageTable[pid]; !exists {
+	if modoSegmentacion() {
+		if !procesoExiste(pid) {
+			log.Printf("Proceso no encontrado")
+			return nil
+		}
+		log.Printf("PID: %d - Tamaño: %d", pid, segmentTable[pid][SegmentoDatos].Limite)
+		liberarSegmentos(pid)
+		delete(heaps, pid)
+		delete(programasBinarios, pid)
+		notificarMemoriaLiberada()
+		return nil
+	}
+
+	if !tieneTablaPaginas(pid) {
 		log.Printf("Proceso no encontrado")
 		return nil
 	} else {
-		if addresses, exists := pageTable[pid]; exists {
-			for _, address := range addresses {
-				memoryMap[address] = false //Marca las addresses del pid como libres
+		if memoriaVirtual {
+			for pagina := 0; pagina < cantidadPaginas(pid); pagina++ {
+				liberarPaginaVirtual(pid, pagina) //Libera el marco (si esta cargada) y el slot de swap
+			}
+			delete(swapTable, pid)
+		} else {
+			for _, address := range paginasDelProceso(pid) {
+				soltarFrame(address, pid) //Marca las addresses del pid como libres (si no las comparte con otro proceso)
 			}
 		}
-		log.Printf("PID: %d - Tamaño: %d", pid, len(pageTable[pid]))
-		delete(pageTable, pid) //Funcion que viene con map, libera los marcos asignados a un pid
+		log.Printf("PID: %d - Tamaño: %d", pid, cantidadPaginas(pid))
+		borrarTablaPaginas(pid) //Libera la tabla (y las tablas de cada nivel) del proceso
+		liberarPool(pid)
+		delete(heaps, pid)
+		notificarMemoriaLiberada()
+		delete(pageProtection, pid)
+		delete(programasBinarios, pid)
 	}
 	return nil
 }
@@ -281,62 +410,137 @@ func ResizeProcessHandler(w http.ResponseWriter, r *http.Request) {
 	Pid := process.PID
 	Pages := process.Pages
 
-	err := ResizeProcess(Pid, Pages)
+	resultado, err := ResizeProcess(Pid, Pages)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BodyResize{Resultado: resultado, Politica: politicaSinMemoria()})
 }
 
-func ResizeProcess(pid int, newSize int) error {
+// Devuelve OK u OUT_OF_MEMORY, que hace la CPU con OUT_OF_MEMORY depende de la politica
+func ResizeProcess(pid int, newSize int) (string, error) {
 	mu.Lock()
 	defer mu.Unlock()
 
-	pages, exists := pageTable[pid]
-	if !exists { // Verifico si el proceso existe
+	resultado, err := redimensionarProceso(pid, newSize)
+	if err != nil || resultado != ResizeOK || !procesoExiste(pid) {
+		return resultado, err
+	}
+	// Si el RESIZE dejo afuera el comienzo del heap sus bloques ya no existen: el proximo MALLOC arma uno nuevo
+	if heap, exists := heaps[pid]; exists {
+		if _, fin := espacioHeap(pid); fin < heap.Base {
+			log.Printf("PID: %d - RESIZE por debajo del heap (base %d), se descartan sus bloques", pid, heap.Base)
+			delete(heaps, pid)
+		}
+	}
+	return resultado, nil
+}
+
+// Hay que tener el mutex de memoria
+func redimensionarProceso(pid int, newSize int) (string, error) {
+	defer ajustarProteccion(pid)
+
+	if modoSegmentacion() { // RESIZE cambia el tamaño del segmento de datos
+		if !procesoExiste(pid) {
+			log.Printf("Proceso no encontrado")
+			return ResizeOK, nil
+		}
+		actual := segmentTable[pid][SegmentoDatos].Limite
+		log.Printf("PID: %d - Tamaño Actual: %d - Tamaño Nuevo: %d", pid, actual, newSize)
+		if superaLimite((newSize + pageSize - 1) / pageSize) {
+			return sinMemoria(pid, "supera el limite de paginas por proceso", false), nil
+		}
+		if err := redimensionarSegmento(pid, SegmentoDatos, newSize); err != nil {
+			log.Printf("PID: %d - Error al redimensionar el segmento de datos: %v", pid, err)
+			return sinMemoria(pid, err.Error(), true), nil
+		}
+		if newSize < actual {
+			notificarMemoriaLiberada()
+		}
+		return ResizeOK, nil
+	}
+
+	if !tieneTablaPaginas(pid) { // Verifico si el proceso existe
 		log.Printf("Proceso no encontrado")
+		return ResizeOK, nil
 	}
 
+	// El tamaño pedido es el de los datos, que van despues del codigo de los programas binarios
+	newSize += baseDatos(pid)
 	if newSize%pageSize != 0 { //Verifico si el nuevo tamaño es multiplo del tamaño de pagina
 		newSize = newSize + pageSize - (newSize % pageSize) //Si no es multiplo, lo redondeo al proximo multiplo
 	}
-	currentSize := len(pages)
+	currentSize := cantidadPaginas(pid)
 	if newSize/pageSize > currentSize { //Comparo el tamaño actual con el nuevo tamaño
 		log.Printf("PID: %d - Tamaño Actual: %d - Tamaño a Ampliar: %d", pid, currentSize, newSize)
-		freespace := counterMemoryFree()
+		if superaLimite(newSize / pageSize) {
+			return sinMemoria(pid, "supera el limite de paginas por proceso", false), nil
+		}
+		if tablasMultinivelActivas() && newSize/pageSize > maxPaginasMultinivel() { //Las tablas no alcanzan para direccionar todas las paginas
+			return sinMemoria(pid, "las tablas de paginas no alcanzan", false), nil
+		}
+		if memoriaVirtual { // Las paginas nuevas arrancan sin marco, solo reservan su lugar en swap
+			if counterSwapFree() < (newSize/pageSize)-currentSize {
+				return sinMemoria(pid, "no hay espacio en swap", true), nil
+			}
+			for i := currentSize; i < newSize/pageSize; i++ {
+				agregarPagina(pid, -1)
+				swapTable[pid] = append(swapTable[pid], reservarSlotSwap())
+			}
+			return ResizeOK, nil
+		}
+		freespace := framesDisponibles(pid)
 		if freespace < (newSize/pageSize)-currentSize { //Verifico si hay suficiente espacio en memoria despues de la ampliacion
-			FinalizarProceso(pid)
-			return nil
+			return sinMemoria(pid, "no hay marcos libres", true), nil
 		}
 
 		for i := currentSize; i < newSize/pageSize; i++ { //Asigno nuevos marcos a la ampliacion
-			indiceLibre := proximoLugarLibre()
+			indiceLibre := proximoLugarLibre(pid)
 			if indiceLibre != -1 {
 
-				pageTable[pid] = append(pageTable[pid], indiceLibre)
-				memoryMap[indiceLibre] = true
+				agregarPagina(pid, indiceLibre)
+				asignarFrame(indiceLibre, pid, i)
 
 			} else {
 				break
 			}
 		}
 	} else {
-		for i := newSize / pageSize; i < len(pageTable[pid]); i++ {
-			memoryMap[pageTable[pid][i]] = false
+		var liberados []int
+		for i := newSize / pageSize; i < currentSize; i++ {
+			if frame := marcoDePagina(pid, i); frame != -1 {
+				liberados = append(liberados, frame)
+			}
+		}
+		invalidarCacheCPU(liberados...) // Los marcos pueden pasar a otro proceso
+		for i := newSize / pageSize; i < currentSize; i++ {
+			if memoriaVirtual {
+				liberarPaginaVirtual(pid, i)
+			} else {
+				soltarFrame(marcoDePagina(pid, i), pid)
+			}
+		}
+		recortarPaginas(pid, newSize/pageSize) //Reduce el tamaño del proceso, con tablas multinivel libera las tablas que quedan vacias
+		if memoriaVirtual {
+			swapTable[pid] = swapTable[pid][:newSize/pageSize]
 		}
-		pageTable[pid] = pageTable[pid][:newSize/pageSize] //Reduce el tamaño del proceso. :newSize es un slice de 0 a newSize (reduce el tope)
 		//fmt.Println("Proceso reducido")
 		log.Printf("PID: %d - Tamaño Actual: %d - Tamaño a Reducir: %d", pid, currentSize, newSize)
+		if newSize/pageSize < currentSize {
+			vaciarTLBProceso(pid) // La CPU puede tener marcos de las paginas que se liberaron
+			notificarMemoriaLiberada()
+		}
 	}
-	return nil
+	return ResizeOK, nil
 }
 
 func counterMemoryFree() int {
 	var contador int
 	for i := 0; i < len(memoryMap); i++ {
-		if !memoryMap[i] {
+		if memoryMap[i] == 0 {
 			contador++
 		}
 	}
@@ -352,24 +556,27 @@ func ReadMemoryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := ReadMemory(memReq.PID, memReq.Address, memReq.Size)
+	if memReq.Acceso == "" {
+		memReq.Acceso = "R"
+	}
+	data, err := ReadMemory(memReq.PID, memReq.Address, memReq.Size, memReq.Acceso)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	if memReq.Type == "CPU" {
-		sendDataToCPU(data)
+		sendDataToCPU(memReq.Core, data)
 	} else if memReq.Type == "IO" {
 		SendContentToIO(string(data), memReq.Port)
 	}
 	w.Write(data)
 }
 
-func ReadMemory(pid int, addresses []int, size int) ([]byte, error) {
+func ReadMemory(pid int, addresses []int, size int, acceso string) ([]byte, error) {
 	mu.Lock()
 	defer mu.Unlock()
 
-	if _, exists := pageTable[pid]; !exists {
+	if !procesoExiste(pid) {
 		return nil, fmt.Errorf("Process with PID %d not found", pid)
 	}
 
@@ -378,15 +585,24 @@ func ReadMemory(pid int, addresses []int, size int) ([]byte, error) {
 		if address < 0 || address >= len(memory) {
 			return nil, fmt.Errorf("memory access out of bounds at address %d", address)
 		}
+		if err := verificarAcceso(pid, address, acceso); err != nil {
+			return nil, err
+		}
 		result = append(result, memory[address])
+		marcarAcceso(address/pageSize, false)
 	}
 	log.Printf("PID: %d - Accion: LEER - Direccion fisica: %d - Tamaño %d", pid, addresses[0], size)
 	return result, nil
 }
 
+// Las respuestas a la CPU van al core que hizo el pedido, cada core espera las suyas
+func urlCore(core int, ruta string) string {
+	return fmt.Sprintf("http://%s:%d/core/%d/%s", globals.ClientConfig.IpCPU, globals.ClientConfig.PuertoCPU, core, ruta)
+}
+
 // address : address+size
-func sendDataToCPU(content []byte) error {
-	CPUurl := fmt.Sprintf("http://%s:%d/receiveDataFromMemory", globals.ClientConfig.IpCPU, globals.ClientConfig.PuertoCPU)
+func sendDataToCPU(core int, content []byte) error {
+	CPUurl := urlCore(core, "receiveDataFromMemory")
 	ContentResponseTest, err := json.Marshal(content)
 	if err != nil {
 		log.Fatalf("Error al serializar el Input: %v", err)
@@ -426,18 +642,31 @@ func WriteMemory(pid int, addresses []int, data []byte) error {
 	mu.Lock()
 	defer mu.Unlock()
 
-	if _, exists := pageTable[pid]; !exists {
+	if !procesoExiste(pid) {
 		return fmt.Errorf("Process with PID %d not found", pid)
 	}
+	for i, address := range addresses {
+		if i >= len(data) {
+			break
+		}
+		if err := verificarAcceso(pid, address, "W"); err != nil {
+			return err
+		}
+	}
+	if len(data) < len(addresses) {
+		addresses = addresses[:len(data)]
+	}
 	i := 0
 	if len(data) >= len(addresses) {
 		for _, address := range addresses {
 			memory[address] = data[i]
+			marcarAcceso(address/pageSize, true)
 			i++
 		}
 	} else {
 		for _, dato := range data {
 			memory[addresses[i]] = dato
+			marcarAcceso(addresses[i]/pageSize, true)
 			i++
 		}
 	}
@@ -477,22 +706,57 @@ func GetPageFromCPU(w http.ResponseWriter, r *http.Request) {
 	CPUpid = bodyCPUpage1.Pid
 	CPUpage = bodyCPUpage1.Page
 
-	sendFrameToCPU(CPUpid, CPUpage)
+	if tablasMultinivelActivas() {
+		indices := bodyCPUpage1.Indices
+		if len(indices) == 0 {
+			indices = indicesDePagina(CPUpage)
+		}
+		esperarAccesoTablas()
+		mu.Lock()
+		pagina, frame, err := recorrerTablas(CPUpid, indices)
+		var permisos string
+		if err == nil {
+			permisos = permisosEfectivos(CPUpid, pagina)
+		}
+		mu.Unlock()
+		if err != nil {
+			log.Printf("PID: %d - Pagina: %d - Error en el recorrido de tablas: %v", CPUpid, CPUpage, err)
+			sendSegFaultToCPU(bodyCPUpage1.Core, CPUpid, CPUpage)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		enviarFrameACPU(bodyCPUpage1.Core, CPUpid, pagina, frame, permisos)
+	} else {
+		sendFrameToCPU(bodyCPUpage1.Core, CPUpid, CPUpage)
+	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Page recibido correctamente"))
 }
 
-func sendFrameToCPU(pid int, page int) error {
+func sendFrameToCPU(core int, pid int, page int) error {
+	mu.Lock()
+	if page < 0 || page >= cantidadPaginas(pid) {
+		mu.Unlock()
+		return sendSegFaultToCPU(core, pid, page)
+	}
+	frame := marcoDePagina(pid, page)
+	permisos := permisosEfectivos(pid, page)
+	mu.Unlock()
+	return enviarFrameACPU(core, pid, page, frame, permisos)
+}
+
+func enviarFrameACPU(core int, pid int, page int, frame int, permisos string) error {
 	var bodyFrame BodyFrame
-	CPUurl := fmt.Sprintf("http://%s:%d/recieveFrame", globals.ClientConfig.IpCPU, globals.ClientConfig.PuertoCPU)
+	CPUurl := urlCore(core, "recieveFrame")
 
-	if page > len(pageTable[pid]) {
-		FinalizarProceso(pid)
-		return nil
+	bodyFrame.Permisos = permisos
+	if frame == -1 { // La pagina esta en swap, la CPU tiene que devolver el proceso al kernel
+		log.Printf("PID: %d - Pagina: %d - Page Fault", pid, page)
+		bodyFrame.PageFault = true
+	} else {
+		log.Printf("PID: %d - Pagina: %d - Marco: %d", pid, page, frame)
 	}
-	frame := pageTable[pid][page]
-	log.Printf("PID: %d - Pagina: %d - Marco: %d", pid, page, frame)
 	bodyFrame.Frame = frame
 	FrameResponseTest, err := json.Marshal(bodyFrame)
 	if err != nil {
@@ -507,19 +771,60 @@ func sendFrameToCPU(pid int, page int) error {
 	return nil
 }
 
-func proximoLugarLibre() int {
-	for i := 0; i < len(memoryMap); i++ {
-		if !memoryMap[i] {
-			return i
+// La pagina esta fuera de la tabla del proceso, la CPU lo devuelve al kernel con SEGMENTATION_FAULT
+func sendSegFaultToCPU(core int, pid int, page int) error {
+	CPUurl := urlCore(core, "recieveFrame")
+	log.Printf("PID: %d - Pagina: %d - Segmentation Fault", pid, page)
+	bodyFrameJSON, err := json.Marshal(BodyFrame{Frame: -1, SegFault: true})
+	if err != nil {
+		log.Fatalf("Error al serializar el frame: %v", err)
+	}
+
+	resp, err := http.Post(CPUurl, "application/json", bytes.NewBuffer(bodyFrameJSON))
+	if err != nil {
+		log.Fatalf("error al enviar la solicitud al módulo de memoria: %v", err)
+	}
+	defer resp.Body.Close()
+	return nil
+}
+
+// Elige el marco libre para el proceso segun la politica de asignacion configurada
+func proximoLugarLibre(pid int) int {
+	frame := -1
+	switch politicaAsignacion() {
+	case "RANDOM":
+		frame = frameAleatorio()
+	case "CONTIGUOUS":
+		frame = frameContiguo(pid)
+	case "POOL":
+		if asignacionPorPool() {
+			frame = frameDelPool(pid)
+			break
 		}
+		fallthrough
+	default: // FIRST
+		if libres := framesLibres(); len(libres) > 0 {
+			frame = libres[0]
+		}
+	}
+	if frame != -1 {
+		ultimoFrame[pid] = frame
 	}
-	return -1
+	return frame
 }
 
 func SendPageTamToCPU(tamPage int) {
 	CPUurl := fmt.Sprintf("http://%s:%d/recievePageTam", globals.ClientConfig.IpCPU, globals.ClientConfig.PuertoCPU)
 	var body BodyPageTam
 	body.PageTam = tamPage
+	if tablasMultinivelActivas() {
+		body.Niveles = globals.ClientConfig.NivelesTablas
+		body.EntradasPorTabla = globals.ClientConfig.EntradasPorTabla
+	}
+	if modoSegmentacion() {
+		body.ModoMemoria = globals.ClientConfig.ModoMemoria
+		body.TamMaxSegmento = globals.ClientConfig.TamMaxSegmento
+	}
 	PageTamResponseTest, err := json.Marshal(body)
 	if err != nil {
 		log.Fatalf("Error al serializar el tamPage: %v", err)
@@ -530,6 +835,10 @@ func SendPageTamToCPU(tamPage int) {
 		log.Fatalf("error al enviar la solicitud al módulo de memoria: %v", err)
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK { // Por ejemplo una cache de datos con lineas que no dividen la pagina
+		motivo, _ := io.ReadAll(resp.Body)
+		log.Fatalf("La CPU rechazo el tamaño de pagina %d: %s", tamPage, strings.TrimSpace(string(motivo)))
+	}
 }
 
 func NotifyOutOfMemory(pid int) {
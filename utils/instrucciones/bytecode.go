@@ -0,0 +1,234 @@
+package instrucciones
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Formato binario de los programas:
+//
+//	"TPSOBIN\x00" | version (uint16) | tamaño del codigo (uint32) | codigo
+//
+// Cada instruccion es su opcode (1 byte) seguido de los operandos, en big endian como MOV_OUT:
+//
+//	Registro        1 byte (posicion en NombresRegistros + 1)
+//	Valor           uint32
+//	RegistroOValor  1 byte de tipo (0 registro, 1 valor) + el operando
+//	Destino         uint32, direccion en bytes dentro del codigo (el PC de los programas binarios es en bytes)
+//	Nombre          1 byte de largo + el texto
+const MagicBinario = "TPSOBIN\x00"
+const VersionBinario uint16 = 1
+const TamCabecera = len(MagicBinario) + 2 + 4
+
+// Cuanto puede ocupar una instruccion (IO_FS_WRITE con dos nombres de 255 caracteres)
+const TamMaxInstruccion = 1 + 2*(1+255) + 3
+
+// El orden define el codigo de cada opcode y registro, solo se agregan al final
+var Opcodes = []string{
+	"SET", "SUM", "SUB", "JNZ", "RESIZE", "MOV_IN", "MOV_OUT", "COPY_STRING", "WAIT", "SIGNAL",
+	"IO_GEN_SLEEP", "IO_STDIN_READ", "IO_STDOUT_WRITE", "IO_FS_CREATE", "IO_FS_DELETE",
+	"IO_FS_TRUNCATE", "IO_FS_WRITE", "IO_FS_READ", "EXIT", "MALLOC", "FREE", "PUSH", "POP",
+	"CALL", "RET", "MUL", "DIV", "MOD", "AND", "OR", "XOR", "SHL", "SHR", "NOT", "CMP",
+	"JZ", "JG", "JL", "JMP", "CLI", "STI",
+}
+
+var NombresRegistros = []string{
+	"PC", "AX", "BX", "CX", "DX", "EAX", "EBX", "ECX", "EDX", "SI", "DI", "ERR", "SP", "FLAGS",
+}
+
+// La instruccion no entra en los bytes leidos, hay que leer mas (hasta TamMaxInstruccion)
+var ErrInstruccionIncompleta = errors.New("instrucción incompleta")
+
+func indice(lista []string, nombre string) int {
+	for i, elemento := range lista {
+		if elemento == nombre {
+			return i
+		}
+	}
+	return -1
+}
+
+// Tamaño en bytes de una instruccion ya validada
+func TamInstruccion(texto string) int {
+	palabras := strings.Fields(texto)
+	tam := 1
+	for i, tipo := range Tabla[palabras[0]].Operandos {
+		switch tipo {
+		case Registro:
+			tam++
+		case Valor, Destino:
+			tam += 4
+		case RegistroOValor:
+			if EsRegistro(palabras[i+1]) {
+				tam += 2
+			} else {
+				tam += 5
+			}
+		case Nombre:
+			tam += 1 + len(palabras[i+1])
+		}
+	}
+	return tam
+}
+
+// Codifica una instruccion ya validada. direcciones traduce numero de instruccion a direccion en bytes
+func codificar(texto string, direcciones []int) ([]byte, error) {
+	palabras := strings.Fields(texto)
+	codigo := []byte{byte(indice(Opcodes, palabras[0]) + 1)}
+	for i, tipo := range Tabla[palabras[0]].Operandos {
+		operando := palabras[i+1]
+		switch tipo {
+		case Registro:
+			codigo = append(codigo, byte(indice(NombresRegistros, operando)+1))
+		case Valor:
+			valor, _ := strconv.ParseUint(operando, 10, 32)
+			codigo = binary.BigEndian.AppendUint32(codigo, uint32(valor))
+		case RegistroOValor:
+			if EsRegistro(operando) {
+				codigo = append(codigo, 0, byte(indice(NombresRegistros, operando)+1))
+			} else {
+				valor, _ := strconv.ParseUint(operando, 10, 32)
+				codigo = binary.BigEndian.AppendUint32(append(codigo, 1), uint32(valor))
+			}
+		case Destino:
+			instruccion, _ := strconv.Atoi(operando)
+			if instruccion >= len(direcciones) {
+				return nil, fmt.Errorf("salto a la instrucción %d, el programa tiene %d", instruccion, len(direcciones)-1)
+			}
+			codigo = binary.BigEndian.AppendUint32(codigo, uint32(direcciones[instruccion]))
+		case Nombre:
+			if len(operando) > 255 {
+				return nil, fmt.Errorf("el nombre %s supera los 255 caracteres", operando)
+			}
+			codigo = append(append(codigo, byte(len(operando))), operando...)
+		}
+	}
+	return codigo, nil
+}
+
+// Ensambla el programa y lo codifica con su cabecera, listo para guardar en un archivo
+func EnsamblarBinario(lineas []string) ([]byte, error) {
+	programa, err := Ensamblar(lineas)
+	if err != nil {
+		return nil, err
+	}
+
+	// Direccion en bytes de cada instruccion, la ultima posicion es el final del codigo
+	direcciones := make([]int, len(programa.Lineas)+1)
+	for i, linea := range programa.Lineas {
+		direcciones[i+1] = direcciones[i] + TamInstruccion(linea.Texto)
+	}
+
+	var codigo []byte
+	var errores ErroresPrograma
+	for _, linea := range programa.Lineas {
+		instruccion, err := codificar(linea.Texto, direcciones)
+		if err != nil {
+			errores = append(errores, ErrorLinea{Numero: linea.Numero, Motivo: err.Error()})
+			continue
+		}
+		codigo = append(codigo, instruccion...)
+	}
+	if len(errores) > 0 {
+		return nil, errores
+	}
+
+	archivo := []byte(MagicBinario)
+	archivo = binary.BigEndian.AppendUint16(archivo, VersionBinario)
+	archivo = binary.BigEndian.AppendUint32(archivo, uint32(len(codigo)))
+	return append(archivo, codigo...), nil
+}
+
+func EsBinario(archivo []byte) bool {
+	return bytes.HasPrefix(archivo, []byte(MagicBinario))
+}
+
+// Valida la cabecera y devuelve solo el codigo
+func CodigoBinario(archivo []byte) ([]byte, error) {
+	if !EsBinario(archivo) || len(archivo) < TamCabecera {
+		return nil, fmt.Errorf("no es un programa binario")
+	}
+	if version := binary.BigEndian.Uint16(archivo[len(MagicBinario):]); version != VersionBinario {
+		return nil, fmt.Errorf("version de programa binario no soportada: %d", version)
+	}
+	tam := int(binary.BigEndian.Uint32(archivo[len(MagicBinario)+2:]))
+	if len(archivo)-TamCabecera != tam {
+		return nil, fmt.Errorf("el programa dice tener %d bytes de codigo y tiene %d", tam, len(archivo)-TamCabecera)
+	}
+	return archivo[TamCabecera:], nil
+}
+
+// Decodifica la instruccion que empieza en codigo[0] y devuelve su texto (los Destino quedan como
+// direcciones en bytes) y cuantos bytes ocupa
+func Decodificar(codigo []byte) (string, int, error) {
+	if len(codigo) == 0 {
+		return "", 0, ErrInstruccionIncompleta
+	}
+	opcode := int(codigo[0]) - 1
+	if opcode < 0 || opcode >= len(Opcodes) {
+		return "", 0, fmt.Errorf("opcode desconocido: %d", codigo[0])
+	}
+	palabras := []string{Opcodes[opcode]}
+	pos := 1
+
+	leer := func(n int) ([]byte, error) {
+		if pos+n > len(codigo) {
+			return nil, ErrInstruccionIncompleta
+		}
+		pos += n
+		return codigo[pos-n : pos], nil
+	}
+	registro := func() (string, error) {
+		datos, err := leer(1)
+		if err != nil {
+			return "", err
+		}
+		if datos[0] == 0 || int(datos[0]) > len(NombresRegistros) {
+			return "", fmt.Errorf("registro desconocido: %d", datos[0])
+		}
+		return NombresRegistros[datos[0]-1], nil
+	}
+	valor := func() (string, error) {
+		datos, err := leer(4)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(uint64(binary.BigEndian.Uint32(datos)), 10), nil
+	}
+
+	for _, tipo := range Tabla[Opcodes[opcode]].Operandos {
+		var operando string
+		var err error
+		switch tipo {
+		case Registro:
+			operando, err = registro()
+		case Valor, Destino:
+			operando, err = valor()
+		case RegistroOValor:
+			var tipoOperando []byte
+			if tipoOperando, err = leer(1); err == nil {
+				if tipoOperando[0] == 0 {
+					operando, err = registro()
+				} else {
+					operando, err = valor()
+				}
+			}
+		case Nombre:
+			var largo []byte
+			if largo, err = leer(1); err == nil {
+				var nombre []byte
+				nombre, err = leer(int(largo[0]))
+				operando = string(nombre)
+			}
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		palabras = append(palabras, operando)
+	}
+	return strings.Join(palabras, " "), pos, nil
+}
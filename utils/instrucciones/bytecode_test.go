@@ -0,0 +1,123 @@
+package instrucciones
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// Ensambla el programa y lo vuelve a leer instruccion por instruccion
+func decodificarPrograma(t *testing.T, lineas []string) []string {
+	t.Helper()
+	archivo, err := EnsamblarBinario(lineas)
+	if err != nil {
+		t.Fatalf("no se pudo ensamblar: %v", err)
+	}
+	codigo, err := CodigoBinario(archivo)
+	if err != nil {
+		t.Fatalf("cabecera invalida: %v", err)
+	}
+	var textos []string
+	for pos := 0; pos < len(codigo); {
+		texto, tam, err := Decodificar(codigo[pos:])
+		if err != nil {
+			t.Fatalf("no se pudo decodificar en el byte %d: %v", pos, err)
+		}
+		if tam != TamInstruccion(texto) {
+			t.Errorf("%s ocupa %d bytes y TamInstruccion dice %d", texto, tam, TamInstruccion(texto))
+		}
+		textos = append(textos, texto)
+		pos += tam
+	}
+	return textos
+}
+
+func TestBytecodeIdaYVuelta(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		lineas    []string
+		esperadas []string // Los Destino quedan como direccion en bytes
+	}{
+		{"registros y valores", []string{"SET AX 255", "SET EAX 4294967295", "SUM EAX EBX", "EXIT"},
+			[]string{"SET AX 255", "SET EAX 4294967295", "SUM EAX EBX", "EXIT"}},
+		{"registro o valor", []string{"MUL AX BX", "MUL ECX 70000", "CMP SI 0", "SHL DX DX"},
+			[]string{"MUL AX BX", "MUL ECX 70000", "CMP SI 0", "SHL DX DX"}},
+		{"nombres", []string{"IO_FS_WRITE Interfaz archivo.txt SI DI ERR", "WAIT RA", "IO_GEN_SLEEP Int 10"},
+			[]string{"IO_FS_WRITE Interfaz archivo.txt SI DI ERR", "WAIT RA", "IO_GEN_SLEEP Int 10"}},
+		{"saltos en bytes", []string{"SET AX 3", "loop:", "SUB AX BX", "JNZ AX loop", "JMP fin", "EXIT", "fin:", "CLI", "STI", "RET"},
+			// SET AX 3 ocupa 6 bytes, SUB 3, JNZ 6, JMP 5 y EXIT 1
+			[]string{"SET AX 3", "SUB AX BX", "JNZ AX 6", "JMP 21", "EXIT", "CLI", "STI", "RET"}},
+		{"todos los registros", []string{"PUSH PC", "PUSH SP", "POP FLAGS", "NOT ERR", "FREE DI"},
+			[]string{"PUSH PC", "PUSH SP", "POP FLAGS", "NOT ERR", "FREE DI"}},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			textos := decodificarPrograma(t, caso.lineas)
+			if len(textos) != len(caso.esperadas) {
+				t.Fatalf("se decodificaron %v, se esperaba %v", textos, caso.esperadas)
+			}
+			for i := range textos {
+				if textos[i] != caso.esperadas[i] {
+					t.Errorf("instruccion %d = %q, se esperaba %q", i, textos[i], caso.esperadas[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodificarErrores(t *testing.T) {
+	casos := []struct {
+		nombre     string
+		codigo     []byte
+		incompleta bool // Se espera ErrInstruccionIncompleta
+	}{
+		{"vacio", []byte{}, true},
+		{"opcode cero", []byte{0}, false},
+		{"opcode desconocido", []byte{byte(len(Opcodes) + 1)}, false},
+		{"falta el registro", []byte{1}, true},
+		{"registro desconocido", []byte{1, byte(len(NombresRegistros) + 1), 0, 0, 0, 1}, false},
+		{"valor cortado", []byte{1, 2, 0, 0}, true},
+		{"nombre cortado", []byte{9, 5, 'R', 'A'}, true},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			_, _, err := Decodificar(caso.codigo)
+			if err == nil {
+				t.Fatal("se esperaba error")
+			}
+			if errors.Is(err, ErrInstruccionIncompleta) != caso.incompleta {
+				t.Errorf("error = %v, incompleta = %v", err, caso.incompleta)
+			}
+		})
+	}
+}
+
+func TestCodigoBinarioCabecera(t *testing.T) {
+	valido, err := EnsamblarBinario([]string{"EXIT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otraVersion := append([]byte{}, valido...)
+	binary.BigEndian.PutUint16(otraVersion[len(MagicBinario):], VersionBinario+1)
+
+	casos := []struct {
+		nombre  string
+		archivo []byte
+		valido  bool
+	}{
+		{"valido", valido, true},
+		{"texto", []byte("SET AX 1\nEXIT\n"), false},
+		{"cabecera cortada", valido[:TamCabecera-1], false},
+		{"otra version", otraVersion, false},
+		{"codigo de menos", valido[:len(valido)-1], false},
+		{"codigo de mas", append(append([]byte{}, valido...), 19), false},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			_, err := CodigoBinario(caso.archivo)
+			if (err == nil) != caso.valido {
+				t.Errorf("error = %v, se esperaba valido = %v", err, caso.valido)
+			}
+		})
+	}
+}
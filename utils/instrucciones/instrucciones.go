@@ -0,0 +1,280 @@
+// Tabla de instrucciones que entiende la CPU. La usan memoria (para ensamblar los programas)
+// y la CPU, asi los dos modulos validan con las mismas reglas
+package instrucciones
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type TipoOperando int
+
+const (
+	Registro       TipoOperando = iota // AX, EAX, SI...
+	Valor                              // Numero sin signo
+	RegistroOValor                     // Registro o numero
+	Destino                            // Etiqueta o numero de instruccion
+	Nombre                             // Interfaz, archivo o recurso
+)
+
+type Instruccion struct {
+	Operandos []TipoOperando
+	Salto     bool // Puede cambiar el PC a un Destino (para el analisis de codigo inalcanzable)
+	Fin       bool // Despues de esta instruccion no se sigue con la siguiente
+	Inmediato bool // Un numero en el segundo operando se guarda o se opera con el registro del primero
+}
+
+// Tamaño en bytes de cada registro de RegisterCPU
+var Registros = map[string]int{
+	"PC": 4, "EAX": 4, "EBX": 4, "ECX": 4, "EDX": 4, "SI": 4, "DI": 4, "SP": 4,
+	"AX": 1, "BX": 1, "CX": 1, "DX": 1, "ERR": 1, "FLAGS": 1,
+}
+
+var Tabla = map[string]Instruccion{
+	"SET":             {Operandos: []TipoOperando{Registro, Valor}, Inmediato: true},
+	"SUM":             {Operandos: []TipoOperando{Registro, Registro}},
+	"SUB":             {Operandos: []TipoOperando{Registro, Registro}},
+	"JNZ":             {Operandos: []TipoOperando{Registro, Destino}, Salto: true},
+	"RESIZE":          {Operandos: []TipoOperando{Valor}},
+	"MOV_IN":          {Operandos: []TipoOperando{Registro, Registro}},
+	"MOV_OUT":         {Operandos: []TipoOperando{Registro, Registro}},
+	"COPY_STRING":     {Operandos: []TipoOperando{Valor}},
+	"WAIT":            {Operandos: []TipoOperando{Nombre}},
+	"SIGNAL":          {Operandos: []TipoOperando{Nombre}},
+	"IO_GEN_SLEEP":    {Operandos: []TipoOperando{Nombre, Valor}},
+	"IO_STDIN_READ":   {Operandos: []TipoOperando{Nombre, Registro, Registro}},
+	"IO_STDOUT_WRITE": {Operandos: []TipoOperando{Nombre, Registro, Registro}},
+	"IO_FS_CREATE":    {Operandos: []TipoOperando{Nombre, Nombre}},
+	"IO_FS_DELETE":    {Operandos: []TipoOperando{Nombre, Nombre}},
+	"IO_FS_TRUNCATE":  {Operandos: []TipoOperando{Nombre, Nombre, Registro}},
+	"IO_FS_WRITE":     {Operandos: []TipoOperando{Nombre, Nombre, Registro, Registro, Registro}},
+	"IO_FS_READ":      {Operandos: []TipoOperando{Nombre, Nombre, Registro, Registro, Registro}},
+	"EXIT":            {Fin: true},
+	"MALLOC":          {Operandos: []TipoOperando{Registro, Registro}},
+	"FREE":            {Operandos: []TipoOperando{Registro}},
+	"PUSH":            {Operandos: []TipoOperando{Registro}},
+	"POP":             {Operandos: []TipoOperando{Registro}},
+	"CALL":            {Operandos: []TipoOperando{Destino}, Salto: true},
+	"RET":             {Fin: true},
+	"MUL":             {Operandos: []TipoOperando{Registro, RegistroOValor}, Inmediato: true},
+	"DIV":             {Operandos: []TipoOperando{Registro, RegistroOValor}, Inmediato: true},
+	"MOD":             {Operandos: []TipoOperando{Registro, RegistroOValor}, Inmediato: true},
+	"AND":             {Operandos: []TipoOperando{Registro, RegistroOValor}, Inmediato: true},
+	"OR":              {Operandos: []TipoOperando{Registro, RegistroOValor}, Inmediato: true},
+	"XOR":             {Operandos: []TipoOperando{Registro, RegistroOValor}, Inmediato: true},
+	"SHL":             {Operandos: []TipoOperando{Registro, RegistroOValor}},
+	"SHR":             {Operandos: []TipoOperando{Registro, RegistroOValor}},
+	"NOT":             {Operandos: []TipoOperando{Registro}},
+	"CMP":             {Operandos: []TipoOperando{Registro, RegistroOValor}, Inmediato: true}, // Resta sin guardar, solo actualiza FLAGS
+	"JZ":              {Operandos: []TipoOperando{Destino}, Salto: true},
+	"JG":              {Operandos: []TipoOperando{Destino}, Salto: true}, // Mayor sin signo (ni Z ni C del ultimo CMP)
+	"JL":              {Operandos: []TipoOperando{Destino}, Salto: true}, // Menor sin signo (C del ultimo CMP)
+	"JMP":             {Operandos: []TipoOperando{Destino}, Salto: true, Fin: true},
+	"CLI":             {}, // Enmascara las interrupciones (seccion critica)
+	"STI":             {}, // Las vuelve a habilitar
+}
+
+// Linea de un programa ya limpia, con el numero de linea del archivo original
+type Linea struct {
+	Numero int
+	Texto  string
+}
+
+type Programa struct {
+	Lineas    []Linea
+	Etiquetas map[string]int // Etiqueta -> numero de instruccion
+}
+
+// Error de una linea del archivo
+type ErrorLinea struct {
+	Numero int
+	Motivo string
+}
+
+func (e ErrorLinea) Error() string {
+	return fmt.Sprintf("linea %d: %s", e.Numero, e.Motivo)
+}
+
+// Errores de todo el programa, uno por linea
+type ErroresPrograma []ErrorLinea
+
+func (e ErroresPrograma) Error() string {
+	var mensajes []string
+	for _, err := range e {
+		mensajes = append(mensajes, err.Error())
+	}
+	return strings.Join(mensajes, "\n")
+}
+
+// Saca el comentario (# o ;) y los espacios de los costados
+func LimpiarLinea(linea string) string {
+	if i := strings.IndexAny(linea, "#;"); i != -1 {
+		linea = linea[:i]
+	}
+	return strings.TrimSpace(linea)
+}
+
+func EsEtiqueta(linea string) bool {
+	return strings.HasSuffix(linea, ":") && len(strings.Fields(linea)) == 1
+}
+
+func EsRegistro(nombre string) bool {
+	_, existe := Registros[nombre]
+	return existe
+}
+
+func esNumero(texto string) bool {
+	_, err := strconv.ParseUint(texto, 10, 32)
+	return err == nil
+}
+
+// Primera pasada: saca comentarios, lineas vacias y etiquetas, guardando a que instruccion apunta cada etiqueta
+func Preprocesar(lineas []string) (Programa, ErroresPrograma) {
+	programa := Programa{Etiquetas: make(map[string]int)}
+	var errores ErroresPrograma
+	for i, linea := range lineas {
+		texto := LimpiarLinea(linea)
+		if texto == "" {
+			continue
+		}
+		if EsEtiqueta(texto) {
+			etiqueta := strings.TrimSuffix(texto, ":")
+			if _, existe := programa.Etiquetas[etiqueta]; existe {
+				errores = append(errores, ErrorLinea{Numero: i + 1, Motivo: fmt.Sprintf("etiqueta %s repetida", etiqueta)})
+				continue
+			}
+			programa.Etiquetas[etiqueta] = len(programa.Lineas)
+			continue
+		}
+		programa.Lineas = append(programa.Lineas, Linea{Numero: i + 1, Texto: strings.Join(strings.Fields(texto), " ")})
+	}
+	return programa, errores
+}
+
+// Valida una instruccion contra la tabla. Con etiquetas en nil cualquier nombre sirve de Destino
+// (la CPU no conoce las etiquetas, las resuelve memoria)
+func Validar(texto string, etiquetas map[string]int) error {
+	palabras := strings.Fields(texto)
+	if len(palabras) == 0 {
+		return fmt.Errorf("instrucción vacía")
+	}
+	instruccion, existe := Tabla[palabras[0]]
+	if !existe {
+		return fmt.Errorf("instrucción desconocida: %s", palabras[0])
+	}
+	operandos := palabras[1:]
+	if len(operandos) != len(instruccion.Operandos) {
+		return fmt.Errorf("%s espera %d operandos y tiene %d", palabras[0], len(instruccion.Operandos), len(operandos))
+	}
+	for i, tipo := range instruccion.Operandos {
+		operando := operandos[i]
+		switch tipo {
+		case Registro:
+			if !EsRegistro(operando) {
+				return fmt.Errorf("registro desconocido: %s", operando)
+			}
+		case Valor:
+			if !esNumero(operando) {
+				return fmt.Errorf("%s no es un numero valido", operando)
+			}
+			if err := validarInmediato(instruccion, operandos, i); err != nil {
+				return err
+			}
+		case RegistroOValor:
+			if !EsRegistro(operando) && !esNumero(operando) {
+				return fmt.Errorf("%s no es un registro ni un numero", operando)
+			}
+			if err := validarInmediato(instruccion, operandos, i); err != nil {
+				return err
+			}
+		case Destino:
+			if _, existe := etiquetas[operando]; etiquetas != nil && !existe && !esNumero(operando) {
+				return fmt.Errorf("etiqueta desconocida: %s", operando)
+			}
+		}
+	}
+	return nil
+}
+
+// SET AX 300 no entra en AX: el numero tiene que entrar en el ancho del registro destino
+func validarInmediato(instruccion Instruccion, operandos []string, i int) error {
+	if !instruccion.Inmediato || i != 1 || !esNumero(operandos[1]) {
+		return nil
+	}
+	valor, _ := strconv.ParseUint(operandos[1], 10, 32)
+	if bits := Registros[operandos[0]] * 8; valor >= 1<<bits {
+		return fmt.Errorf("%s no entra en %s (%d bits)", operandos[1], operandos[0], bits)
+	}
+	return nil
+}
+
+// Segunda pasada: valida cada instruccion y reemplaza las etiquetas por el numero de instruccion
+func Ensamblar(lineas []string) (Programa, error) {
+	programa, errores := Preprocesar(lineas)
+	for i, linea := range programa.Lineas {
+		if err := Validar(linea.Texto, programa.Etiquetas); err != nil {
+			errores = append(errores, ErrorLinea{Numero: linea.Numero, Motivo: err.Error()})
+			continue
+		}
+		programa.Lineas[i].Texto = resolverEtiquetas(linea.Texto, programa.Etiquetas)
+	}
+	if len(errores) > 0 {
+		sort.Slice(errores, func(i, j int) bool { return errores[i].Numero < errores[j].Numero })
+		return programa, errores
+	}
+	return programa, nil
+}
+
+func resolverEtiquetas(texto string, etiquetas map[string]int) string {
+	palabras := strings.Fields(texto)
+	for i, tipo := range Tabla[palabras[0]].Operandos {
+		if pc, existe := etiquetas[palabras[i+1]]; tipo == Destino && existe {
+			palabras[i+1] = strconv.Itoa(pc)
+		}
+	}
+	return strings.Join(palabras, " ")
+}
+
+// Instrucciones a las que no se llega desde la primera siguiendo el flujo del programa.
+// CALL sigue en la instruccion siguiente (el RET vuelve ahi), los saltos fuera del programa son error
+func Analizar(programa Programa) (inalcanzables []Linea, errores ErroresPrograma) {
+	alcanzada := make([]bool, len(programa.Lineas))
+	pendientes := []int{0}
+	for len(pendientes) > 0 {
+		pc := pendientes[len(pendientes)-1]
+		pendientes = pendientes[:len(pendientes)-1]
+		if pc >= len(programa.Lineas) || alcanzada[pc] {
+			continue
+		}
+		alcanzada[pc] = true
+
+		palabras := strings.Fields(programa.Lineas[pc].Texto)
+		instruccion := Tabla[palabras[0]]
+		if !instruccion.Fin {
+			pendientes = append(pendientes, pc+1)
+		}
+		for i, tipo := range instruccion.Operandos {
+			if tipo != Destino || i+1 >= len(palabras) {
+				continue
+			}
+			destino, err := strconv.Atoi(palabras[i+1])
+			if err != nil {
+				continue // Etiqueta sin resolver, ya la reporto Ensamblar
+			}
+			if destino >= len(programa.Lineas) {
+				errores = append(errores, ErrorLinea{Numero: programa.Lineas[pc].Numero, Motivo: fmt.Sprintf("salto a la instrucción %d, el programa tiene %d", destino, len(programa.Lineas))})
+				continue
+			}
+			pendientes = append(pendientes, destino)
+		}
+	}
+
+	for pc, linea := range programa.Lineas {
+		if !alcanzada[pc] {
+			inalcanzables = append(inalcanzables, linea)
+		}
+	}
+	return inalcanzables, errores
+}
@@ -0,0 +1,102 @@
+package instrucciones
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEnsamblarErrores(t *testing.T) {
+	casos := []struct {
+		nombre  string
+		lineas  []string
+		errores []ErrorLinea // nil si el programa ensambla
+	}{
+		{"programa valido", []string{"SET AX 1", "EXIT"}, nil},
+		{"instruccion desconocida", []string{"FOO AX"}, []ErrorLinea{{1, "instrucción desconocida: FOO"}}},
+		{"faltan operandos", []string{"SET AX"}, []ErrorLinea{{1, "SET espera 2 operandos y tiene 1"}}},
+		{"sobran operandos", []string{"EXIT AX"}, []ErrorLinea{{1, "EXIT espera 0 operandos y tiene 1"}}},
+		{"registro desconocido", []string{"SET ZX 1"}, []ErrorLinea{{1, "registro desconocido: ZX"}}},
+		{"numero negativo", []string{"SET AX -1"}, []ErrorLinea{{1, "-1 no es un numero valido"}}},
+		{"numero de mas de 32 bits", []string{"SET EAX 4294967296"}, []ErrorLinea{{1, "4294967296 no es un numero valido"}}},
+		{"registro o valor invalido", []string{"MUL AX x"}, []ErrorLinea{{1, "x no es un registro ni un numero"}}},
+		{"etiqueta desconocida", []string{"JNZ AX fin"}, []ErrorLinea{{1, "etiqueta desconocida: fin"}}},
+		{"etiqueta repetida", []string{"a:", "a:", "EXIT"}, []ErrorLinea{{2, "etiqueta a repetida"}}},
+		{"comentarios y lineas vacias", []string{"# inicio", "", "SET AX 1 ; uno", "FOO"}, []ErrorLinea{{4, "instrucción desconocida: FOO"}}},
+		{"errores ordenados por linea", []string{"FOO", "a:", "SET AX 300", "a:", "EXIT"}, []ErrorLinea{
+			{1, "instrucción desconocida: FOO"}, {3, "300 no entra en AX (8 bits)"}, {4, "etiqueta a repetida"},
+		}},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			_, err := Ensamblar(caso.lineas)
+			if caso.errores == nil {
+				if err != nil {
+					t.Fatalf("no se esperaba error: %v", err)
+				}
+				return
+			}
+			var errores ErroresPrograma
+			if !errors.As(err, &errores) {
+				t.Fatalf("se esperaba ErroresPrograma y llego %v", err)
+			}
+			if !reflect.DeepEqual([]ErrorLinea(errores), caso.errores) {
+				t.Errorf("errores = %v, se esperaba %v", errores, caso.errores)
+			}
+		})
+	}
+}
+
+func TestValidarInmediato(t *testing.T) {
+	casos := []struct {
+		texto string
+		error string // Vacio si es valida
+	}{
+		{"SET AX 255", ""},
+		{"SET AX 256", "256 no entra en AX (8 bits)"},
+		{"SET EAX 4294967295", ""},
+		{"SET ERR 2", ""},
+		{"SET FLAGS 300", "300 no entra en FLAGS (8 bits)"},
+		{"MUL BX 256", "256 no entra en BX (8 bits)"},
+		{"DIV ECX 70000", ""},
+		{"AND CX 255", ""},
+		{"OR DX 1000", "1000 no entra en DX (8 bits)"},
+		{"CMP AX 300", "300 no entra en AX (8 bits)"},
+		{"CMP AX EAX", ""}, // Registro contra registro se controla al ejecutar
+		{"SHL AX 9", ""},   // La cantidad de bits no es un inmediato del ancho del registro
+		{"RESIZE 4294967295", ""},
+	}
+	for _, caso := range casos {
+		t.Run(caso.texto, func(t *testing.T) {
+			err := Validar(caso.texto, nil)
+			switch {
+			case caso.error == "" && err != nil:
+				t.Errorf("no se esperaba error: %v", err)
+			case caso.error != "" && (err == nil || err.Error() != caso.error):
+				t.Errorf("error = %v, se esperaba %q", err, caso.error)
+			}
+		})
+	}
+}
+
+func TestEnsamblarResuelveEtiquetas(t *testing.T) {
+	programa, err := Ensamblar([]string{
+		"SET AX 3",
+		"loop:",
+		"SUB AX BX",
+		"JNZ AX loop",
+		"CALL fin",
+		"EXIT",
+		"fin:",
+		"RET",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	esperadas := []Linea{
+		{1, "SET AX 3"}, {3, "SUB AX BX"}, {4, "JNZ AX 1"}, {5, "CALL 5"}, {6, "EXIT"}, {8, "RET"},
+	}
+	if !reflect.DeepEqual(programa.Lineas, esperadas) {
+		t.Errorf("lineas = %v, se esperaba %v", programa.Lineas, esperadas)
+	}
+}
@@ -0,0 +1,31 @@
+// Cuentas de las tablas de paginas multinivel. Las usan la MMU de la CPU (para mandar los indices)
+// y memoria (para recorrer las tablas), asi los dos modulos separan la pagina de la misma forma
+package paginacion
+
+// Indice de cada nivel para un numero de pagina, el primero es el de la tabla de primer nivel
+func IndicesDePagina(pagina int, niveles int, entradas int) []int {
+	indices := make([]int, niveles)
+	for nivel := niveles - 1; nivel >= 0; nivel-- {
+		indices[nivel] = pagina % entradas
+		pagina /= entradas
+	}
+	return indices
+}
+
+// Numero de pagina que corresponde a los indices de cada nivel
+func PaginaDeIndices(indices []int, entradas int) int {
+	pagina := 0
+	for _, indice := range indices {
+		pagina = pagina*entradas + indice
+	}
+	return pagina
+}
+
+// Cantidad maxima de paginas que se pueden direccionar con la estructura de tablas
+func MaxPaginas(niveles int, entradas int) int {
+	maximo := 1
+	for i := 0; i < niveles; i++ {
+		maximo *= entradas
+	}
+	return maximo
+}